@@ -0,0 +1,405 @@
+package openairt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// handoffToolPrefix prefixes the agent names in the names of the handoff tools.
+const handoffToolPrefix = "transfer_to_"
+
+// ErrUnknownAgent is returned when handing off to an agent which isn't defined or isn't a handoff of the active agent.
+var ErrUnknownAgent = errors.New("unknown agent")
+
+// Agent is a persona of a voice agent, applied to the session while it's active.
+type Agent struct {
+	// Name of the agent, unique among the agents of a runner.
+	Name string
+
+	// Description of the agent, telling the other agents when to hand off to it.
+	Description string
+
+	// Instructions of the session while the agent is active.
+	Instructions string
+
+	// Tools of the agent. The handoff tools are added to them.
+	Tools []ToolUnion
+
+	// Voice of the agent, the voice is kept as is if empty.
+	Voice Voice
+
+	// TurnDetection of the agent, the turn detection is kept as is if unset.
+	TurnDetection Optional[TurnDetectionUnion]
+
+	// Handoffs are the names of the agents it can hand off to.
+	Handoffs []string
+}
+
+// AgentHandoff is a switch of the active agent.
+type AgentHandoff struct {
+	From string
+	To   string
+
+	// CallID and ResponseID of the handoff function call, empty for the handoffs made by AgentRunner.Handoff.
+	CallID     string
+	ResponseID string
+
+	// Reason given by the model.
+	Reason string
+
+	// VoiceKept reports that the voice of To wasn't applied, because the model has already responded with audio.
+	VoiceKept bool
+
+	// Err is the error of the handoff, if any, in which case From is still active.
+	Err error
+}
+
+// AgentRunnerOptions configures an AgentRunner.
+type AgentRunnerOptions struct {
+	// Agents which can be active.
+	Agents []Agent
+
+	// Initial is the name of the agent applied by Start. Default is the first agent.
+	Initial string
+
+	// Context returns the text of a system message added to the conversation on a handoff,
+	// e.g. to brief the next agent. Nothing is added if it returns an empty string.
+	Context func(handoff AgentHandoff) string
+
+	// BeforeHandoff is called before a handoff, returning an error refuses it. The error is sent
+	// to the model as the output of the handoff call.
+	BeforeHandoff func(ctx context.Context, handoff AgentHandoff) error
+
+	// OnHandoff is called after every handoff, successful or not.
+	OnHandoff func(handoff AgentHandoff)
+
+	// OnError is called with the errors of the responses created after the handoffs which
+	// were deferred while another response was in progress, or which the server refused.
+	OnError func(err error)
+
+	// StrictVoice refuses the handoffs to an agent with another voice after the model has responded with audio,
+	// instead of keeping the current voice.
+	StrictVoice bool
+
+	// NoResponse doesn't create a response after a handoff requested by the model.
+	NoResponse bool
+}
+
+// AgentRunner runs agents on a Conn. Each agent gets a handoff function tool, e.g. "transfer_to_billing",
+// for each of its handoffs. When the model calls one, the runner switches the active agent
+// once the response is done, by updating the session with the instructions, tools, voice and
+// turn detection of the new agent. It then outputs the result of the call, adds the context
+// message, if any, and creates a response from the new agent. The response is deferred while another
+// response is in progress, and merged with the responses to resume of the other components of the
+// connection, e.g. an MCPBridge.
+//
+// Its HandleEvent must be registered on the ConnHandler of the connection. The handoffs run
+// in their own goroutines, since they wait for the session.updated read by the ConnHandler.
+type AgentRunner struct {
+	conn   *Conn
+	opts   AgentRunnerOptions
+	agents map[string]Agent
+	// handoffs maps the names of the handoff tools to the names of the agents.
+	handoffs map[string]string
+
+	mu      sync.Mutex
+	active  string
+	pending map[string]AgentHandoff
+	wg      sync.WaitGroup
+}
+
+// NewAgentRunner creates an AgentRunner on conn. The agents must have unique names and hand off to defined agents.
+func NewAgentRunner(conn *Conn, opts AgentRunnerOptions) (*AgentRunner, error) {
+	if len(opts.Agents) == 0 {
+		return nil, errors.New("no agents")
+	}
+	r := &AgentRunner{
+		conn:     conn,
+		opts:     opts,
+		agents:   make(map[string]Agent),
+		handoffs: make(map[string]string),
+		pending:  make(map[string]AgentHandoff),
+	}
+	for _, agent := range opts.Agents {
+		if agent.Name == "" {
+			return nil, errors.New("agent without a name")
+		}
+		if _, ok := r.agents[agent.Name]; ok {
+			return nil, fmt.Errorf("duplicate agent %s", agent.Name)
+		}
+		r.agents[agent.Name] = agent
+		tool := handoffToolName(agent.Name)
+		if other, ok := r.handoffs[tool]; ok {
+			return nil, fmt.Errorf("agents %s and %s have the same handoff tool %s", other, agent.Name, tool)
+		}
+		r.handoffs[tool] = agent.Name
+	}
+	for _, agent := range opts.Agents {
+		for _, name := range agent.Handoffs {
+			if _, ok := r.agents[name]; !ok {
+				return nil, fmt.Errorf("%w: %s hands off to %s", ErrUnknownAgent, agent.Name, name)
+			}
+		}
+		for _, tool := range agent.Tools {
+			if tool.Function == nil {
+				continue
+			}
+			if _, ok := r.handoffs[tool.Function.Name]; ok {
+				return nil, fmt.Errorf("tool %s of agent %s is a handoff tool", tool.Function.Name, agent.Name)
+			}
+		}
+	}
+	r.active = opts.Initial
+	if r.active == "" {
+		r.active = opts.Agents[0].Name
+	}
+	if _, ok := r.agents[r.active]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAgent, r.active)
+	}
+	return r, nil
+}
+
+// Start applies the initial agent to the session.
+func (r *AgentRunner) Start(ctx context.Context) error {
+	session, err := r.session(r.Active(), false)
+	if err != nil {
+		return err
+	}
+	_, err = r.conn.ApplySession(ctx, session)
+	return err
+}
+
+// Active returns the active agent.
+func (r *AgentRunner) Active() Agent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.agents[r.active]
+}
+
+// Handoff switches the active agent to the agent named to, which must be a handoff of the active agent.
+// It must not be called from a ServerEventHandler, since it waits for the session.updated.
+func (r *AgentRunner) Handoff(ctx context.Context, to, reason string) error {
+	handoff := AgentHandoff{From: r.Active().Name, To: to, Reason: reason}
+	handoff.Err = r.handoff(ctx, &handoff)
+	if r.opts.OnHandoff != nil {
+		r.opts.OnHandoff(handoff)
+	}
+	return handoff.Err
+}
+
+// Wait waits for the pending handoffs.
+func (r *AgentRunner) Wait() {
+	r.wg.Wait()
+}
+
+// HandleEvent is the ServerEventHandler of the AgentRunner.
+func (r *AgentRunner) HandleEvent(ctx context.Context, event ServerEvent) {
+	switch e := event.(type) {
+	case ResponseOutputItemDoneEvent:
+		call := e.Item.FunctionCall
+		if call == nil {
+			return
+		}
+		to, ok := r.handoffs[call.Name]
+		if !ok {
+			return
+		}
+		r.mu.Lock()
+		// The first handoff of a response wins.
+		if _, ok := r.pending[e.ResponseID]; !ok {
+			r.pending[e.ResponseID] = AgentHandoff{
+				From:       r.active,
+				To:         to,
+				CallID:     call.CallID,
+				ResponseID: e.ResponseID,
+				Reason:     handoffReason(call.Arguments),
+			}
+		}
+		r.mu.Unlock()
+	case ResponseDoneEvent:
+		r.mu.Lock()
+		handoff, ok := r.pending[e.Response.ID]
+		delete(r.pending, e.Response.ID)
+		r.mu.Unlock()
+		if !ok {
+			return
+		}
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.handoffCall(ctx, handoff)
+		}()
+	}
+}
+
+// handoffCall makes the handoff requested by a function call and responds to it.
+func (r *AgentRunner) handoffCall(ctx context.Context, handoff AgentHandoff) {
+	handoff.Err = r.handoff(ctx, &handoff)
+
+	var output string
+	if handoff.Err != nil {
+		output = functionErrorOutput(handoff.Err.Error())
+	} else {
+		data, _ := json.Marshal(map[string]string{"agent": handoff.To})
+		output = string(data)
+	}
+	err := r.conn.SendMessage(ctx, ConversationItemCreateEvent{Item: MessageItemUnion{
+		FunctionCallOutput: &MessageItemFunctionCallOutput{CallID: handoff.CallID, Output: output},
+	}})
+	if err == nil && handoff.Err == nil {
+		err = r.sendContext(ctx, handoff)
+	}
+	if err == nil && !r.opts.NoResponse {
+		err = r.conn.resumeResponse(ctx, r.report)
+	}
+	if handoff.Err == nil {
+		handoff.Err = err
+	}
+	if r.opts.OnHandoff != nil {
+		r.opts.OnHandoff(handoff)
+	}
+}
+
+// report reports the error of creating the response after a handoff, if any.
+func (r *AgentRunner) report(err error) {
+	if err != nil && r.opts.OnError != nil {
+		r.opts.OnError(err)
+	}
+}
+
+// handoff applies the agent handoff.To to the session if allowed, and makes it active.
+func (r *AgentRunner) handoff(ctx context.Context, handoff *AgentHandoff) error {
+	from, to := r.Active(), r.agents[handoff.To]
+	if !stringsContain(from.Handoffs, handoff.To) {
+		return fmt.Errorf("%w: %s can't hand off to %s", ErrUnknownAgent, from.Name, handoff.To)
+	}
+	if r.opts.BeforeHandoff != nil {
+		if err := r.opts.BeforeHandoff(ctx, *handoff); err != nil {
+			return err
+		}
+	}
+
+	// Guard against changing the voice once the model has responded with audio, which the API refuses.
+	keepVoice := false
+	if to.Voice != "" && r.conn.AudioOutputStarted() && to.Voice != r.currentVoice() {
+		if r.opts.StrictVoice {
+			return ErrVoiceChange
+		}
+		keepVoice = true
+	}
+	session, err := r.session(to, keepVoice)
+	if err != nil {
+		return err
+	}
+	if _, err := r.conn.ApplySession(ctx, session); err != nil {
+		return err
+	}
+	handoff.VoiceKept = keepVoice
+
+	r.mu.Lock()
+	r.active = to.Name
+	r.mu.Unlock()
+	if handoff.CallID == "" {
+		return r.sendContext(ctx, *handoff)
+	}
+	return nil
+}
+
+// sendContext adds the context message of the handoff, if any.
+func (r *AgentRunner) sendContext(ctx context.Context, handoff AgentHandoff) error {
+	if r.opts.Context == nil {
+		return nil
+	}
+	text := r.opts.Context(handoff)
+	if text == "" {
+		return nil
+	}
+	return r.conn.SendMessage(ctx, ConversationItemCreateEvent{Item: MessageItemUnion{
+		System: &MessageItemSystem{Content: []MessageContentSystem{{Text: text}}},
+	}})
+}
+
+func (r *AgentRunner) currentVoice() Voice {
+	session, ok := r.conn.Session()
+	if !ok || session.Realtime == nil || session.Realtime.Audio == nil || session.Realtime.Audio.Output == nil {
+		return ""
+	}
+	return session.Realtime.Audio.Output.Voice
+}
+
+// session returns the session configuration of agent. As the tools can't be cleared by a session update,
+// an agent without tools gets the tool choice none.
+func (r *AgentRunner) session(agent Agent, keepVoice bool) (SessionUnion, error) {
+	tools := append([]ToolUnion(nil), agent.Tools...)
+	for _, name := range agent.Handoffs {
+		target, ok := r.agents[name]
+		if !ok {
+			return SessionUnion{}, fmt.Errorf("%w: %s", ErrUnknownAgent, name)
+		}
+		tools = append(tools, handoffTool(target))
+	}
+	toolChoice := ToolChoiceModeAuto
+	if len(tools) == 0 {
+		toolChoice = ToolChoiceModeNone
+	}
+	session := &RealtimeSession{
+		Instructions: agent.Instructions,
+		Tools:        tools,
+		ToolChoice:   &ToolChoiceUnion{Mode: toolChoice},
+	}
+	if (agent.Voice != "" && !keepVoice) || agent.TurnDetection.IsSet() {
+		session.Audio = &RealtimeSessionAudio{}
+		if agent.Voice != "" && !keepVoice {
+			session.Audio.Output = &SessionAudioOutput{Voice: agent.Voice}
+		}
+		if agent.TurnDetection.IsSet() {
+			session.Audio.Input = &SessionAudioInput{TurnDetection: agent.TurnDetection}
+		}
+	}
+	return SessionUnion{Realtime: session}, nil
+}
+
+func handoffToolName(agent string) string {
+	return sanitizeFunctionName(handoffToolPrefix + agent)
+}
+
+// handoffTool is the function tool handing off to agent.
+func handoffTool(agent Agent) ToolUnion {
+	description := "Hand off the conversation to " + agent.Name + "."
+	if agent.Description != "" {
+		description += " " + agent.Description
+	}
+	return ToolUnion{Function: &ToolFunction{
+		Name:        handoffToolName(agent.Name),
+		Description: description,
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"reason": map[string]any{
+					"type":        "string",
+					"description": "Why the conversation is handed off, and what the next agent should know.",
+				},
+			},
+		},
+	}}
+}
+
+func handoffReason(arguments string) string {
+	var args struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.Unmarshal([]byte(arguments), &args)
+	return args.Reason
+}
+
+func stringsContain(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package openairt_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func testAgents() []openairt.Agent {
+	return []openairt.Agent{
+		{
+			Name:         "triage",
+			Instructions: "Route the caller.",
+			Voice:        openairt.VoiceMarin,
+			Handoffs:     []string{"billing"},
+		},
+		{
+			Name:         "billing",
+			Description:  "Handles invoices.",
+			Instructions: "Answer billing questions.",
+			Voice:        openairt.VoiceCedar,
+			Handoffs:     []string{"triage"},
+		},
+		{
+			Name:         "closing",
+			Instructions: "Say goodbye.",
+		},
+	}
+}
+
+func TestAgentRunnerHandoff(t *testing.T) {
+	conn, server := startSessionConn(t)
+	handoffs := make(chan openairt.AgentHandoff, 1)
+	runner, err := openairt.NewAgentRunner(conn, openairt.AgentRunnerOptions{
+		Agents:    testAgents(),
+		Context:   func(h openairt.AgentHandoff) string { return "From " + h.From + ": " + h.Reason },
+		OnHandoff: func(h openairt.AgentHandoff) { handoffs <- h },
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, runner.Start(ctx))
+
+	updates := sentOfType(server, "session.update")
+	require.Len(t, updates, 1)
+	require.JSONEq(t, `{"type":"realtime","instructions":"Route the caller.","tool_choice":"auto",
+		"audio":{"output":{"voice":"marin"}},
+		"tools":[{"type":"function","name":"transfer_to_billing",
+			"description":"Hand off the conversation to billing. Handles invoices.",
+			"parameters":{"type":"object","properties":{"reason":{"type":"string",
+				"description":"Why the conversation is handed off, and what the next agent should know."}}}}]}`,
+		sentSession(t, updates[0]))
+
+	handleServerEvents(t, runner.HandleEvent,
+		`{"type":"response.output_item.done","response_id":"resp_1","item":{"id":"item_1","type":"function_call",`+
+			`"call_id":"call_1","name":"transfer_to_billing","arguments":"{\"reason\":\"invoice question\"}"}}`,
+		responseDoneEvent("resp_1", 10, 10),
+	)
+	runner.Wait()
+	handoff := <-handoffs
+	require.Equal(t, openairt.AgentHandoff{
+		From: "triage", To: "billing", CallID: "call_1", ResponseID: "resp_1", Reason: "invoice question",
+	}, handoff)
+	require.Equal(t, "billing", runner.Active().Name)
+
+	updates = sentOfType(server, "session.update")
+	require.Len(t, updates, 2)
+	session := updates[1]["session"].(map[string]any)
+	require.Equal(t, "Answer billing questions.", session["instructions"])
+	require.Equal(t, "transfer_to_triage", session["tools"].([]any)[0].(map[string]any)["name"])
+
+	created := sentOfType(server, "conversation.item.create")
+	require.Len(t, created, 2)
+	require.Equal(t, map[string]any{
+		"type": "function_call_output", "call_id": "call_1", "output": `{"agent":"billing"}`,
+	}, created[0]["item"])
+	require.Equal(t, map[string]any{
+		"type": "message", "role": "system",
+		"content": []any{map[string]any{"type": "input_text", "text": "From triage: invoice question"}},
+	}, created[1]["item"])
+	require.Len(t, sentOfType(server, "response.create"), 1)
+}
+
+func TestAgentRunnerGuards(t *testing.T) {
+	conn, server := startSessionConn(t)
+	refuse := errors.New("caller not verified")
+	handoffs := make(chan openairt.AgentHandoff, 1)
+	runner, err := openairt.NewAgentRunner(conn, openairt.AgentRunnerOptions{
+		Agents:  testAgents(),
+		Initial: "billing",
+		BeforeHandoff: func(_ context.Context, h openairt.AgentHandoff) error {
+			if h.Reason == "unverified" {
+				return refuse
+			}
+			return nil
+		},
+		OnHandoff: func(h openairt.AgentHandoff) { handoffs <- h },
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The model has responded with audio in the voice alloy of the session.
+	server.push(`{"type":"response.output_audio.delta","response_id":"resp_0","item_id":"item_0","delta":"AAAA"}`)
+	require.Eventually(t, conn.AudioOutputStarted, time.Second, time.Millisecond)
+
+	require.ErrorIs(t, runner.Handoff(ctx, "closing", ""), openairt.ErrUnknownAgent)
+	<-handoffs
+
+	// The refused handoff is reported to the model, which keeps the active agent.
+	handleServerEvents(t, runner.HandleEvent,
+		`{"type":"response.output_item.done","response_id":"resp_1","item":{"id":"item_1","type":"function_call",`+
+			`"call_id":"call_1","name":"transfer_to_triage","arguments":"{\"reason\":\"unverified\"}"}}`,
+		responseDoneEvent("resp_1", 10, 10),
+	)
+	runner.Wait()
+	require.ErrorIs(t, (<-handoffs).Err, refuse)
+	require.Equal(t, "billing", runner.Active().Name)
+	created := sentOfType(server, "conversation.item.create")
+	require.Len(t, created, 1)
+	require.Equal(t, `{"error":"caller not verified"}`, created[0]["item"].(map[string]any)["output"])
+	require.Empty(t, sentOfType(server, "session.update"))
+
+	// The voice of triage isn't applied since the model has responded with audio.
+	require.NoError(t, runner.Handoff(ctx, "triage", "verified"))
+	require.True(t, (<-handoffs).VoiceKept)
+	updates := sentOfType(server, "session.update")
+	require.Len(t, updates, 1)
+	require.NotContains(t, updates[0]["session"], "audio")
+	require.Equal(t, "triage", runner.Active().Name)
+}
+
+func TestNewAgentRunnerErrors(t *testing.T) {
+	conn, _ := newFakeServer(t, nil, nil)
+	_, err := openairt.NewAgentRunner(conn, openairt.AgentRunnerOptions{
+		Agents: []openairt.Agent{{Name: "triage", Handoffs: []string{"sales"}}},
+	})
+	require.ErrorIs(t, err, openairt.ErrUnknownAgent)
+
+	_, err = openairt.NewAgentRunner(conn, openairt.AgentRunnerOptions{
+		Agents: []openairt.Agent{{Name: "triage"}, {Name: "triage"}},
+	})
+	require.Error(t, err)
+
+	_, err = openairt.NewAgentRunner(conn, openairt.AgentRunnerOptions{
+		Agents: []openairt.Agent{{Name: "triage", Tools: []openairt.ToolUnion{
+			{Function: &openairt.ToolFunction{Name: "transfer_to_triage"}},
+		}}},
+	})
+	require.Error(t, err)
+}
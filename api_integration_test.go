@@ -70,20 +70,20 @@ func TestCreateTranscriptionSession(t *testing.T) {
 								Rate: 24000,
 							},
 						},
-						Transcription: &openairt.AudioTranscription{
+						Transcription: openairt.NewOptional(openairt.AudioTranscription{
 							Model:    openairt.GPT4oTranscribe,
 							Language: "en",
-						},
-						NoiseReduction: &openairt.AudioNoiseReduction{
+						}),
+						NoiseReduction: openairt.NewOptional(openairt.AudioNoiseReduction{
 							Type: openairt.NoiseReductionNearField,
-						},
-						TurnDetection: &openairt.TurnDetectionUnion{
+						}),
+						TurnDetection: openairt.NewOptional(openairt.TurnDetectionUnion{
 							ServerVad: &openairt.ServerVad{
 								Threshold:         0.6,
 								PrefixPaddingMs:   300,
 								SilenceDurationMs: 500,
 							},
-						},
+						}),
 					},
 				},
 			},
@@ -94,12 +94,12 @@ func TestCreateTranscriptionSession(t *testing.T) {
 	require.NotZero(t, session.ExpiresAt)
 	require.Equal(t, "realtime.transcription_session", session.Session.Transcription.Object)
 	require.Equal(t, int(24000), session.Session.Transcription.Audio.Input.Format.PCM.Rate)
-	require.Equal(t, openairt.GPT4oTranscribe, session.Session.Transcription.Audio.Input.Transcription.Model)
-	require.Equal(t, "en", session.Session.Transcription.Audio.Input.Transcription.Language)
-	require.NotNil(t, session.Session.Transcription.Audio.Input.TurnDetection.ServerVad)
-	require.Nil(t, session.Session.Transcription.Audio.Input.TurnDetection.SemanticVad)
-	require.InEpsilon(t, 0.6, session.Session.Transcription.Audio.Input.TurnDetection.ServerVad.Threshold, 0.0001)
-	require.Equal(t, int64(300), session.Session.Transcription.Audio.Input.TurnDetection.ServerVad.PrefixPaddingMs)
-	require.Equal(t, int64(500), session.Session.Transcription.Audio.Input.TurnDetection.ServerVad.SilenceDurationMs)
+	require.Equal(t, openairt.GPT4oTranscribe, session.Session.Transcription.Audio.Input.Transcription.Value().Model)
+	require.Equal(t, "en", session.Session.Transcription.Audio.Input.Transcription.Value().Language)
+	require.NotNil(t, session.Session.Transcription.Audio.Input.TurnDetection.Value().ServerVad)
+	require.Nil(t, session.Session.Transcription.Audio.Input.TurnDetection.Value().SemanticVad)
+	require.InEpsilon(t, 0.6, session.Session.Transcription.Audio.Input.TurnDetection.Value().ServerVad.Threshold, 0.0001)
+	require.Equal(t, int64(300), session.Session.Transcription.Audio.Input.TurnDetection.Value().ServerVad.PrefixPaddingMs)
+	require.Equal(t, int64(500), session.Session.Transcription.Audio.Input.TurnDetection.Value().ServerVad.SilenceDurationMs)
 	t.Logf("transcription session: %+v", session)
 }
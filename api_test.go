@@ -93,9 +93,10 @@ func TestCreateClientSecretResponse(t *testing.T) {
 		},
 		Session: &openairt.SessionUnion{
 			Realtime: &openairt.RealtimeSession{
-				ID:     "sess_C9CiUVUzUzYIssh3ELY1d",
-				Object: "realtime.session",
-				Model:  openairt.GPTRealtime,
+				Tracing: openairt.NullOptional[openairt.TracingUnion](),
+				ID:      "sess_C9CiUVUzUzYIssh3ELY1d",
+				Object:  "realtime.session",
+				Model:   openairt.GPTRealtime,
 				OutputModalities: []openairt.Modality{
 					openairt.ModalityAudio,
 				},
@@ -105,14 +106,16 @@ func TestCreateClientSecretResponse(t *testing.T) {
 				},
 				Audio: &openairt.RealtimeSessionAudio{
 					Input: &openairt.SessionAudioInput{
+						NoiseReduction: openairt.NullOptional[openairt.AudioNoiseReduction](),
+						Transcription:  openairt.NullOptional[openairt.AudioTranscription](),
 						Format: &openairt.AudioFormatUnion{
 							PCM: &openairt.AudioFormatPCM{
 								Rate: 24000,
 							},
 						},
-						TurnDetection: &openairt.TurnDetectionUnion{
+						TurnDetection: openairt.NewOptional(openairt.TurnDetectionUnion{
 							ServerVad: &openairt.ServerVad{},
-						},
+						}),
 					},
 					Output: &openairt.SessionAudioOutput{
 						Format: &openairt.AudioFormatUnion{
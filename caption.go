@@ -0,0 +1,292 @@
+package openairt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCaptionLineLength = 42
+	defaultCaptionLines      = 2
+)
+
+// CaptionFormat is the file format of captions.
+type CaptionFormat int
+
+const (
+	// CaptionFormatSRT is the SubRip format.
+	CaptionFormatSRT CaptionFormat = iota
+	// CaptionFormatWebVTT is the WebVTT format, speakers are written as voice tags.
+	CaptionFormatWebVTT
+)
+
+// Caption is a caption cue.
+type Caption struct {
+	ItemID  string
+	Speaker string
+	// Start and End since the session started.
+	Start time.Duration
+	End   time.Duration
+	// Lines of the caption, split by the maximum line length.
+	Lines []string
+}
+
+// CaptionOptions configures a CaptionBuilder.
+type CaptionOptions struct {
+	Format CaptionFormat
+
+	// MaxLineLength is the maximum number of characters of a line. Default is 42.
+	MaxLineLength int
+
+	// MaxLines is the maximum number of lines of a caption, longer text is split into several
+	// captions sharing its time proportionally. Default is 2.
+	MaxLines int
+
+	// Live writes the captions to the writer as they are built, e.g. for live captions.
+	// Leave it nil to write them at the end with WriteTo.
+	Live io.Writer
+}
+
+// CaptionBuilder builds captions from the transcription segments, or from the speech start and stop
+// of the input audio buffer and the final transcript when the model doesn't report segments.
+//
+// Segment times are relative to the start of the speech of their item. Without turn detection,
+// the items have no speech times and are placed at the start of the session.
+//
+// Its HandleEvent must be registered on the ConnHandler of the connection.
+type CaptionBuilder struct {
+	opts CaptionOptions
+
+	mu       sync.Mutex
+	items    map[string]*captionItem
+	captions []Caption
+	written  int
+	err      error
+}
+
+type captionItem struct {
+	audioStart time.Duration
+	audioEnd   time.Duration
+	segments   bool
+}
+
+// NewCaptionBuilder creates a CaptionBuilder.
+func NewCaptionBuilder(opts CaptionOptions) *CaptionBuilder {
+	if opts.MaxLineLength <= 0 {
+		opts.MaxLineLength = defaultCaptionLineLength
+	}
+	if opts.MaxLines <= 0 {
+		opts.MaxLines = defaultCaptionLines
+	}
+	return &CaptionBuilder{
+		opts:  opts,
+		items: make(map[string]*captionItem),
+	}
+}
+
+// HandleEvent is the ServerEventHandler of the CaptionBuilder.
+func (b *CaptionBuilder) HandleEvent(_ context.Context, event ServerEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch e := event.(type) {
+	case InputAudioBufferSpeechStartedEvent:
+		b.item(e.ItemID).audioStart = time.Duration(e.AudioStartMs) * time.Millisecond
+	case InputAudioBufferSpeechStoppedEvent:
+		b.item(e.ItemID).audioEnd = time.Duration(e.AudioEndMs) * time.Millisecond
+	case ConversationItemInputAudioTranscriptionSegmentEvent:
+		item := b.item(e.ItemID)
+		item.segments = true
+		b.add(e.ItemID, e.Speaker, e.Text,
+			item.audioStart+secondsToDuration(e.Start),
+			item.audioStart+secondsToDuration(e.End))
+	case ConversationItemInputAudioTranscriptionCompletedEvent:
+		item := b.item(e.ItemID)
+		if !item.segments {
+			b.add(e.ItemID, "", e.Transcript, item.audioStart, item.audioEnd)
+		}
+		delete(b.items, e.ItemID)
+	case ConversationItemInputAudioTranscriptionFailedEvent:
+		delete(b.items, e.ItemID)
+	}
+}
+
+// Captions returns the captions ordered by start time.
+func (b *CaptionBuilder) Captions() []Caption {
+	b.mu.Lock()
+	captions := append([]Caption(nil), b.captions...)
+	b.mu.Unlock()
+	sort.SliceStable(captions, func(i, j int) bool {
+		return captions[i].Start < captions[j].Start
+	})
+	return captions
+}
+
+// WriteTo writes all the captions ordered by start time in the format of the options.
+func (b *CaptionBuilder) WriteTo(w io.Writer) (int64, error) {
+	return WriteCaptions(w, b.opts.Format, b.Captions())
+}
+
+// Err returns the error of writing the live captions, if any.
+func (b *CaptionBuilder) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+func (b *CaptionBuilder) item(itemID string) *captionItem {
+	item, ok := b.items[itemID]
+	if !ok {
+		item = &captionItem{}
+		b.items[itemID] = item
+	}
+	return item
+}
+
+// add splits text into captions sharing the time between start and end, and writes them if live.
+func (b *CaptionBuilder) add(itemID, speaker, text string, start, end time.Duration) {
+	lines := wrapCaptionText(text, b.opts.MaxLineLength)
+	if len(lines) == 0 {
+		return
+	}
+	if end < start {
+		end = start
+	}
+
+	total := 0
+	for _, line := range lines {
+		total += len([]rune(line))
+	}
+	var captions []Caption
+	done := 0
+	for i := 0; i < len(lines); i += b.opts.MaxLines {
+		j := i + b.opts.MaxLines
+		if j > len(lines) {
+			j = len(lines)
+		}
+		chars := 0
+		for _, line := range lines[i:j] {
+			chars += len([]rune(line))
+		}
+		captions = append(captions, Caption{
+			ItemID:  itemID,
+			Speaker: speaker,
+			Start:   start + (end-start)*time.Duration(done)/time.Duration(total),
+			End:     start + (end-start)*time.Duration(done+chars)/time.Duration(total),
+			Lines:   lines[i:j],
+		})
+		done += chars
+	}
+	b.captions = append(b.captions, captions...)
+
+	if b.opts.Live == nil || b.err != nil {
+		return
+	}
+	if b.written == 0 && b.opts.Format == CaptionFormatWebVTT {
+		if _, err := io.WriteString(b.opts.Live, webVTTHeader); err != nil {
+			b.err = err
+			return
+		}
+	}
+	for _, caption := range captions {
+		b.written++
+		if _, err := writeCaption(b.opts.Live, b.opts.Format, b.written, caption); err != nil {
+			b.err = err
+			return
+		}
+	}
+}
+
+const webVTTHeader = "WEBVTT\n\n"
+
+// WriteCaptions writes captions as an SRT or WebVTT file.
+func WriteCaptions(w io.Writer, format CaptionFormat, captions []Caption) (int64, error) {
+	var written int64
+	if format == CaptionFormatWebVTT {
+		n, err := io.WriteString(w, webVTTHeader)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	for i, caption := range captions {
+		n, err := writeCaption(w, format, i+1, caption)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func writeCaption(w io.Writer, format CaptionFormat, index int, caption Caption) (int, error) {
+	var sb strings.Builder
+	switch format {
+	case CaptionFormatWebVTT:
+		fmt.Fprintf(&sb, "%s --> %s\n", formatCaptionTime(caption.Start, '.'), formatCaptionTime(caption.End, '.'))
+		for i, line := range caption.Lines {
+			line = escapeWebVTT(line)
+			if caption.Speaker != "" && i == 0 {
+				line = "<v " + escapeWebVTT(caption.Speaker) + ">" + line
+			}
+			sb.WriteString(line)
+			sb.WriteByte('\n')
+		}
+	default:
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n", index,
+			formatCaptionTime(caption.Start, ','), formatCaptionTime(caption.End, ','))
+		for i, line := range caption.Lines {
+			if caption.Speaker != "" && i == 0 {
+				line = caption.Speaker + ": " + line
+			}
+			sb.WriteString(line)
+			sb.WriteByte('\n')
+		}
+	}
+	sb.WriteByte('\n')
+	return io.WriteString(w, sb.String())
+}
+
+// formatCaptionTime formats d as hh:mm:ss followed by sep and milliseconds.
+func formatCaptionTime(d time.Duration, sep byte) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+func escapeWebVTT(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// wrapCaptionText splits text into lines of at most maxLength characters at spaces,
+// words longer than maxLength are put on their own line.
+func wrapCaptionText(text string, maxLength int) []string {
+	var lines []string
+	var line []rune
+	for _, word := range strings.Fields(text) {
+		w := []rune(word)
+		if len(line) > 0 && len(line)+1+len(w) > maxLength {
+			lines = append(lines, string(line))
+			line = line[:0]
+		}
+		if len(line) > 0 {
+			line = append(line, ' ')
+		}
+		line = append(line, w...)
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,108 @@
+package openairt_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func handleServerEvents(t *testing.T, handler openairt.ServerEventHandler, events ...string) {
+	t.Helper()
+	for _, data := range events {
+		event, err := openairt.UnmarshalServerEvent([]byte(data))
+		require.NoError(t, err)
+		handler(context.Background(), event)
+	}
+}
+
+var captionEvents = []string{
+	`{"type":"input_audio_buffer.speech_started","item_id":"item_1","audio_start_ms":1000}`,
+	`{"type":"input_audio_buffer.speech_stopped","item_id":"item_1","audio_end_ms":4000}`,
+	`{"type":"conversation.item.input_audio_transcription.segment","item_id":"item_1",` +
+		`"speaker":"A","start":0,"end":1.5,"text":"Hello <there>"}`,
+	`{"type":"conversation.item.input_audio_transcription.segment","item_id":"item_1",` +
+		`"speaker":"B","start":1.5,"end":3,"text":"Hi"}`,
+	`{"type":"conversation.item.input_audio_transcription.completed","item_id":"item_1","transcript":"Hello <there> Hi"}`,
+	// Without segments, the caption falls back to the speech times.
+	`{"type":"input_audio_buffer.speech_started","item_id":"item_2","audio_start_ms":5000}`,
+	`{"type":"input_audio_buffer.speech_stopped","item_id":"item_2","audio_end_ms":9000}`,
+	`{"type":"conversation.item.input_audio_transcription.completed","item_id":"item_2",` +
+		`"transcript":"one two three four five six seven eight"}`,
+}
+
+func TestCaptionBuilderSRT(t *testing.T) {
+	builder := openairt.NewCaptionBuilder(openairt.CaptionOptions{MaxLineLength: 10, MaxLines: 2})
+	handleServerEvents(t, builder.HandleEvent, captionEvents...)
+
+	var buf bytes.Buffer
+	_, err := builder.WriteTo(&buf)
+	require.NoError(t, err)
+	require.Equal(t, `1
+00:00:01,000 --> 00:00:02,500
+A: Hello
+<there>
+
+2
+00:00:02,500 --> 00:00:04,000
+B: Hi
+
+3
+00:00:05,000 --> 00:00:06,942
+one two
+three four
+
+4
+00:00:06,942 --> 00:00:08,428
+five six
+seven
+
+5
+00:00:08,428 --> 00:00:09,000
+eight
+
+`, buf.String())
+}
+
+func TestCaptionBuilderLiveWebVTT(t *testing.T) {
+	var buf bytes.Buffer
+	builder := openairt.NewCaptionBuilder(openairt.CaptionOptions{Format: openairt.CaptionFormatWebVTT, Live: &buf})
+	handleServerEvents(t, builder.HandleEvent, captionEvents[:3]...)
+
+	// The segment is written as soon as it arrives.
+	require.Equal(t, "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\n<v A>Hello &lt;there&gt;\n\n", buf.String())
+
+	handleServerEvents(t, builder.HandleEvent, captionEvents[3:]...)
+	require.NoError(t, builder.Err())
+	require.Equal(t, `WEBVTT
+
+00:00:01.000 --> 00:00:02.500
+<v A>Hello &lt;there&gt;
+
+00:00:02.500 --> 00:00:04.000
+<v B>Hi
+
+00:00:05.000 --> 00:00:09.000
+one two three four five six seven eight
+
+`, buf.String())
+
+	var full bytes.Buffer
+	_, err := builder.WriteTo(&full)
+	require.NoError(t, err)
+	require.Equal(t, buf.String(), full.String())
+}
+
+func TestWriteCaptions(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := openairt.WriteCaptions(&buf, openairt.CaptionFormatSRT, []openairt.Caption{{
+		Start: time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond,
+		End:   time.Hour + 2*time.Minute + 5*time.Second,
+		Lines: []string{"late"},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, "1\n01:02:03,004 --> 01:02:05,000\nlate\n\n", buf.String())
+}
@@ -70,10 +70,15 @@ func (c *Client) getHeaders() http.Header {
 }
 
 type connectOption struct {
-	model  string
-	intent string
-	dialer WebSocketDialer
-	logger Logger
+	model      string
+	intent     string
+	dialer     WebSocketDialer
+	logger     Logger
+	logAttrs   []any
+	logPayload bool
+	metrics    Metrics
+	validate   bool
+	sent       []ClientEventHandler
 }
 
 type ConnectOption func(*connectOption)
@@ -106,11 +111,51 @@ func WithLogger(logger Logger) ConnectOption {
 	}
 }
 
+// WithLogAttrs attaches the attributes in kv, which are alternating keys and values,
+// to every message logged by the connection.
+func WithLogAttrs(kv ...any) ConnectOption {
+	return func(opts *connectOption) {
+		opts.logAttrs = append(opts.logAttrs, kv...)
+	}
+}
+
+// WithLogPayload logs the payload of every sent and received event at debug level,
+// with base64 audio, inline images and credentials redacted by RedactPayload.
+func WithLogPayload() ConnectOption {
+	return func(opts *connectOption) {
+		opts.logPayload = true
+	}
+}
+
+// WithMetrics sets the metrics for the connection.
+func WithMetrics(metrics Metrics) ConnectOption {
+	return func(opts *connectOption) {
+		opts.metrics = metrics
+	}
+}
+
+// WithClientEventHandler registers handlers called with every client event sent by SendMessage,
+// e.g. to record the input audio. They are called in the goroutine of SendMessage.
+func WithClientEventHandler(handlers ...ClientEventHandler) ConnectOption {
+	return func(opts *connectOption) {
+		opts.sent = append(opts.sent, handlers...)
+	}
+}
+
+// WithValidation validates every client event implementing Validator before sending it,
+// so that SendMessage fails with ValidationErrors instead of an error event from the server.
+func WithValidation() ConnectOption {
+	return func(opts *connectOption) {
+		opts.validate = true
+	}
+}
+
 // Connect connects to the OpenAI Realtime API.
 func (c *Client) Connect(ctx context.Context, opts ...ConnectOption) (*Conn, error) {
 	connectOpts := connectOption{
-		model:  GPTRealtime,
-		logger: NopLogger{},
+		model:   GPTRealtime,
+		logger:  NopLogger{},
+		metrics: NopMetrics{},
 	}
 	for _, opt := range opts {
 		opt(&connectOpts)
@@ -132,13 +177,34 @@ func (c *Client) Connect(ctx context.Context, opts ...ConnectOption) (*Conn, err
 		url = c.config.BaseURL + "?" + "intent=" + connectOpts.intent
 	}
 
+	logger := NewStructuredLogger(connectOpts.logger)
+	if connectOpts.intent == "" {
+		logger = logger.With("model", connectOpts.model)
+	} else {
+		logger = logger.With("intent", connectOpts.intent)
+	}
+	if len(connectOpts.logAttrs) > 0 {
+		logger = logger.With(connectOpts.logAttrs...)
+	}
+
 	// dial
+	if logger.Enabled(LogLevelDebug) {
+		logger.Log(LogLevelDebug, "dialing", "url", url, "headers", RedactHeaders(headers))
+	}
 	conn, err := connectOpts.dialer.Dial(ctx, url, headers)
 	if err != nil {
+		logger.Log(LogLevelDebug, "dial failed", "error", err)
 		return nil, err
 	}
 
-	return &Conn{conn: conn, logger: connectOpts.logger}, nil
+	return &Conn{
+		conn:       conn,
+		logger:     logger,
+		logPayload: connectOpts.logPayload,
+		metrics:    connectOpts.metrics,
+		validate:   connectOpts.validate,
+		sent:       connectOpts.sent,
+	}, nil
 }
 
 func (c *Client) getAPIHeaders() http.Header {
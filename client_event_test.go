@@ -24,16 +24,16 @@ func TestSessionUpdateEvent(t *testing.T) {
 								Rate: 24000,
 							},
 						},
-						Transcription: &openairt.AudioTranscription{
+						Transcription: openairt.NewOptional(openairt.AudioTranscription{
 							Model: openairt.Whisper1,
-						},
-						TurnDetection: &openairt.TurnDetectionUnion{
+						}),
+						TurnDetection: openairt.NewOptional(openairt.TurnDetectionUnion{
 							ServerVad: &openairt.ServerVad{
 								Threshold:         0.5,
 								PrefixPaddingMs:   1000,
 								SilenceDurationMs: 2000,
 							},
-						},
+						}),
 					},
 					Output: &openairt.SessionAudioOutput{
 						Format: &openairt.AudioFormatUnion{
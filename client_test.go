@@ -96,7 +96,7 @@ func TestUnmarshalCreateClientSecretResponseRealtime(t *testing.T) {
 	require.Equal(t, "auto", session.ToolChoice.Mode.ToolChoiceType())
 	require.Equal(t, Inf, session.MaxOutputTokens)
 	require.Equal(t, "auto", session.Truncation.Strategy.TruncationStrategy())
-	vad := session.Audio.Input.TurnDetection.ServerVad
+	vad := session.Audio.Input.TurnDetection.Value().ServerVad
 	require.Equal(t, "server_vad", string(vad.VadType()))
 	require.InDelta(t, 0.5, vad.Threshold, 0.0001)
 	require.Equal(t, int64(300), vad.PrefixPaddingMs)
@@ -153,12 +153,12 @@ func TestUnmarshalCreateClientSecretResponseTranscription(t *testing.T) {
 	require.Equal(t, "sess_CTjMo5AmgZuoEWQTvyBqd", session.ID)
 	require.Equal(t, SessionTypeTranscription, session.Type())
 	require.Equal(t, int64(0), session.ExpiresAt)
-	require.Equal(t, "gpt-4o-transcribe", session.Audio.Input.Transcription.Model)
-	require.Equal(t, "en", session.Audio.Input.Transcription.Language)
-	require.Equal(t, int64(300), session.Audio.Input.TurnDetection.ServerVad.PrefixPaddingMs)
-	require.Equal(t, int64(500), session.Audio.Input.TurnDetection.ServerVad.SilenceDurationMs)
-	require.Equal(t, NoiseReductionNearField, session.Audio.Input.NoiseReduction.Type)
-	require.Equal(t, TurnDetectionTypeServerVad, session.Audio.Input.TurnDetection.ServerVad.VadType())
-	require.InDelta(t, 0.6, session.Audio.Input.TurnDetection.ServerVad.Threshold, 0.0001)
+	require.Equal(t, "gpt-4o-transcribe", session.Audio.Input.Transcription.Value().Model)
+	require.Equal(t, "en", session.Audio.Input.Transcription.Value().Language)
+	require.Equal(t, int64(300), session.Audio.Input.TurnDetection.Value().ServerVad.PrefixPaddingMs)
+	require.Equal(t, int64(500), session.Audio.Input.TurnDetection.Value().ServerVad.SilenceDurationMs)
+	require.Equal(t, NoiseReductionNearField, session.Audio.Input.NoiseReduction.Value().Type)
+	require.Equal(t, TurnDetectionTypeServerVad, session.Audio.Input.TurnDetection.Value().ServerVad.VadType())
+	require.InDelta(t, 0.6, session.Audio.Input.TurnDetection.Value().ServerVad.Threshold, 0.0001)
 	require.Equal(t, int(24000), session.Audio.Input.Format.PCM.Rate)
 }
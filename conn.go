@@ -5,60 +5,168 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"sync"
 )
 
+const defaultDispatchQueueSize = 256
+
+// ErrConnHandlerNotStarted is returned by ConnHandler.Stop and ConnHandler.Shutdown before Start.
+var ErrConnHandlerNotStarted = errors.New("connection handler is not started")
+
 type ServerEventHandler func(ctx context.Context, event ServerEvent)
 
+// ClientEventHandler is called with the client events sent on a Conn, see WithClientEventHandler.
+type ClientEventHandler func(ctx context.Context, event ClientEvent)
+
 // Conn is a connection to the OpenAI Realtime API.
 type Conn struct {
-	logger Logger
-	conn   WebSocketConn
+	logger     StructuredLogger
+	logPayload bool
+	metrics    Metrics
+	conn       WebSocketConn
+	latency    latencyTracker
+	validate   bool
+	session    sessionState
+	responses  responseCoordinator
+	sent       []ClientEventHandler
+	reader     *connReader
 }
 
 // Close closes the connection.
 func (c *Conn) Close() error {
+	if c.reader != nil {
+		defer c.reader.stop()
+	}
 	return c.conn.Close()
 }
 
+// CloseWithStatus closes the connection with the WebSocket close status code and reason.
+// It's the same as Close if the WebSocketConn doesn't implement WebSocketStatusCloser.
+func (c *Conn) CloseWithStatus(code StatusCode, reason string) error {
+	closer, ok := c.conn.(WebSocketStatusCloser)
+	if !ok {
+		return c.Close()
+	}
+	if c.reader != nil {
+		defer c.reader.stop()
+	}
+	return closer.CloseWithStatus(code, reason)
+}
+
 // SendMessageRaw sends a raw message to the server.
 func (c *Conn) SendMessageRaw(ctx context.Context, data []byte) error {
-	return c.conn.WriteMessage(ctx, MessageText, data)
+	err := c.conn.WriteMessage(ctx, MessageText, data)
+	if err != nil {
+		return err
+	}
+	c.logEvent("sent event", data)
+	return nil
 }
 
 // SendMessage sends a client event to the server.
+// If the connection was created WithValidation, the event is validated before sending.
 func (c *Conn) SendMessage(ctx context.Context, msg ClientEvent) error {
+	if c.validate {
+		if v, ok := msg.(Validator); ok {
+			if err := v.Validate(); err != nil {
+				return err
+			}
+		}
+	}
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return err
 	}
-	return c.SendMessageRaw(ctx, data)
+	err = c.SendMessageRaw(ctx, data)
+	if err != nil {
+		return err
+	}
+	c.observeSent(msg)
+	for _, handler := range c.sent {
+		handler(ctx, msg)
+	}
+	return nil
 }
 
 // ReadMessageRaw reads a raw message from the server.
 func (c *Conn) ReadMessageRaw(ctx context.Context) ([]byte, error) {
-	messageType, data, err := c.conn.ReadMessage(ctx)
+	var messageType MessageType
+	var data []byte
+	var err error
+	if c.reader != nil {
+		messageType, data, err = c.reader.read(ctx)
+	} else {
+		messageType, data, err = c.conn.ReadMessage(ctx)
+	}
 	if err != nil {
 		return nil, err
 	}
 	if messageType != MessageText {
 		return nil, fmt.Errorf("expected text message, got %d", messageType)
 	}
+	c.logEvent("received event", data)
 	return data, nil
 }
 
 // ReadMessage reads a server event from the server.
+// If the WebSocketConn is a WebSocketBufferReader, the message is read into a pooled buffer.
 func (c *Conn) ReadMessage(ctx context.Context) (ServerEvent, error) {
-	data, err := c.ReadMessageRaw(ctx)
+	bufReader, ok := c.conn.(WebSocketBufferReader)
+	if c.reader != nil || !ok {
+		data, err := c.ReadMessageRaw(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return c.decodeMessage(data)
+	}
+
+	buf := getReadBuffer()
+	defer putReadBuffer(buf)
+	messageType, err := bufReader.ReadMessageInto(ctx, buf)
 	if err != nil {
 		return nil, err
 	}
+	if messageType != MessageText {
+		return nil, fmt.Errorf("expected text message, got %d", messageType)
+	}
+	c.logEvent("received event", buf.Bytes())
+	return c.decodeMessage(buf.Bytes())
+}
+
+// decodeMessage unmarshals the server event and observes it.
+func (c *Conn) decodeMessage(data []byte) (ServerEvent, error) {
 	event, err := UnmarshalServerEvent(data)
 	if err != nil {
 		return nil, err
 	}
+	c.observeReceived(event)
+	c.session.observe(event)
+	c.observeResponses(event)
 	return event, nil
 }
 
+// observeResponses lets the response coordinator track the event, and sends the deferred response.create, if any.
+func (c *Conn) observeResponses(event ServerEvent) {
+	failed, next := c.responses.observe(event)
+	if failed != nil && failed.report != nil {
+		failed.report(&ServerError{Detail: event.(ErrorEvent).Error})
+	}
+	c.sendDeferredResponses(next)
+}
+
+// logEvent logs the type and identifiers of the event at debug level.
+func (c *Conn) logEvent(msg string, data []byte) {
+	if c.logger == nil || !c.logger.Enabled(LogLevelDebug) {
+		return
+	}
+	kv := eventLogAttrs(data)
+	if c.logPayload {
+		kv = append(kv, "payload", string(RedactPayload(data)))
+	}
+	c.logger.Log(LogLevelDebug, msg, kv...)
+}
+
 // Ping sends a ping message to the WebSocket connection.
 func (c *Conn) Ping(ctx context.Context) error {
 	return c.conn.Ping(ctx)
@@ -71,29 +179,120 @@ func (c *Conn) Ping(ctx context.Context) error {
 // Users should not call ReadMessage directly when using ConnHandler.
 type ConnHandler struct {
 	ctx      context.Context
+	cancel   context.CancelFunc
 	conn     *Conn
 	handlers []ServerEventHandler
 	errCh    chan error
+	done     chan struct{}
+	err      error
+
+	middlewares []Middleware
+	dispatch    DispatchOptions
+	queues      []chan ServerEvent
+	workers     sync.WaitGroup
+
+	mu      sync.Mutex
+	started bool
+	// closing is set once Shutdown closes the connection, the read errors are expected from then.
+	closing   bool
+	responses map[string]struct{}
+	changed   chan struct{}
+}
+
+// DispatchOptions configures how a ConnHandler calls its handlers, see ConnHandler.SetDispatch.
+type DispatchOptions struct {
+	// Workers is the number of goroutines calling the handlers. Zero calls them on the reading goroutine.
+	//
+	// The events of a response, or else of an item, are handled by the same worker in the order
+	// they are read. The events without response or item, such as the session and input audio buffer
+	// events, are handled in order by one worker. The order across responses and items is not kept.
+	Workers int
+
+	// QueueSize is the number of events buffered by each worker. Default is 256.
+	QueueSize int
+
+	// Overflow is applied when the queue of a worker is full. Default is OverflowBlock, which stops
+	// reading until there's room. The dropped events are reported to Metrics.EventDropped.
+	Overflow OverflowPolicy
+}
+
+// ShutdownOptions configures ConnHandler.Shutdown.
+type ShutdownOptions struct {
+	// WaitResponse waits for the responses in progress to be done before closing the connection.
+	WaitResponse bool
+
+	// Code is the WebSocket close status code. Default is StatusNormalClosure.
+	Code StatusCode
+
+	// Reason is the WebSocket close reason.
+	Reason string
 }
 
 // NewConnHandler creates a new ConnHandler with the given context and connection.
 func NewConnHandler(ctx context.Context, conn *Conn, handlers ...ServerEventHandler) *ConnHandler {
+	ctx, cancel := context.WithCancel(ctx)
 	return &ConnHandler{
-		ctx:      ctx,
-		conn:     conn,
-		handlers: handlers,
-		errCh:    make(chan error, 1),
+		ctx:       ctx,
+		cancel:    cancel,
+		conn:      conn,
+		handlers:  handlers,
+		errCh:     make(chan error, 1),
+		done:      make(chan struct{}),
+		responses: make(map[string]struct{}),
+		changed:   make(chan struct{}),
 	}
 }
 
+// Use adds middlewares wrapping the handlers, the first one being the outermost.
+// The handlers are wrapped together, e.g. a panic recovered by RecoverMiddleware skips the
+// remaining handlers of the event. It must be called before Start.
+func (c *ConnHandler) Use(middlewares ...Middleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// SetDispatch sets how the handlers are called, see DispatchOptions. It must be called before Start.
+func (c *ConnHandler) SetDispatch(opts DispatchOptions) {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultDispatchQueueSize
+	}
+	c.dispatch = opts
+}
+
 // Start starts the ConnHandler.
 func (c *ConnHandler) Start() {
+	c.mu.Lock()
+	c.started = true
+	c.mu.Unlock()
+
+	handle := c.handle
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		handle = c.middlewares[i](handle)
+	}
+	for i := 0; i < c.dispatch.Workers; i++ {
+		queue := make(chan ServerEvent, c.dispatch.QueueSize)
+		c.queues = append(c.queues, queue)
+		c.workers.Add(1)
+		go func() {
+			defer c.workers.Done()
+			for event := range queue {
+				handle(c.ctx, event)
+			}
+		}()
+	}
+
 	go func() {
-		err := c.run()
+		err := c.run(handle)
+		for _, queue := range c.queues {
+			close(queue)
+		}
+		c.workers.Wait()
+		c.err = err
 		if err != nil {
 			c.errCh <- err
 		}
 		close(c.errCh)
+		c.cancel()
+		close(c.done)
 	}()
 }
 
@@ -105,16 +304,191 @@ func (c *ConnHandler) Err() <-chan error {
 	return c.errCh
 }
 
-func (c *ConnHandler) run() error {
+// Wait waits for the ConnHandler to stop, and returns its terminal error: nil if the connection was
+// closed by Stop or Shutdown, the error of the context if it's done, otherwise the read error, which is
+// a *CloseError carrying the close status code and reason if the server closed the connection,
+// even while Shutdown waits for the responses in progress.
+func (c *ConnHandler) Wait() error {
+	<-c.done
+	return c.err
+}
+
+// Stop stops reading, closes the connection with StatusNormalClosure and waits for the ConnHandler to stop.
+// The responses in progress are not waited for, see Shutdown.
+func (c *ConnHandler) Stop() error {
+	return c.Shutdown(context.Background(), ShutdownOptions{})
+}
+
+// Shutdown stops the ConnHandler gracefully: if opts.WaitResponse is set, it keeps reading until
+// the responses in progress are done, then it closes the connection with the status of opts and
+// waits for the ConnHandler to stop. If ctx is done first, the connection is closed anyway and
+// ctx.Err() is returned. It returns ErrConnHandlerNotStarted before Start.
+func (c *ConnHandler) Shutdown(ctx context.Context, opts ShutdownOptions) error {
+	c.mu.Lock()
+	if !c.started {
+		c.mu.Unlock()
+		return ErrConnHandlerNotStarted
+	}
+	c.mu.Unlock()
+
+	var err error
+	if opts.WaitResponse {
+		err = c.waitResponses(ctx)
+	}
+	if opts.Code == 0 {
+		opts.Code = StatusNormalClosure
+	}
+	c.mu.Lock()
+	c.closing = true
+	c.mu.Unlock()
+	select {
+	case <-c.done:
+	default:
+		if closeErr := c.conn.CloseWithStatus(opts.Code, opts.Reason); err == nil {
+			err = closeErr
+		}
+	}
+	c.cancel()
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	return err
+}
+
+// waitResponses waits until no response is in progress.
+func (c *ConnHandler) waitResponses(ctx context.Context) error {
+	for {
+		c.mu.Lock()
+		if len(c.responses) == 0 {
+			c.mu.Unlock()
+			return nil
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-c.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ActiveResponses returns the IDs of the responses created and not done yet, sorted.
+func (c *ConnHandler) ActiveResponses() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ids []string
+	for id := range c.responses {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// trackResponses tracks the responses in progress, which Shutdown waits for.
+func (c *ConnHandler) trackResponses(event ServerEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch e := event.(type) {
+	case ResponseCreatedEvent:
+		c.responses[e.Response.ID] = struct{}{}
+	case ResponseDoneEvent:
+		delete(c.responses, e.Response.ID)
+	default:
+		return
+	}
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// isClosing reports whether the connection is closed by Shutdown, so that the read errors are its own.
+func (c *ConnHandler) isClosing() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closing
+}
+
+// handle calls the handlers in order.
+func (c *ConnHandler) handle(ctx context.Context, event ServerEvent) {
+	for _, handler := range c.handlers {
+		handler(ctx, event)
+	}
+}
+
+// enqueue queues the event to the worker of its response or item.
+func (c *ConnHandler) enqueue(event ServerEvent) {
+	key, itemID := serverEventIDs(event)
+	if key == "" {
+		key = itemID
+	}
+	queue := c.queues[fnv32a(key)%uint32(len(c.queues))]
+
+	switch c.dispatch.Overflow {
+	case OverflowDropNewest:
+		select {
+		case queue <- event:
+		default:
+			c.conn.metrics.EventDropped(event.ServerEventType())
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case queue <- event:
+				return
+			default:
+			}
+			select {
+			case dropped := <-queue:
+				c.conn.metrics.EventDropped(dropped.ServerEventType())
+			default:
+			}
+		}
+	default:
+		select {
+		case queue <- event:
+		case <-c.ctx.Done():
+		}
+	}
+}
+
+// fnv32a returns the FNV-1a hash of s, without the allocations of hash/fnv on the read path.
+func fnv32a(s string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	hash := uint32(offset32)
+	for i := 0; i < len(s); i++ {
+		hash ^= uint32(s[i])
+		hash *= prime32
+	}
+	return hash
+}
+
+func (c *ConnHandler) run(handle ServerEventHandler) error {
 	for {
 		select {
 		case <-c.ctx.Done():
+			if c.isClosing() {
+				return nil
+			}
 			return c.ctx.Err()
 		default:
 		}
 
 		msg, err := c.conn.ReadMessage(c.ctx)
 		if err != nil {
+			if c.isClosing() {
+				return nil
+			}
 			var permanent *PermanentError
 			if errors.As(err, &permanent) {
 				return permanent.Err
@@ -122,8 +496,11 @@ func (c *ConnHandler) run() error {
 			c.conn.logger.Warnf("read message temporary error: %+v", err)
 			continue
 		}
-		for _, handler := range c.handlers {
-			handler(c.ctx, msg)
+		c.trackResponses(msg)
+		if len(c.queues) > 0 {
+			c.enqueue(msg)
+		} else {
+			handle(c.ctx, msg)
 		}
 	}
 }
@@ -2,10 +2,16 @@ package openairt_test
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/coder/websocket"
 	"github.com/stretchr/testify/require"
 )
 
@@ -72,3 +78,213 @@ func TestConnect(t *testing.T) {
 	require.True(t, dialCalled)
 	require.False(t, readMessageCalled)
 }
+
+// fakeServer is a mock connection which replies to every client event with the server events
+// returned by respond, and records the client events.
+type fakeServer struct {
+	mu       sync.Mutex
+	sent     [][]byte
+	messages chan []byte
+	respond  func(event map[string]any) []string
+}
+
+// newFakeServer connects a Conn to a fakeServer which sends the initial server events first.
+func newFakeServer(
+	t *testing.T,
+	respond func(event map[string]any) []string,
+	initial []string,
+	opts ...openairt.ConnectOption,
+) (*openairt.Conn, *fakeServer) {
+	t.Helper()
+	server := &fakeServer{messages: make(chan []byte, 1024), respond: respond}
+	for _, msg := range initial {
+		server.messages <- []byte(msg)
+	}
+	dialer := &mockDialer{
+		dialFunc: func(_ context.Context, _ string, _ http.Header) (openairt.WebSocketConn, error) {
+			return &mockWebSocketConn{
+				readMessageFunc: func(ctx context.Context) (openairt.MessageType, []byte, error) {
+					select {
+					case <-ctx.Done():
+						return 0, nil, openairt.Permanent(ctx.Err())
+					case msg := <-server.messages:
+						return openairt.MessageText, msg, nil
+					}
+				},
+				writeMessageFunc: func(_ context.Context, _ openairt.MessageType, data []byte) error {
+					var event map[string]any
+					if err := json.Unmarshal(data, &event); err != nil {
+						return err
+					}
+					server.mu.Lock()
+					server.sent = append(server.sent, data)
+					server.mu.Unlock()
+					if server.respond != nil {
+						for _, msg := range server.respond(event) {
+							server.messages <- []byte(msg)
+						}
+					}
+					return nil
+				},
+				closeFunc: func() error { return nil },
+				pingFunc:  func(_ context.Context) error { return nil },
+			}, nil
+		},
+	}
+	opts = append([]openairt.ConnectOption{openairt.WithDialer(dialer)}, opts...)
+	conn, err := openairt.NewClient("token").Connect(context.Background(), opts...)
+	require.NoError(t, err)
+	return conn, server
+}
+
+// push sends server events to the client.
+func (s *fakeServer) push(messages ...string) {
+	for _, msg := range messages {
+		s.messages <- []byte(msg)
+	}
+}
+
+// events returns the client events sent so far, decoded as JSON objects.
+func (s *fakeServer) events() []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := make([]map[string]any, len(s.sent))
+	for i, data := range s.sent {
+		_ = json.Unmarshal(data, &events[i])
+	}
+	return events
+}
+
+// dialCoderServer connects a Conn to a WebSocket server running handle.
+func dialCoderServer(tb testing.TB, handle func(c *websocket.Conn)) *openairt.Conn {
+	tb.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		handle(c)
+	}))
+	tb.Cleanup(server.Close)
+	dialer := &mockDialer{
+		dialFunc: func(ctx context.Context, _ string, header http.Header) (openairt.WebSocketConn, error) {
+			return openairt.DefaultDialer().Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), header)
+		},
+	}
+	conn, err := openairt.NewClient("token").Connect(context.Background(), openairt.WithDialer(dialer))
+	require.NoError(tb, err)
+	return conn
+}
+
+func TestConnHandlerCloseError(t *testing.T) {
+	conn := dialCoderServer(t, func(c *websocket.Conn) {
+		_ = c.Write(context.Background(), websocket.MessageText, []byte(`{"type":"input_audio_buffer.cleared"}`))
+		_ = c.Close(websocket.StatusPolicyViolation, "bye")
+	})
+	handlerCtx := make(chan context.Context, 1)
+	handler := openairt.NewConnHandler(context.Background(), conn, func(ctx context.Context, _ openairt.ServerEvent) {
+		handlerCtx <- ctx
+	})
+	require.ErrorIs(t, handler.Stop(), openairt.ErrConnHandlerNotStarted)
+	handler.Start()
+	err := handler.Wait()
+	var closeErr *openairt.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	require.Equal(t, openairt.StatusPolicyViolation, closeErr.Code)
+	require.Equal(t, "bye", closeErr.Reason)
+	require.Equal(t, err, <-handler.Err())
+	// The context of the handlers is released once the ConnHandler stopped.
+	require.ErrorIs(t, (<-handlerCtx).Err(), context.Canceled)
+}
+
+func TestConnHandlerShutdownStatus(t *testing.T) {
+	status := make(chan websocket.StatusCode, 1)
+	conn := dialCoderServer(t, func(c *websocket.Conn) {
+		_, _, err := c.Read(context.Background())
+		status <- websocket.CloseStatus(err)
+	})
+	handler := openairt.NewConnHandler(context.Background(), conn)
+	handler.Start()
+	require.NoError(t, handler.Shutdown(context.Background(), openairt.ShutdownOptions{
+		Code:   openairt.StatusGoingAway,
+		Reason: "restart",
+	}))
+	require.Equal(t, websocket.StatusGoingAway, <-status)
+	require.NoError(t, handler.Wait())
+}
+
+func TestConnHandlerShutdownWaitResponse(t *testing.T) {
+	conn, server := newFakeServer(t, nil, nil)
+	handler := openairt.NewConnHandler(context.Background(), conn)
+	handler.Start()
+	server.push(`{"type":"response.created","response":{"id":"resp_1","status":"in_progress"}}`)
+
+	time.Sleep(10 * time.Millisecond)
+
+	// The response in progress is waited for until ctx is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, handler.Shutdown(ctx, openairt.ShutdownOptions{WaitResponse: true}), context.DeadlineExceeded)
+	require.NoError(t, handler.Wait())
+
+	conn, server = newFakeServer(t, nil, nil)
+	handler = openairt.NewConnHandler(context.Background(), conn)
+	handler.Start()
+	server.push(`{"type":"response.created","response":{"id":"resp_1","status":"in_progress"}}`)
+	time.Sleep(10 * time.Millisecond)
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- handler.Shutdown(context.Background(), openairt.ShutdownOptions{WaitResponse: true})
+	}()
+	select {
+	case <-shutdown:
+		t.Fatal("shutdown didn't wait for the response")
+	case <-time.After(20 * time.Millisecond):
+	}
+	server.push(responseDoneEvent("resp_1", 10, 10))
+	require.NoError(t, <-shutdown)
+	require.NoError(t, handler.Wait())
+}
+
+func TestConnHandlerShutdownPeerClose(t *testing.T) {
+	shutdown := make(chan struct{})
+	conn := dialCoderServer(t, func(c *websocket.Conn) {
+		ctx := context.Background()
+		_ = c.Write(ctx, websocket.MessageText,
+			[]byte(`{"type":"response.created","response":{"id":"resp_1","status":"in_progress"}}`))
+		<-shutdown
+		_ = c.Close(websocket.StatusInternalError, "boom")
+	})
+	handler := openairt.NewConnHandler(context.Background(), conn)
+	handler.Start()
+	require.Eventually(t, func() bool { return len(handler.ActiveResponses()) == 1 }, time.Second, time.Millisecond)
+
+	// The server closing the connection while the responses are waited for is the terminal error.
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.Shutdown(context.Background(), openairt.ShutdownOptions{WaitResponse: true})
+	}()
+	close(shutdown)
+	<-done
+	var closeErr *openairt.CloseError
+	require.ErrorAs(t, handler.Wait(), &closeErr)
+	require.Equal(t, openairt.StatusInternalError, closeErr.Code)
+	require.Equal(t, "boom", closeErr.Reason)
+}
+
+func TestConnHandlerStop(t *testing.T) {
+	conn, _ := newFakeServer(t, nil, nil)
+	handler := openairt.NewConnHandler(context.Background(), conn)
+	handler.Start()
+	require.NoError(t, handler.Stop())
+	require.NoError(t, handler.Wait())
+	_, ok := <-handler.Err()
+	require.False(t, ok)
+
+	// The context error is the terminal error if it's done first.
+	ctx, cancel := context.WithCancel(context.Background())
+	handler = openairt.NewConnHandler(ctx, conn)
+	handler.Start()
+	cancel()
+	require.ErrorIs(t, handler.Wait(), context.Canceled)
+}
@@ -0,0 +1,532 @@
+package openairt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultContextKeepItems = 4
+
+	// contextSummaryMetadataKey marks the out-of-band responses summarizing pruned items.
+	contextSummaryMetadataKey = "openairt_context_summary"
+
+	defaultContextSummaryInstructions = "Summarize the following conversation in a few sentences, " +
+		"keeping the facts, decisions and open questions needed to continue it. Reply with the summary only."
+)
+
+// ContextManagerOptions configures a ContextManager.
+type ContextManagerOptions struct {
+	// MaxTokens is the budget of the conversation context, it's pruned when exceeded.
+	MaxTokens int
+
+	// TargetTokens is the size the context is pruned down to. Default is 3/4 of MaxTokens.
+	TargetTokens int
+
+	// KeepItems is the number of most recent items which are never pruned. Default is 4.
+	KeepItems int
+
+	// Summarize replaces the pruned items with a system message summarizing them, produced by an
+	// out-of-band response and inserted at the beginning of the conversation. Otherwise they're deleted.
+	Summarize bool
+
+	// SummaryInstructions are the instructions of the summary response.
+	SummaryInstructions string
+
+	// OnPrune is called after the items are pruned.
+	OnPrune func(ContextPrune)
+}
+
+// ContextPrune reports a pruning of the conversation.
+type ContextPrune struct {
+	// ItemIDs are the ids of the deleted items.
+	ItemIDs []string
+	// Tokens is the estimated size of the deleted items.
+	Tokens int
+	// Summary replacing the items, empty if not summarized.
+	Summary string
+	// Err is the error of the summary response or of sending the events, if any.
+	Err error
+}
+
+// ContextItem is a conversation item tracked by a ContextManager.
+type ContextItem struct {
+	ID     string
+	Type   MessageItemType
+	Role   MessageRole
+	CallID string
+	// Tokens is the approximate size of the item, estimated from its text until
+	// it's measured by the input token usage of a response.
+	Tokens int
+	// Measured reports whether Tokens comes from the usage of a response.
+	Measured bool
+}
+
+// ContextManager tracks the conversation items and their approximate token cost, and prunes the
+// oldest items when the context exceeds a budget, deleting them or replacing them with a summary.
+// Function calls and their outputs are pruned together.
+//
+// The costs are measured by the input token usage of each response.done: the increase of the
+// input tokens is shared by the items added since the previous response, and the output tokens
+// by the items of the response.
+//
+// Its HandleEvent must be registered on the ConnHandler of the connection.
+type ContextManager struct {
+	conn *Conn
+	opts ContextManagerOptions
+
+	mu    sync.Mutex
+	items []*ContextItem
+	// content is the text of the items, used for the summary.
+	content map[string]string
+	// overhead is the input tokens not attributed to items, e.g. the instructions and tools.
+	overhead int
+	measured bool
+	// summary is the pending summary, if any.
+	summary *contextSummary
+}
+
+type contextSummary struct {
+	key    string
+	items  []string
+	tokens int
+}
+
+// contextPlan is a pruning decided under the lock, whose events are sent after unlocking.
+type contextPlan struct {
+	// request is the summary response to create, the items are deleted without a summary if it fails.
+	request *ResponseCreateEvent
+	key     string
+	// summary is the text inserted before deleting the items, if any.
+	summary string
+	ids     []string
+	tokens  int
+	err     error
+}
+
+// NewContextManager creates a ContextManager on conn.
+func NewContextManager(conn *Conn, opts ContextManagerOptions) *ContextManager {
+	if opts.TargetTokens <= 0 || opts.TargetTokens > opts.MaxTokens {
+		opts.TargetTokens = opts.MaxTokens * 3 / 4 //nolint:mnd // 75% of the budget
+	}
+	if opts.KeepItems <= 0 {
+		opts.KeepItems = defaultContextKeepItems
+	}
+	if opts.SummaryInstructions == "" {
+		opts.SummaryInstructions = defaultContextSummaryInstructions
+	}
+	return &ContextManager{
+		conn:    conn,
+		opts:    opts,
+		content: make(map[string]string),
+	}
+}
+
+// Items returns the tracked items in the order of the conversation.
+func (m *ContextManager) Items() []ContextItem {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	items := make([]ContextItem, 0, len(m.items))
+	for _, item := range m.items {
+		items = append(items, *item)
+	}
+	return items
+}
+
+// Tokens returns the approximate size of the context.
+func (m *ContextManager) Tokens() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tokens()
+}
+
+// HandleEvent is the ServerEventHandler of the ContextManager.
+func (m *ContextManager) HandleEvent(ctx context.Context, event ServerEvent) {
+	m.mu.Lock()
+	var plan *contextPlan
+	switch e := event.(type) {
+	case ConversationItemAddedEvent:
+		m.update(e.Item, e.PreviousItemID)
+	case ConversationItemDoneEvent:
+		m.update(e.Item, e.PreviousItemID)
+	case ConversationItemInputAudioTranscriptionCompletedEvent:
+		if item := m.item(e.ItemID); item != nil {
+			m.content[e.ItemID] = e.Transcript
+			if !item.Measured {
+				item.Tokens = estimateTokens(e.Transcript)
+			}
+		}
+	case ConversationItemDeletedEvent:
+		m.remove(e.ItemID)
+	case ResponseDoneEvent:
+		if key := e.Response.Metadata[contextSummaryMetadataKey]; key != "" {
+			plan = m.summarized(key, e.Response)
+			break
+		}
+		m.measure(e.Response)
+		plan = m.prune()
+	}
+	m.mu.Unlock()
+
+	prune := m.send(ctx, plan)
+	if prune != nil && m.opts.OnPrune != nil {
+		m.opts.OnPrune(*prune)
+	}
+}
+
+// Prune prunes the context down to the target if it exceeds the budget. It's called on every
+// response.done, call it to prune after changing the items without a response.
+func (m *ContextManager) Prune(ctx context.Context) {
+	m.mu.Lock()
+	plan := m.prune()
+	m.mu.Unlock()
+	prune := m.send(ctx, plan)
+	if prune != nil && m.opts.OnPrune != nil {
+		m.opts.OnPrune(*prune)
+	}
+}
+
+func (m *ContextManager) tokens() int {
+	tokens := m.overhead
+	for _, item := range m.items {
+		tokens += item.Tokens
+	}
+	return tokens
+}
+
+func (m *ContextManager) item(id string) *ContextItem {
+	for _, item := range m.items {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
+
+// update tracks a new item after previousItemID, or updates the estimate of a tracked one.
+func (m *ContextManager) update(message MessageItemUnion, previousItemID string) {
+	id := messageItemID(message)
+	text := contextItemText(message)
+	m.content[id] = text
+	if item := m.item(id); item != nil {
+		if !item.Measured {
+			item.Tokens = estimateTokens(text)
+		}
+		return
+	}
+
+	item := &ContextItem{ID: id, Tokens: estimateTokens(text)}
+	switch message.Kind() {
+	case MessageItemKindSystem:
+		item.Type, item.Role = message.System.MessageItemType(), message.System.Role()
+	case MessageItemKindUser:
+		item.Type, item.Role = message.User.MessageItemType(), message.User.Role()
+	case MessageItemKindAssistant:
+		item.Type, item.Role = message.Assistant.MessageItemType(), message.Assistant.Role()
+	case MessageItemKindFunctionCall:
+		item.Type, item.CallID = message.FunctionCall.MessageItemType(), message.FunctionCall.CallID
+	case MessageItemKindFunctionCallOutput:
+		item.Type, item.CallID = message.FunctionCallOutput.MessageItemType(), message.FunctionCallOutput.CallID
+	case MessageItemKindMCPToolCall:
+		item.Type = message.MCPToolCall.MessageItemType()
+	case MessageItemKindMCPListTools:
+		item.Type = message.MCPListTools.MessageItemType()
+	case MessageItemKindMCPApprovalRequest:
+		item.Type = message.MCPApprovalRequest.MessageItemType()
+	case MessageItemKindMCPApprovalResponse:
+		item.Type = message.MCPApprovalResponse.MessageItemType()
+	}
+
+	index := len(m.items)
+	if previousItemID == PreviousItemIDRoot {
+		index = 0
+	} else if previousItemID != "" {
+		for i, prev := range m.items {
+			if prev.ID == previousItemID {
+				index = i + 1
+				break
+			}
+		}
+	}
+	m.items = append(m.items, nil)
+	copy(m.items[index+1:], m.items[index:])
+	m.items[index] = item
+}
+
+func (m *ContextManager) remove(id string) {
+	for i, item := range m.items {
+		if item.ID == id {
+			m.items = append(m.items[:i], m.items[i+1:]...)
+			break
+		}
+	}
+	delete(m.content, id)
+}
+
+// removeAll stops tracking the items.
+func (m *ContextManager) removeAll(ids []string) {
+	for _, id := range ids {
+		m.remove(id)
+	}
+}
+
+// measure shares the input tokens of the response between the items added since the previous one,
+// and the output tokens between the output items.
+func (m *ContextManager) measure(resp Response) {
+	if resp.Usage == nil {
+		return
+	}
+	outputs := make(map[string]bool, len(resp.Output))
+	for _, item := range resp.Output {
+		outputs[messageItemID(item)] = true
+	}
+	var inputs, outputItems []*ContextItem
+	measuredInput := m.overhead
+	for _, item := range m.items {
+		switch {
+		case outputs[item.ID]:
+			outputItems = append(outputItems, item)
+		case item.Measured:
+			measuredInput += item.Tokens
+		default:
+			inputs = append(inputs, item)
+		}
+	}
+
+	added := resp.Usage.InputTokens - measuredInput
+	if !m.measured {
+		// The input of the first response includes the instructions and tools.
+		estimated := 0
+		for _, item := range inputs {
+			estimated += item.Tokens
+		}
+		if added > estimated {
+			m.overhead += added - estimated
+			added = estimated
+		}
+		m.measured = true
+	}
+	shareTokens(inputs, added)
+	shareTokens(outputItems, resp.Usage.OutputTokens)
+}
+
+// shareTokens shares tokens between items in proportion to their estimates.
+func shareTokens(items []*ContextItem, tokens int) {
+	if len(items) == 0 || tokens < 0 {
+		return
+	}
+	estimated := 0
+	for _, item := range items {
+		estimated += item.Tokens
+	}
+	remaining := tokens
+	for i, item := range items {
+		share := tokens / len(items)
+		if estimated > 0 {
+			share = tokens * item.Tokens / estimated
+		}
+		if i == len(items)-1 {
+			share = remaining
+		}
+		item.Tokens = share
+		item.Measured = true
+		remaining -= share
+	}
+}
+
+// prune plans the deletion or the summary of the oldest items if the context exceeds the budget.
+func (m *ContextManager) prune() *contextPlan {
+	if m.opts.MaxTokens <= 0 || m.summary != nil || m.tokens() <= m.opts.MaxTokens {
+		return nil
+	}
+	ids, tokens := m.selectPrunable()
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if m.opts.Summarize {
+		key := GenerateID("sum_", eventIDLength)
+		m.summary = &contextSummary{key: key, items: ids, tokens: tokens}
+		return &contextPlan{
+			request: &ResponseCreateEvent{Response: ResponseCreateParams{
+				Conversation:     "none",
+				OutputModalities: []Modality{ModalityText},
+				Instructions:     m.opts.SummaryInstructions,
+				Metadata:         map[string]string{contextSummaryMetadataKey: key},
+				Input: []MessageItemUnion{{User: &MessageItemUser{Content: []MessageContentInput{{
+					Type: MessageContentTypeInputText,
+					Text: m.render(ids),
+				}}}}},
+			}},
+			key:    key,
+			ids:    ids,
+			tokens: tokens,
+		}
+	}
+	m.removeAll(ids)
+	return &contextPlan{ids: ids, tokens: tokens}
+}
+
+// selectPrunable returns the oldest items to prune down to the target, keeping the most recent
+// items and the function calls with their outputs.
+func (m *ContextManager) selectPrunable() ([]string, int) {
+	candidates := len(m.items) - m.opts.KeepItems
+	if candidates <= 0 {
+		return nil, 0
+	}
+	kept := make(map[string]bool)
+	for _, item := range m.items[candidates:] {
+		if item.CallID != "" {
+			kept[item.CallID] = true
+		}
+	}
+
+	excess := m.tokens() - m.opts.TargetTokens
+	var ids []string
+	tokens := 0
+	for _, item := range m.items[:candidates] {
+		if tokens >= excess {
+			break
+		}
+		if item.CallID != "" && kept[item.CallID] {
+			// Its call or output is kept.
+			continue
+		}
+		ids = append(ids, item.ID)
+		tokens += item.Tokens
+	}
+	// Complete the pairs whose other half wasn't reached.
+	selected := make(map[string]bool, len(ids))
+	calls := make(map[string]bool)
+	for _, id := range ids {
+		selected[id] = true
+		if item := m.item(id); item.CallID != "" {
+			calls[item.CallID] = true
+		}
+	}
+	for _, item := range m.items[:candidates] {
+		if !selected[item.ID] && item.CallID != "" && calls[item.CallID] {
+			ids = append(ids, item.ID)
+			tokens += item.Tokens
+		}
+	}
+	return ids, tokens
+}
+
+// render renders the items as a plain text conversation for the summary.
+func (m *ContextManager) render(ids []string) string {
+	selected := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		selected[id] = true
+	}
+	var sb strings.Builder
+	for _, item := range m.items {
+		if !selected[item.ID] {
+			continue
+		}
+		label := string(item.Role)
+		if label == "" {
+			label = string(item.Type)
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", label, m.content[item.ID])
+	}
+	return sb.String()
+}
+
+// summarized plans the insertion of the summary of the response and the deletion of the summarized items.
+func (m *ContextManager) summarized(key string, resp Response) *contextPlan {
+	summary := m.summary
+	if summary == nil || summary.key != key {
+		return nil
+	}
+	m.summary = nil
+	m.removeAll(summary.items)
+
+	var texts []string
+	for _, item := range resp.Output {
+		if text := messageItemText(item); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	plan := &contextPlan{summary: strings.Join(texts, "\n"), ids: summary.items, tokens: summary.tokens}
+	if resp.Status != ResponseStatusCompleted || plan.summary == "" {
+		plan.err = fmt.Errorf("context summary response %s: %s", resp.ID, resp.Status)
+	}
+	return plan
+}
+
+// send sends the events of the plan, it must be called without the lock.
+func (m *ContextManager) send(ctx context.Context, plan *contextPlan) *ContextPrune {
+	if plan == nil {
+		return nil
+	}
+	if plan.request != nil {
+		err := m.conn.SendMessage(ctx, *plan.request)
+		if err == nil {
+			return nil
+		}
+		// Delete the items without a summary.
+		m.mu.Lock()
+		if m.summary != nil && m.summary.key == plan.key {
+			m.summary = nil
+		}
+		m.removeAll(plan.ids)
+		m.mu.Unlock()
+		prune := m.delete(ctx, plan.ids, plan.tokens)
+		if prune.Err == nil {
+			prune.Err = err
+		}
+		return prune
+	}
+
+	err := plan.err
+	if err == nil && plan.summary != "" {
+		err = m.conn.SendMessage(ctx, ConversationItemCreateEvent{
+			PreviousItemID: PreviousItemIDRoot,
+			Item: MessageItemUnion{System: &MessageItemSystem{
+				Content: []MessageContentSystem{{Text: plan.summary}},
+			}},
+		})
+	}
+	prune := m.delete(ctx, plan.ids, plan.tokens)
+	if err != nil {
+		prune.Err = err
+	} else {
+		prune.Summary = plan.summary
+	}
+	return prune
+}
+
+// delete sends the deletion of the items.
+func (m *ContextManager) delete(ctx context.Context, ids []string, tokens int) *ContextPrune {
+	prune := &ContextPrune{ItemIDs: ids, Tokens: tokens}
+	for _, id := range ids {
+		if err := m.conn.SendMessage(ctx, ConversationItemDeleteEvent{ItemID: id}); err != nil && prune.Err == nil {
+			prune.Err = err
+		}
+	}
+	return prune
+}
+
+// contextItemText returns the text of an item for the token estimate and the summary.
+func contextItemText(item MessageItemUnion) string {
+	switch {
+	case item.FunctionCall != nil:
+		return fmt.Sprintf("call %s(%s)", item.FunctionCall.Name, item.FunctionCall.Arguments)
+	case item.FunctionCallOutput != nil:
+		return "result " + item.FunctionCallOutput.Output
+	case item.MCPToolCall != nil:
+		return fmt.Sprintf("call %s.%s(%s) %s",
+			item.MCPToolCall.ServerLabel, item.MCPToolCall.Name, item.MCPToolCall.Arguments, item.MCPToolCall.Output)
+	default:
+		return messageItemText(item)
+	}
+}
+
+// estimateTokens estimates the tokens of a text, about 4 characters per token.
+func estimateTokens(text string) int {
+	n := len([]rune(text))
+	return (n + 3) / 4 //nolint:mnd // 4 characters per token
+}
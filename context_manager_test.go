@@ -0,0 +1,136 @@
+package openairt_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func itemAddedEvent(item string) string {
+	return fmt.Sprintf(`{"type":"conversation.item.added","item":%s}`, item)
+}
+
+func responseDoneEvent(id string, input, output int, items ...string) string {
+	return fmt.Sprintf(`{"type":"response.done","response":{"id":%q,"status":"completed","output":[%s],`+
+		`"usage":{"total_tokens":%d,"input_tokens":%d,"output_tokens":%d}}}`,
+		id, strings.Join(items, ","), input+output, input, output)
+}
+
+// contextEvents builds a conversation of 6 items exceeding a budget of 100 tokens on the last response.
+func contextEvents() []string {
+	user1 := `{"id":"item_1","type":"message","role":"user","content":[{"type":"input_text","text":"` +
+		strings.Repeat("a", 40) + `"}]}`
+	assistant2 := `{"id":"item_2","type":"message","role":"assistant","content":[{"type":"output_text","text":"hello"}]}`
+	user3 := `{"id":"item_3","type":"message","role":"user","content":[{"type":"input_text","text":"weather?"}]}`
+	call4 := `{"id":"item_4","type":"function_call","call_id":"call_1","name":"weather","arguments":"{}"}`
+	output5 := `{"id":"item_5","type":"function_call_output","call_id":"call_1","output":"sunny"}`
+	assistant6 := `{"id":"item_6","type":"message","role":"assistant","content":[{"type":"output_text","text":"It's sunny"}]}`
+	return []string{
+		itemAddedEvent(user1),
+		itemAddedEvent(assistant2),
+		// The first input includes 40 tokens of instructions.
+		responseDoneEvent("resp_1", 50, 10, assistant2),
+		itemAddedEvent(user3),
+		itemAddedEvent(call4),
+		responseDoneEvent("resp_2", 70, 5, call4),
+		itemAddedEvent(output5),
+		itemAddedEvent(assistant6),
+		responseDoneEvent("resp_3", 100, 20, assistant6),
+	}
+}
+
+func sentOfType(server *fakeServer, eventType string) []map[string]any {
+	var events []map[string]any
+	for _, e := range server.events() {
+		if e["type"] == eventType {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func TestContextManagerDelete(t *testing.T) {
+	var manager *openairt.ContextManager
+	var tracked []int
+	// The events are sent without holding the lock of the manager.
+	conn, server := newFakeServer(t, func(map[string]any) []string {
+		tracked = append(tracked, len(manager.Items()))
+		return nil
+	}, nil)
+	var prunes []openairt.ContextPrune
+	manager = openairt.NewContextManager(conn, openairt.ContextManagerOptions{
+		MaxTokens: 100,
+		KeepItems: 2,
+		OnPrune:   func(p openairt.ContextPrune) { prunes = append(prunes, p) },
+	})
+	events := contextEvents()
+	handleServerEvents(t, manager.HandleEvent, events[:6]...)
+	require.Equal(t, 75, manager.Tokens())
+	require.Empty(t, server.events())
+
+	handleServerEvents(t, manager.HandleEvent, events[6:]...)
+	// The call is kept with its output, which is one of the 2 most recent items.
+	require.Equal(t, []openairt.ContextPrune{{ItemIDs: []string{"item_1", "item_2", "item_3"}, Tokens: 30}}, prunes)
+	var deleted []any
+	for _, e := range sentOfType(server, "conversation.item.delete") {
+		deleted = append(deleted, e["item_id"])
+	}
+	require.Equal(t, []any{"item_1", "item_2", "item_3"}, deleted)
+	require.Equal(t, []int{3, 3, 3}, tracked)
+
+	items := manager.Items()
+	require.Len(t, items, 3)
+	require.Equal(t, openairt.ContextItem{
+		ID: "item_5", Type: openairt.MessageItemTypeFunctionCallOutput, CallID: "call_1", Tokens: 25, Measured: true,
+	}, items[1])
+	require.Equal(t, 90, manager.Tokens())
+}
+
+func TestContextManagerSummarize(t *testing.T) {
+	conn, server := newFakeServer(t, nil, nil)
+	var prunes []openairt.ContextPrune
+	manager := openairt.NewContextManager(conn, openairt.ContextManagerOptions{
+		MaxTokens: 100,
+		KeepItems: 2,
+		Summarize: true,
+		OnPrune:   func(p openairt.ContextPrune) { prunes = append(prunes, p) },
+	})
+	handleServerEvents(t, manager.HandleEvent, contextEvents()...)
+
+	creates := sentOfType(server, "response.create")
+	require.Len(t, creates, 1)
+	response := creates[0]["response"].(map[string]any)
+	require.Equal(t, "none", response["conversation"])
+	metadata := response["metadata"].(map[string]any)
+	require.Len(t, metadata, 1)
+	input := response["input"].([]any)[0].(map[string]any)["content"].([]any)[0].(map[string]any)
+	require.Equal(t, "user: "+strings.Repeat("a", 40)+"\nassistant: hello\nuser: weather?\n", input["text"])
+	require.Empty(t, prunes)
+
+	// Another response.done doesn't start a second summary while one is pending.
+	handleServerEvents(t, manager.HandleEvent, responseDoneEvent("resp_4", 130, 0))
+	require.Len(t, sentOfType(server, "response.create"), 1)
+
+	var key string
+	for k, v := range metadata {
+		key = fmt.Sprintf(`{%q:%q}`, k, v)
+	}
+	handleServerEvents(t, manager.HandleEvent, fmt.Sprintf(`{"type":"response.done","response":{"id":"resp_sum",`+
+		`"status":"completed","metadata":%s,"output":[{"id":"item_s","type":"message","role":"assistant",`+
+		`"content":[{"type":"output_text","text":"The user asked about the weather."}]}]}}`, key))
+
+	created := sentOfType(server, "conversation.item.create")
+	require.Len(t, created, 1)
+	require.Equal(t, "root", created[0]["previous_item_id"])
+	require.Equal(t, map[string]any{
+		"type": "message", "role": "system",
+		"content": []any{map[string]any{"type": "input_text", "text": "The user asked about the weather."}},
+	}, created[0]["item"])
+	require.Len(t, sentOfType(server, "conversation.item.delete"), 3)
+	require.Len(t, prunes, 1)
+	require.Equal(t, "The user asked about the weather.", prunes[0].Summary)
+	require.NoError(t, prunes[0].Err)
+}
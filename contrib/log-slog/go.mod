@@ -0,0 +1,17 @@
+module github.com/WqyJh/go-openai-realtime/v2/contrib/log-slog
+
+go 1.23
+
+// replace github.com/WqyJh/go-openai-realtime/v2 => ../../
+
+require (
+	github.com/WqyJh/go-openai-realtime/v2 v2.0.0-20251024121129-c3290b09cc8f
+	github.com/stretchr/testify v1.9.0
+)
+
+require (
+	github.com/coder/websocket v1.8.12 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
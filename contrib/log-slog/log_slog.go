@@ -0,0 +1,110 @@
+package slog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+)
+
+// Logger is an openairt.StructuredLogger backed by a slog.Handler.
+type Logger struct {
+	handler slog.Handler
+}
+
+var _ openairt.StructuredLogger = (*Logger)(nil)
+
+// New creates a Logger writing to the handler.
+func New(handler slog.Handler) *Logger {
+	return &Logger{handler: handler}
+}
+
+// NewFromLogger creates a Logger writing to the handler of logger.
+func NewFromLogger(logger *slog.Logger) *Logger {
+	return New(logger.Handler())
+}
+
+// Level converts the openairt level to the slog level.
+func Level(level openairt.LogLevel) slog.Level {
+	switch level {
+	case openairt.LogLevelDebug:
+		return slog.LevelDebug
+	case openairt.LogLevelInfo:
+		return slog.LevelInfo
+	case openairt.LogLevelWarn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// Enabled implements openairt.StructuredLogger.
+func (l *Logger) Enabled(level openairt.LogLevel) bool {
+	return l.handler.Enabled(context.Background(), Level(level))
+}
+
+// Log implements openairt.StructuredLogger.
+func (l *Logger) Log(level openairt.LogLevel, msg string, kv ...any) {
+	l.log(Level(level), msg, kv...)
+}
+
+// With implements openairt.StructuredLogger.
+func (l *Logger) With(kv ...any) openairt.StructuredLogger {
+	var r slog.Record
+	r.Add(kv...)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return New(l.handler.WithAttrs(attrs))
+}
+
+// Debugf implements openairt.Logger.
+func (l *Logger) Debugf(format string, v ...any) {
+	l.logf(slog.LevelDebug, format, v...)
+}
+
+// Infof implements openairt.Logger.
+func (l *Logger) Infof(format string, v ...any) {
+	l.logf(slog.LevelInfo, format, v...)
+}
+
+// Warnf implements openairt.Logger.
+func (l *Logger) Warnf(format string, v ...any) {
+	l.logf(slog.LevelWarn, format, v...)
+}
+
+// Errorf implements openairt.Logger.
+func (l *Logger) Errorf(format string, v ...any) {
+	l.logf(slog.LevelError, format, v...)
+}
+
+func (l *Logger) logf(level slog.Level, format string, v ...any) {
+	ctx := context.Background()
+	if !l.handler.Enabled(ctx, level) {
+		return
+	}
+	l.handle(ctx, level, fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) log(level slog.Level, msg string, kv ...any) {
+	ctx := context.Background()
+	if !l.handler.Enabled(ctx, level) {
+		return
+	}
+	l.handle(ctx, level, msg, kv...)
+}
+
+// handle must be called by log or logf, which are called by the exported methods,
+// so that the source of the record is the caller of the exported method.
+func (l *Logger) handle(ctx context.Context, level slog.Level, msg string, kv ...any) {
+	var pcs [1]uintptr
+	runtime.Callers(4, pcs[:]) //nolint:mnd // skip [Callers, handle, log/logf, exported method]
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(kv...)
+	_ = l.handler.Handle(ctx, r)
+}
@@ -0,0 +1,66 @@
+package slog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	slogrt "github.com/WqyJh/go-openai-realtime/v2/contrib/log-slog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+	var logger openairt.StructuredLogger = slogrt.New(handler)
+
+	require.False(t, logger.Enabled(openairt.LogLevelDebug))
+	require.True(t, logger.Enabled(openairt.LogLevelInfo))
+
+	logger = logger.With("model", "gpt-realtime")
+	logger.Log(openairt.LogLevelDebug, "dropped")
+	logger.Log(openairt.LogLevelWarn, "received event", "type", "error", "bytes", 42)
+	logger.Errorf("read message: %s", "eof")
+
+	dec := json.NewDecoder(&buf)
+	var entry map[string]any
+	require.NoError(t, dec.Decode(&entry))
+	require.Equal(t, "WARN", entry["level"])
+	require.Equal(t, "received event", entry["msg"])
+	require.Equal(t, "gpt-realtime", entry["model"])
+	require.Equal(t, "error", entry["type"])
+	require.InDelta(t, 42, entry["bytes"], 0)
+
+	entry = nil
+	require.NoError(t, dec.Decode(&entry))
+	require.Equal(t, "ERROR", entry["level"])
+	require.Equal(t, "read message: eof", entry["msg"])
+	require.Equal(t, "gpt-realtime", entry["model"])
+	require.False(t, dec.More())
+}
+
+func TestConnectWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slogrt.NewFromLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	client := openairt.NewClient("secret-token")
+	_, err := client.Connect(context.Background(),
+		openairt.WithLogger(logger),
+		openairt.WithLogAttrs("tenant", "t1"),
+		openairt.WithDialer(failingDialer{}),
+	)
+	require.Error(t, err)
+	require.Contains(t, buf.String(), "msg=dialing")
+	require.Contains(t, buf.String(), "tenant=t1")
+	require.NotContains(t, buf.String(), "secret-token")
+}
+
+type failingDialer struct{}
+
+func (failingDialer) Dial(_ context.Context, _ string, _ http.Header) (openairt.WebSocketConn, error) {
+	return nil, errors.New("dial failed")
+}
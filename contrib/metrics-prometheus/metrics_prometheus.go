@@ -0,0 +1,169 @@
+package prometheus
+
+import (
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Options is the options for Metrics.
+type Options struct {
+	// Namespace of the metrics. Default is "openai_realtime".
+	Namespace string
+	// Subsystem of the metrics. Default is empty.
+	Subsystem string
+	// ConstLabels are attached to all metrics, e.g. the name of the service.
+	ConstLabels prom.Labels
+	// Buckets of the latency histograms in seconds. Default is prometheus.DefBuckets.
+	Buckets []float64
+}
+
+// Metrics is an openairt.Metrics implementation exporting Prometheus metrics.
+// It implements prometheus.Collector, register it to a registry to export the metrics.
+type Metrics struct {
+	eventsSent         *prom.CounterVec
+	eventsReceived     *prom.CounterVec
+	audioBytes         *prom.CounterVec
+	speechToFirstAudio prom.Histogram
+	timeToFirstText    prom.Histogram
+	errors             *prom.CounterVec
+	reconnects         prom.Counter
+	eventsDropped      *prom.CounterVec
+}
+
+var _ openairt.Metrics = (*Metrics)(nil)
+
+// NewMetrics creates a new Metrics.
+func NewMetrics(options Options) *Metrics {
+	if options.Namespace == "" {
+		options.Namespace = "openai_realtime"
+	}
+	if len(options.Buckets) == 0 {
+		options.Buckets = prom.DefBuckets
+	}
+	counterOpts := func(name, help string) prom.CounterOpts {
+		return prom.CounterOpts{
+			Namespace:   options.Namespace,
+			Subsystem:   options.Subsystem,
+			Name:        name,
+			Help:        help,
+			ConstLabels: options.ConstLabels,
+		}
+	}
+	histogramOpts := func(name, help string) prom.HistogramOpts {
+		return prom.HistogramOpts{
+			Namespace:   options.Namespace,
+			Subsystem:   options.Subsystem,
+			Name:        name,
+			Help:        help,
+			ConstLabels: options.ConstLabels,
+			Buckets:     options.Buckets,
+		}
+	}
+	return &Metrics{
+		eventsSent: prom.NewCounterVec(
+			counterOpts("client_events_total", "Number of client events sent, by type."),
+			[]string{"type"},
+		),
+		eventsReceived: prom.NewCounterVec(
+			counterOpts("server_events_total", "Number of server events received, by type."),
+			[]string{"type"},
+		),
+		audioBytes: prom.NewCounterVec(
+			counterOpts("audio_bytes_total", "Number of audio bytes sent and received, by direction."),
+			[]string{"direction"},
+		),
+		speechToFirstAudio: prom.NewHistogram(histogramOpts(
+			"speech_to_first_audio_seconds",
+			"Time from input_audio_buffer.speech_stopped to the first response.output_audio.delta.",
+		)),
+		timeToFirstText: prom.NewHistogram(histogramOpts(
+			"time_to_first_text_seconds",
+			"Time from response.created to the first text or audio transcript delta.",
+		)),
+		errors: prom.NewCounterVec(
+			counterOpts("errors_total", "Number of error events, by code."),
+			[]string{"code"},
+		),
+		reconnects: prom.NewCounter(
+			counterOpts("reconnects_total", "Number of dropped connections replaced with new ones."),
+		),
+		eventsDropped: prom.NewCounterVec(
+			counterOpts("server_events_dropped_total", "Number of server events dropped by full dispatch queues, by type."),
+			[]string{"type"},
+		),
+	}
+}
+
+func (m *Metrics) collectors() []prom.Collector {
+	return []prom.Collector{
+		m.eventsSent,
+		m.eventsReceived,
+		m.audioBytes,
+		m.speechToFirstAudio,
+		m.timeToFirstText,
+		m.errors,
+		m.reconnects,
+		m.eventsDropped,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prom.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prom.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+// EventSent implements openairt.Metrics.
+func (m *Metrics) EventSent(eventType openairt.ClientEventType) {
+	m.eventsSent.WithLabelValues(string(eventType)).Inc()
+}
+
+// EventReceived implements openairt.Metrics.
+func (m *Metrics) EventReceived(eventType openairt.ServerEventType) {
+	m.eventsReceived.WithLabelValues(string(eventType)).Inc()
+}
+
+// AudioSent implements openairt.Metrics.
+func (m *Metrics) AudioSent(bytes int) {
+	m.audioBytes.WithLabelValues("sent").Add(float64(bytes))
+}
+
+// AudioReceived implements openairt.Metrics.
+func (m *Metrics) AudioReceived(bytes int) {
+	m.audioBytes.WithLabelValues("received").Add(float64(bytes))
+}
+
+// SpeechToFirstAudio implements openairt.Metrics.
+func (m *Metrics) SpeechToFirstAudio(d time.Duration) {
+	m.speechToFirstAudio.Observe(d.Seconds())
+}
+
+// TimeToFirstText implements openairt.Metrics.
+func (m *Metrics) TimeToFirstText(d time.Duration) {
+	m.timeToFirstText.Observe(d.Seconds())
+}
+
+// Error implements openairt.Metrics.
+func (m *Metrics) Error(code string) {
+	m.errors.WithLabelValues(code).Inc()
+}
+
+// Reconnect implements openairt.Metrics.
+func (m *Metrics) Reconnect() {
+	m.reconnects.Inc()
+}
+
+// EventDropped implements openairt.Metrics.
+func (m *Metrics) EventDropped(eventType openairt.ServerEventType) {
+	m.eventsDropped.WithLabelValues(string(eventType)).Inc()
+}
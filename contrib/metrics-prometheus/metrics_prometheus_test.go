@@ -0,0 +1,68 @@
+package prometheus_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	promrt "github.com/WqyJh/go-openai-realtime/v2/contrib/metrics-prometheus"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := promrt.NewMetrics(promrt.Options{
+		ConstLabels: prom.Labels{"service": "test"},
+	})
+	registry := prom.NewPedanticRegistry()
+	require.NoError(t, registry.Register(metrics))
+
+	metrics.EventSent(openairt.ClientEventTypeInputAudioBufferAppend)
+	metrics.EventSent(openairt.ClientEventTypeInputAudioBufferAppend)
+	metrics.EventReceived(openairt.ServerEventTypeResponseDone)
+	metrics.AudioSent(100)
+	metrics.AudioReceived(200)
+	metrics.AudioReceived(50)
+	metrics.SpeechToFirstAudio(300 * time.Millisecond)
+	metrics.TimeToFirstText(100 * time.Millisecond)
+	metrics.Error("invalid_value")
+	metrics.Reconnect()
+	metrics.EventDropped(openairt.ServerEventTypeResponseOutputAudioDelta)
+
+	expected := `
+# HELP openai_realtime_audio_bytes_total Number of audio bytes sent and received, by direction.
+# TYPE openai_realtime_audio_bytes_total counter
+openai_realtime_audio_bytes_total{direction="received",service="test"} 250
+openai_realtime_audio_bytes_total{direction="sent",service="test"} 100
+# HELP openai_realtime_client_events_total Number of client events sent, by type.
+# TYPE openai_realtime_client_events_total counter
+openai_realtime_client_events_total{service="test",type="input_audio_buffer.append"} 2
+# HELP openai_realtime_errors_total Number of error events, by code.
+# TYPE openai_realtime_errors_total counter
+openai_realtime_errors_total{code="invalid_value",service="test"} 1
+# HELP openai_realtime_reconnects_total Number of dropped connections replaced with new ones.
+# TYPE openai_realtime_reconnects_total counter
+openai_realtime_reconnects_total{service="test"} 1
+# HELP openai_realtime_server_events_dropped_total Number of server events dropped by full dispatch queues, by type.
+# TYPE openai_realtime_server_events_dropped_total counter
+openai_realtime_server_events_dropped_total{service="test",type="response.output_audio.delta"} 1
+# HELP openai_realtime_server_events_total Number of server events received, by type.
+# TYPE openai_realtime_server_events_total counter
+openai_realtime_server_events_total{service="test",type="response.done"} 1
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"openai_realtime_audio_bytes_total",
+		"openai_realtime_client_events_total",
+		"openai_realtime_errors_total",
+		"openai_realtime_reconnects_total",
+		"openai_realtime_server_events_dropped_total",
+		"openai_realtime_server_events_total",
+	)
+	require.NoError(t, err)
+
+	count, err := testutil.GatherAndCount(registry, "openai_realtime_speech_to_first_audio_seconds")
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
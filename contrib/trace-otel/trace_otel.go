@@ -0,0 +1,477 @@
+package otel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/WqyJh/go-openai-realtime/v2/contrib/trace-otel"
+
+// Span names.
+const (
+	SpanDial          = "realtime.dial"
+	SpanSessionUpdate = "realtime.session.update"
+	SpanResponse      = "realtime.response"
+	SpanOutputItem    = "realtime.output_item"
+	SpanToolCall      = "realtime.tool_call"
+)
+
+// Attribute keys.
+const (
+	AttrModel             = attribute.Key("openai.realtime.model")
+	AttrSessionID         = attribute.Key("openai.realtime.session.id")
+	AttrEventID           = attribute.Key("openai.realtime.event.id")
+	AttrResponseID        = attribute.Key("openai.realtime.response.id")
+	AttrResponseStatus    = attribute.Key("openai.realtime.response.status")
+	AttrItemID            = attribute.Key("openai.realtime.item.id")
+	AttrItemType          = attribute.Key("openai.realtime.item.type")
+	AttrToolName          = attribute.Key("openai.realtime.tool.name")
+	AttrToolCallID        = attribute.Key("openai.realtime.tool.call_id")
+	AttrToolServerLabel   = attribute.Key("openai.realtime.tool.server_label")
+	AttrTimeToFirstAudio  = attribute.Key("openai.realtime.time_to_first_audio_ms")
+	AttrErrorCode         = attribute.Key("openai.realtime.error.code")
+	AttrInputTokens       = attribute.Key("gen_ai.usage.input_tokens")
+	AttrOutputTokens      = attribute.Key("gen_ai.usage.output_tokens")
+	AttrInputAudioTokens  = attribute.Key("openai.realtime.usage.input_audio_tokens")
+	AttrOutputAudioTokens = attribute.Key("openai.realtime.usage.output_audio_tokens")
+	AttrCachedTokens      = attribute.Key("openai.realtime.usage.cached_tokens")
+)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+}
+
+// Option configures the instrumentation.
+type Option func(*options)
+
+// WithTracerProvider sets the tracer provider. Defaults to the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// Dialer is a WebSocketDialer that traces the dial and the events of the dialed connections.
+//
+// The spans of the session updates are children of the ctx passed to Conn.SendMessage,
+// the spans of the responses are children of the ctx passed to Conn.ReadMessage,
+// which is the ctx of the ConnHandler if it's used.
+type Dialer struct {
+	dialer openairt.WebSocketDialer
+	tracer trace.Tracer
+}
+
+// NewDialer wraps the dialer with tracing.
+func NewDialer(dialer openairt.WebSocketDialer, opts ...Option) *Dialer {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.tracerProvider == nil {
+		o.tracerProvider = otel.GetTracerProvider()
+	}
+	return &Dialer{
+		dialer: dialer,
+		tracer: o.tracerProvider.Tracer(instrumentationName),
+	}
+}
+
+// Dial establishes a new WebSocket connection to the given URL.
+func (d *Dialer) Dial(ctx context.Context, rawURL string, header http.Header) (openairt.WebSocketConn, error) {
+	var model string
+	if u, err := url.Parse(rawURL); err == nil {
+		model = u.Query().Get("model")
+	}
+	ctx, span := d.tracer.Start(ctx, SpanDial,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("url.full", rawURL)),
+	)
+	if model != "" {
+		span.SetAttributes(AttrModel.String(model))
+	}
+	defer span.End()
+
+	conn, err := d.dialer.Dial(ctx, rawURL, header)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	return &WebSocketConn{
+		WebSocketConn: conn,
+		tracer:        d.tracer,
+		model:         model,
+		responses:     make(map[string]*responseSpan),
+	}, nil
+}
+
+type responseSpan struct {
+	span      trace.Span
+	ctx       context.Context
+	start     time.Time
+	audioSeen bool
+	items     map[string]trace.Span
+}
+
+type updateSpan struct {
+	eventID string
+	span    trace.Span
+}
+
+// WebSocketConn is a traced WebSocketConn.
+type WebSocketConn struct {
+	openairt.WebSocketConn
+	tracer trace.Tracer
+
+	mu        sync.Mutex
+	model     string
+	sessionID string
+	updates   []updateSpan
+	responses map[string]*responseSpan
+}
+
+// sniff is the part of the events needed by the spans, decoded once per message.
+type sniff struct {
+	Type       string                     `json:"type"`
+	EventID    string                     `json:"event_id"`
+	ResponseID string                     `json:"response_id"`
+	Session    *sniffSession              `json:"session"`
+	Error      *openairt.Error            `json:"error"`
+	Item       *openairt.MessageItemUnion `json:"item"`
+	Response   *sniffResponse             `json:"response"`
+}
+
+type sniffSession struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Model string `json:"model"`
+}
+
+type sniffResponse struct {
+	ID            string                  `json:"id"`
+	Status        openairt.ResponseStatus `json:"status"`
+	StatusDetails *openairt.StatusDetail  `json:"status_details"`
+	Usage         *openairt.TokenUsage    `json:"usage"`
+}
+
+// WriteMessage writes a message to the WebSocket connection.
+func (c *WebSocketConn) WriteMessage(ctx context.Context, messageType openairt.MessageType, data []byte) error {
+	var ev sniff
+	if messageType == openairt.MessageText && json.Unmarshal(data, &ev) == nil &&
+		openairt.ClientEventType(ev.Type) == openairt.ClientEventTypeSessionUpdate {
+		c.mu.Lock()
+		_, span := c.tracer.Start(ctx, SpanSessionUpdate,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(c.commonAttributes()...),
+		)
+		if ev.EventID != "" {
+			span.SetAttributes(AttrEventID.String(ev.EventID))
+		}
+		c.updates = append(c.updates, updateSpan{eventID: ev.EventID, span: span})
+		c.mu.Unlock()
+
+		err := c.WebSocketConn.WriteMessage(ctx, messageType, data)
+		if err != nil {
+			c.endUpdate(ev.EventID, err)
+		}
+		return err
+	}
+	return c.WebSocketConn.WriteMessage(ctx, messageType, data)
+}
+
+// ReadMessage reads a message from the WebSocket connection.
+func (c *WebSocketConn) ReadMessage(ctx context.Context) (openairt.MessageType, []byte, error) {
+	messageType, data, err := c.WebSocketConn.ReadMessage(ctx)
+	if err == nil && messageType == openairt.MessageText {
+		c.trace(ctx, data)
+	}
+	return messageType, data, err
+}
+
+// ReadMessageInto reads a message into buf, without allocating if the wrapped WebSocketConn
+// is an openairt.WebSocketBufferReader.
+func (c *WebSocketConn) ReadMessageInto(ctx context.Context, buf *bytes.Buffer) (openairt.MessageType, error) {
+	reader, ok := c.WebSocketConn.(openairt.WebSocketBufferReader)
+	if !ok {
+		buf.Reset()
+		messageType, data, err := c.ReadMessage(ctx)
+		buf.Write(data)
+		return messageType, err
+	}
+	messageType, err := reader.ReadMessageInto(ctx, buf)
+	if err == nil && messageType == openairt.MessageText {
+		c.trace(ctx, buf.Bytes())
+	}
+	return messageType, err
+}
+
+// trace updates the spans with a server event.
+func (c *WebSocketConn) trace(ctx context.Context, data []byte) {
+	var ev sniff
+	if json.Unmarshal(data, &ev) != nil {
+		return
+	}
+	now := time.Now()
+	if openairt.ServerEventType(ev.Type) == openairt.ServerEventTypeResponseOutputAudioDelta {
+		c.firstAudio(ev.ResponseID, now)
+		return
+	}
+	c.handle(ctx, ev, now)
+}
+
+// Close closes the WebSocket connection and ends all unfinished spans.
+func (c *WebSocketConn) Close() error {
+	c.endSpans()
+	return c.WebSocketConn.Close()
+}
+
+// CloseWithStatus closes the WebSocket connection with the status code and reason, if the wrapped
+// WebSocketConn is an openairt.WebSocketStatusCloser, and ends all unfinished spans.
+func (c *WebSocketConn) CloseWithStatus(code openairt.StatusCode, reason string) error {
+	closer, ok := c.WebSocketConn.(openairt.WebSocketStatusCloser)
+	if !ok {
+		return c.Close()
+	}
+	c.endSpans()
+	return closer.CloseWithStatus(code, reason)
+}
+
+func (c *WebSocketConn) endSpans() {
+	c.mu.Lock()
+	for _, u := range c.updates {
+		u.span.SetStatus(codes.Error, "connection closed")
+		u.span.End()
+	}
+	c.updates = nil
+	for id, r := range c.responses {
+		for _, item := range r.items {
+			item.End()
+		}
+		r.span.SetStatus(codes.Error, "connection closed")
+		r.span.End()
+		delete(c.responses, id)
+	}
+	c.mu.Unlock()
+}
+
+func (c *WebSocketConn) commonAttributes() []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, 2)
+	if c.model != "" {
+		attrs = append(attrs, AttrModel.String(c.model))
+	}
+	if c.sessionID != "" {
+		attrs = append(attrs, AttrSessionID.String(c.sessionID))
+	}
+	return attrs
+}
+
+func (c *WebSocketConn) endUpdate(eventID string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, u := range c.updates {
+		if u.eventID != eventID {
+			continue
+		}
+		if err != nil {
+			u.span.RecordError(err)
+			u.span.SetStatus(codes.Error, err.Error())
+		}
+		u.span.End()
+		c.updates = append(c.updates[:i], c.updates[i+1:]...)
+		return
+	}
+}
+
+func (c *WebSocketConn) firstAudio(responseID string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.responses[responseID]
+	if !ok || r.audioSeen {
+		return
+	}
+	r.audioSeen = true
+	r.span.SetAttributes(AttrTimeToFirstAudio.Int64(now.Sub(r.start).Milliseconds()))
+	r.span.AddEvent("first_audio", trace.WithTimestamp(now))
+}
+
+func (c *WebSocketConn) handle(ctx context.Context, ev sniff, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch openairt.ServerEventType(ev.Type) {
+	case openairt.ServerEventTypeSessionCreated:
+		c.updateSession(ev.Session)
+	case openairt.ServerEventTypeSessionUpdated:
+		c.updateSession(ev.Session)
+		if len(c.updates) > 0 {
+			c.updates[0].span.SetAttributes(c.commonAttributes()...)
+			c.updates[0].span.End(trace.WithTimestamp(now))
+			c.updates = c.updates[1:]
+		}
+	case openairt.ServerEventTypeError:
+		if ev.Error != nil {
+			c.handleError(*ev.Error)
+		}
+	case openairt.ServerEventTypeResponseCreated:
+		if ev.Response == nil {
+			return
+		}
+		attrs := append(c.commonAttributes(), AttrResponseID.String(ev.Response.ID))
+		rctx, span := c.tracer.Start(ctx, SpanResponse,
+			trace.WithTimestamp(now),
+			trace.WithAttributes(attrs...),
+		)
+		c.responses[ev.Response.ID] = &responseSpan{
+			span:  span,
+			ctx:   rctx,
+			start: now,
+			items: make(map[string]trace.Span),
+		}
+	case openairt.ServerEventTypeResponseOutputItemAdded:
+		r, ok := c.responses[ev.ResponseID]
+		if !ok || ev.Item == nil {
+			return
+		}
+		name, attrs := itemAttributes(*ev.Item)
+		_, span := c.tracer.Start(r.ctx, name, trace.WithTimestamp(now), trace.WithAttributes(attrs...))
+		r.items[itemID(*ev.Item)] = span
+	case openairt.ServerEventTypeResponseOutputItemDone:
+		r, ok := c.responses[ev.ResponseID]
+		if !ok || ev.Item == nil {
+			return
+		}
+		id := itemID(*ev.Item)
+		if span, ok := r.items[id]; ok {
+			_, attrs := itemAttributes(*ev.Item)
+			span.SetAttributes(attrs...)
+			span.End(trace.WithTimestamp(now))
+			delete(r.items, id)
+		}
+	case openairt.ServerEventTypeResponseDone:
+		if ev.Response != nil {
+			c.endResponse(*ev.Response, now)
+		}
+	default:
+	}
+}
+
+func (c *WebSocketConn) updateSession(session *sniffSession) {
+	if session == nil {
+		return
+	}
+	c.sessionID = session.ID
+	if session.Type == "realtime" && session.Model != "" {
+		c.model = session.Model
+	}
+}
+
+func (c *WebSocketConn) handleError(e openairt.Error) {
+	for i, u := range c.updates {
+		if e.EventID == "" || u.eventID != e.EventID {
+			continue
+		}
+		u.span.SetAttributes(AttrErrorCode.String(e.Code))
+		u.span.SetStatus(codes.Error, e.Message)
+		u.span.End()
+		c.updates = append(c.updates[:i], c.updates[i+1:]...)
+		return
+	}
+}
+
+func (c *WebSocketConn) endResponse(resp sniffResponse, now time.Time) {
+	r, ok := c.responses[resp.ID]
+	if !ok {
+		return
+	}
+	delete(c.responses, resp.ID)
+	for _, item := range r.items {
+		item.End(trace.WithTimestamp(now))
+	}
+	r.span.SetAttributes(AttrResponseStatus.String(string(resp.Status)))
+	if u := resp.Usage; u != nil {
+		r.span.SetAttributes(
+			AttrInputTokens.Int(u.InputTokens),
+			AttrOutputTokens.Int(u.OutputTokens),
+		)
+		if u.InputTokenDetails != nil {
+			r.span.SetAttributes(
+				AttrInputAudioTokens.Int(u.InputTokenDetails.AudioTokens),
+				AttrCachedTokens.Int(u.InputTokenDetails.CachedTokens),
+			)
+		}
+		if u.OutputTokenDetails != nil {
+			r.span.SetAttributes(AttrOutputAudioTokens.Int(u.OutputTokenDetails.AudioTokens))
+		}
+	}
+	switch resp.Status {
+	case openairt.ResponseStatusFailed, openairt.ResponseStatusIncomplete, openairt.ResponseStatusCancelled:
+		desc := string(resp.Status)
+		if d := resp.StatusDetails; d != nil {
+			if d.Error != nil {
+				desc = d.Error.Message
+			} else if d.Reason != "" {
+				desc = d.Reason
+			}
+		}
+		r.span.SetStatus(codes.Error, desc)
+	default:
+	}
+	r.span.End(trace.WithTimestamp(now))
+}
+
+func itemID(item openairt.MessageItemUnion) string {
+	switch {
+	case item.Assistant != nil:
+		return item.Assistant.ID
+	case item.FunctionCall != nil:
+		return item.FunctionCall.ID
+	case item.MCPToolCall != nil:
+		return item.MCPToolCall.ID
+	case item.MCPListTools != nil:
+		return item.MCPListTools.ID
+	case item.MCPApprovalRequest != nil:
+		return item.MCPApprovalRequest.ID
+	default:
+		return ""
+	}
+}
+
+func itemAttributes(item openairt.MessageItemUnion) (string, []attribute.KeyValue) {
+	attrs := []attribute.KeyValue{AttrItemID.String(itemID(item))}
+	switch {
+	case item.FunctionCall != nil:
+		return SpanToolCall, append(attrs,
+			AttrItemType.String(string(openairt.MessageItemTypeFunctionCall)),
+			AttrToolName.String(item.FunctionCall.Name),
+			AttrToolCallID.String(item.FunctionCall.CallID),
+		)
+	case item.MCPToolCall != nil:
+		return SpanToolCall, append(attrs,
+			AttrItemType.String(string(openairt.MessageItemTypeMCPCall)),
+			AttrToolName.String(item.MCPToolCall.Name),
+			AttrToolServerLabel.String(item.MCPToolCall.ServerLabel),
+		)
+	case item.MCPListTools != nil:
+		return SpanOutputItem, append(attrs,
+			AttrItemType.String(string(openairt.MessageItemTypeMCPListTools)),
+			AttrToolServerLabel.String(item.MCPListTools.ServerLabel),
+		)
+	case item.MCPApprovalRequest != nil:
+		return SpanOutputItem, append(attrs,
+			AttrItemType.String(string(openairt.MessageItemTypeMCPApprovalRequest)),
+			AttrToolName.String(item.MCPApprovalRequest.Name),
+		)
+	default:
+		return SpanOutputItem, append(attrs, AttrItemType.String(string(openairt.MessageItemTypeMessage)))
+	}
+}
@@ -0,0 +1,223 @@
+package otel_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	otelrt "github.com/WqyJh/go-openai-realtime/v2/contrib/trace-otel"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type mockDialer struct {
+	conn openairt.WebSocketConn
+	err  error
+}
+
+func (d *mockDialer) Dial(_ context.Context, _ string, _ http.Header) (openairt.WebSocketConn, error) {
+	return d.conn, d.err
+}
+
+// scriptedConn returns the scripted messages on read and records the written messages.
+type scriptedConn struct {
+	mu      sync.Mutex
+	reads   []string
+	written [][]byte
+}
+
+func (c *scriptedConn) ReadMessage(_ context.Context) (openairt.MessageType, []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.reads) == 0 {
+		return 0, nil, openairt.Permanent(errors.New("closed"))
+	}
+	msg := c.reads[0]
+	c.reads = c.reads[1:]
+	return openairt.MessageText, []byte(msg), nil
+}
+
+func (c *scriptedConn) WriteMessage(_ context.Context, _ openairt.MessageType, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.written = append(c.written, data)
+	return nil
+}
+
+func (c *scriptedConn) Close() error                 { return nil }
+func (c *scriptedConn) Response() *http.Response     { return nil }
+func (c *scriptedConn) Ping(_ context.Context) error { return nil }
+
+// bufferedConn is a scriptedConn which reads into buffers and closes with a status.
+type bufferedConn struct {
+	scriptedConn
+	bufferedReads int
+	code          openairt.StatusCode
+	reason        string
+}
+
+func (c *bufferedConn) ReadMessageInto(ctx context.Context, buf *bytes.Buffer) (openairt.MessageType, error) {
+	messageType, data, err := c.ReadMessage(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bufferedReads++
+	buf.Reset()
+	buf.Write(data)
+	return messageType, err
+}
+
+func (c *bufferedConn) CloseWithStatus(code openairt.StatusCode, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.code, c.reason = code, reason
+	return nil
+}
+
+func attrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	script := &scriptedConn{reads: []string{
+		`{"type":"session.created","event_id":"e1","session":{"type":"realtime","id":"sess_1","model":"gpt-realtime-2025-08-28"}}`,
+		`{"type":"session.updated","event_id":"e2","session":{"type":"realtime","id":"sess_1","model":"gpt-realtime-2025-08-28"}}`,
+		`{"type":"response.created","event_id":"e3","response":{"id":"resp_1","status":"in_progress"}}`,
+		`{"type":"response.output_item.added","event_id":"e4","response_id":"resp_1","output_index":0,"item":{"id":"item_1","type":"function_call","name":"get_weather","call_id":"call_1"}}`,
+		`{"type":"response.output_item.done","event_id":"e5","response_id":"resp_1","output_index":0,"item":{"id":"item_1","type":"function_call","name":"get_weather","call_id":"call_1","arguments":"{}"}}`,
+		`{"type":"response.output_item.added","event_id":"e6","response_id":"resp_1","output_index":1,"item":{"id":"item_2","type":"message","role":"assistant"}}`,
+		`{"type":"response.output_audio.delta","event_id":"e7","response_id":"resp_1","item_id":"item_2","delta":"AAAA"}`,
+		`{"type":"response.done","event_id":"e8","response":{"id":"resp_1","status":"completed","usage":{"total_tokens":30,"input_tokens":10,"output_tokens":20,"input_token_details":{"cached_tokens":2,"text_tokens":5,"audio_tokens":5},"output_token_details":{"text_tokens":5,"audio_tokens":15}}}}`,
+		`{"type":"error","event_id":"e9","error":{"type":"invalid_request_error","code":"invalid_value","message":"bad voice","event_id":"update_2"}}`,
+	}}
+	dialer := otelrt.NewDialer(&mockDialer{conn: script}, otelrt.WithTracerProvider(tp))
+
+	client := openairt.NewClient("token")
+	ctx := context.Background()
+	conn, err := client.Connect(ctx, openairt.WithDialer(dialer))
+	require.NoError(t, err)
+
+	_, err = conn.ReadMessage(ctx)
+	require.NoError(t, err)
+
+	err = conn.SendMessage(ctx, openairt.SessionUpdateEvent{
+		EventBase: openairt.EventBase{EventID: "update_1"},
+		Session:   openairt.SessionUnion{Realtime: &openairt.RealtimeSession{Instructions: "hi"}},
+	})
+	require.NoError(t, err)
+	for range 7 {
+		_, err = conn.ReadMessage(ctx)
+		require.NoError(t, err)
+	}
+	err = conn.SendMessage(ctx, openairt.SessionUpdateEvent{
+		EventBase: openairt.EventBase{EventID: "update_2"},
+		Session:   openairt.SessionUnion{Realtime: &openairt.RealtimeSession{Instructions: "hi"}},
+	})
+	require.NoError(t, err)
+	_, err = conn.ReadMessage(ctx)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	spans := recorder.Ended()
+	byName := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range spans {
+		byName[span.Name()] = append(byName[span.Name()], span)
+	}
+
+	require.Len(t, byName[otelrt.SpanDial], 1)
+	require.Len(t, byName[otelrt.SpanSessionUpdate], 2)
+	require.Len(t, byName[otelrt.SpanResponse], 1)
+	require.Len(t, byName[otelrt.SpanToolCall], 1)
+	require.Len(t, byName[otelrt.SpanOutputItem], 1)
+
+	update := byName[otelrt.SpanSessionUpdate][0]
+	require.Equal(t, "update_1", attrs(update)[otelrt.AttrEventID].AsString())
+	require.Equal(t, "sess_1", attrs(update)[otelrt.AttrSessionID].AsString())
+	require.Equal(t, codes.Unset, update.Status().Code)
+
+	failed := byName[otelrt.SpanSessionUpdate][1]
+	require.Equal(t, codes.Error, failed.Status().Code)
+	require.Equal(t, "invalid_value", attrs(failed)[otelrt.AttrErrorCode].AsString())
+
+	response := byName[otelrt.SpanResponse][0]
+	ra := attrs(response)
+	require.Equal(t, "resp_1", ra[otelrt.AttrResponseID].AsString())
+	require.Equal(t, "gpt-realtime-2025-08-28", ra[otelrt.AttrModel].AsString())
+	require.Equal(t, "completed", ra[otelrt.AttrResponseStatus].AsString())
+	require.Equal(t, int64(10), ra[otelrt.AttrInputTokens].AsInt64())
+	require.Equal(t, int64(20), ra[otelrt.AttrOutputTokens].AsInt64())
+	require.Equal(t, int64(15), ra[otelrt.AttrOutputAudioTokens].AsInt64())
+	require.Contains(t, ra, otelrt.AttrTimeToFirstAudio)
+
+	tool := byName[otelrt.SpanToolCall][0]
+	require.Equal(t, response.SpanContext().SpanID(), tool.Parent().SpanID())
+	require.Equal(t, "get_weather", attrs(tool)[otelrt.AttrToolName].AsString())
+	require.Equal(t, "call_1", attrs(tool)[otelrt.AttrToolCallID].AsString())
+
+	item := byName[otelrt.SpanOutputItem][0]
+	require.Equal(t, response.SpanContext().SpanID(), item.Parent().SpanID())
+	require.Equal(t, "item_2", attrs(item)[otelrt.AttrItemID].AsString())
+}
+
+func TestTracingDialError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	dialErr := errors.New("dial failed")
+	dialer := otelrt.NewDialer(&mockDialer{err: dialErr}, otelrt.WithTracerProvider(tp))
+
+	_, err := dialer.Dial(context.Background(), openairt.OpenaiRealtimeAPIURLv1+"?model=gpt-realtime", nil)
+	require.ErrorIs(t, err, dialErr)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, otelrt.SpanDial, spans[0].Name())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Equal(t, "gpt-realtime", attrs(spans[0])[otelrt.AttrModel].AsString())
+}
+
+func TestTracingForwardsOptionalMethods(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	script := &bufferedConn{scriptedConn: scriptedConn{reads: []string{
+		`{"type":"session.created","event_id":"e1","session":{"type":"realtime","id":"sess_1","model":"gpt-realtime"}}`,
+		`{"type":"response.created","event_id":"e2","response":{"id":"resp_1","status":"in_progress"}}`,
+	}}}
+	dialer := otelrt.NewDialer(&mockDialer{conn: script}, otelrt.WithTracerProvider(tp))
+	ctx := context.Background()
+	conn, err := openairt.NewClient("token").Connect(ctx, openairt.WithDialer(dialer))
+	require.NoError(t, err)
+
+	// The messages are read into buffers and traced.
+	for range 2 {
+		_, err = conn.ReadMessage(ctx)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, script.bufferedReads)
+
+	// The close status reaches the wrapped conn, and the unfinished spans are ended.
+	require.NoError(t, conn.CloseWithStatus(openairt.StatusGoingAway, "restart"))
+	require.Equal(t, openairt.StatusGoingAway, script.code)
+	require.Equal(t, "restart", script.reason)
+	var responses []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == otelrt.SpanResponse {
+			responses = append(responses, span)
+		}
+	}
+	require.Len(t, responses, 1)
+	require.Equal(t, "sess_1", attrs(responses[0])[otelrt.AttrSessionID].AsString())
+	require.Equal(t, codes.Error, responses[0].Status().Code)
+}
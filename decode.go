@@ -0,0 +1,268 @@
+package openairt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// audioDecodeChunk is the number of base64 characters decoded at once by AppendAudio, a multiple of 4.
+	audioDecodeChunk = 1024
+
+	// maxPooledReadBuffer is the capacity above which the read buffers are not reused,
+	// so that a single large message doesn't stay in memory.
+	maxPooledReadBuffer = 1 << 20
+)
+
+var readBufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+func getReadBuffer() *bytes.Buffer {
+	return readBufferPool.Get().(*bytes.Buffer) //nolint:forcetypeassert // the pool only holds *bytes.Buffer
+}
+
+func putReadBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledReadBuffer {
+		return
+	}
+	buf.Reset()
+	readBufferPool.Put(buf)
+}
+
+// AudioLen returns the number of audio bytes of the delta.
+func (e ResponseOutputAudioDeltaEvent) AudioLen() int {
+	return base64DecodedLen(e.Delta)
+}
+
+// AppendAudio decodes the base64 audio of the delta and appends it to dst, without intermediate copies.
+// Reusing dst across deltas avoids allocating a slice per delta. On error, dst is returned as is.
+func (e ResponseOutputAudioDeltaEvent) AppendAudio(dst []byte) ([]byte, error) {
+	n := len(dst)
+	// The upper bound, so that a malformed padding can't write past out.
+	size := base64.StdEncoding.DecodedLen(len(e.Delta))
+	if cap(dst)-n < size {
+		grown := make([]byte, n, n+size)
+		copy(grown, dst)
+		dst = grown
+	}
+	out := dst[n : n+size]
+
+	var chunk [audioDecodeChunk]byte
+	written := 0
+	for s := e.Delta; len(s) > 0; {
+		k := copy(chunk[:], s)
+		s = s[k:]
+		m, err := base64.StdEncoding.Decode(out[written:], chunk[:k])
+		if err != nil {
+			return dst[:n], err
+		}
+		written += m
+	}
+	return dst[:n+written], nil
+}
+
+// AudioReader returns a reader streaming the decoded audio of the delta.
+func (e ResponseOutputAudioDeltaEvent) AudioReader() io.Reader {
+	return base64.NewDecoder(base64.StdEncoding, strings.NewReader(e.Delta))
+}
+
+// scanServerEventType returns the top-level "type" of the JSON object in data,
+// scanning no further than the field. It reports false if it can't be found this way.
+func scanServerEventType(data []byte) (ServerEventType, bool) {
+	var eventType ServerEventType
+	found := false
+	ok := scanJSONObject(data, func(key, value []byte) bool {
+		if string(key) != "type" {
+			return true
+		}
+		if found = jsonPlainString(value); found {
+			eventType = ServerEventType(value[1 : len(value)-1])
+		}
+		return false
+	})
+	return eventType, ok && found
+}
+
+// decodeAudioDelta decodes a response.output_audio.delta in a single scan. It reports false
+// for the unusual encodings, e.g. escaped strings or unknown fields, which are left to encoding/json.
+// The data is copied once, the strings of the event are slices of the copy.
+func decodeAudioDelta(data []byte) (ResponseOutputAudioDeltaEvent, bool) {
+	var e ResponseOutputAudioDeltaEvent
+	msg := string(data)
+	valid := true
+	ok := scanJSONObject(data, func(key, value []byte) bool {
+		// value is a slice of data, its offset is given by the capacity left.
+		offset := cap(data) - cap(value)
+		str := func() string {
+			if !jsonPlainString(value) {
+				valid = false
+				return ""
+			}
+			return msg[offset+1 : offset+len(value)-1]
+		}
+		switch string(key) {
+		case "type":
+			e.Type = ServerEventType(str())
+		case "event_id":
+			e.EventID = str()
+		case "response_id":
+			e.ResponseID = str()
+		case "item_id":
+			e.ItemID = str()
+		case "delta":
+			e.Delta = str()
+		case "output_index":
+			e.OutputIndex, valid = jsonPlainInt(value)
+		case "content_index":
+			e.ContentIndex, valid = jsonPlainInt(value)
+		default:
+			valid = false
+		}
+		return valid
+	})
+	return e, ok && valid
+}
+
+// jsonPlainString reports whether the JSON value is a string of printable ASCII without escape sequences.
+func jsonPlainString(value []byte) bool {
+	if len(value) < 2 || value[0] != '"' {
+		return false
+	}
+	for _, c := range value[1 : len(value)-1] {
+		if c < ' ' || c > '~' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonPlainInt returns the JSON integer value, if it fits in an int.
+func jsonPlainInt(value []byte) (int, bool) {
+	digits := value
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if len(digits) == 0 || (digits[0] == '0' && len(digits) > 1) {
+		return 0, false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(string(value))
+	return n, err == nil
+}
+
+// scanJSONObject calls fn with the raw key and value of each top-level field of the JSON object
+// in data, until fn returns false. It reports false if data isn't an object it can scan:
+// the keys with escape sequences are not supported. The values are returned raw, unvalidated.
+func scanJSONObject(data []byte, fn func(key, value []byte) bool) bool {
+	i := skipJSONSpace(data, 0)
+	if i >= len(data) || data[i] != '{' {
+		return false
+	}
+	i = skipJSONSpace(data, i+1)
+	if i < len(data) && data[i] == '}' {
+		return skipJSONSpace(data, i+1) == len(data)
+	}
+	for i < len(data) {
+		end := scanJSONValue(data, i)
+		if end < 0 || data[i] != '"' {
+			return false
+		}
+		key := data[i+1 : end-1]
+		if bytes.IndexByte(key, '\\') >= 0 {
+			return false
+		}
+		i = skipJSONSpace(data, end)
+		if i >= len(data) || data[i] != ':' {
+			return false
+		}
+		i = skipJSONSpace(data, i+1)
+		end = scanJSONValue(data, i)
+		if end < 0 {
+			return false
+		}
+		if !fn(key, data[i:end]) {
+			return true
+		}
+		i = skipJSONSpace(data, end)
+		if i >= len(data) {
+			return false
+		}
+		switch data[i] {
+		case ',':
+			i = skipJSONSpace(data, i+1)
+		case '}':
+			return skipJSONSpace(data, i+1) == len(data)
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+func skipJSONSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// scanJSONValue returns the end of the JSON value starting at i, or -1 if it's malformed.
+// The nested values are skipped without being validated, encoding/json validates them later.
+func scanJSONValue(data []byte, i int) int {
+	if i >= len(data) {
+		return -1
+	}
+	switch data[i] {
+	case '"':
+		for j := i + 1; j < len(data); j++ {
+			switch data[j] {
+			case '\\':
+				j++
+			case '"':
+				return j + 1
+			}
+		}
+		return -1
+	case '{', '[':
+		depth := 0
+		for j := i; j < len(data); j++ {
+			switch data[j] {
+			case '"':
+				end := scanJSONValue(data, j)
+				if end < 0 {
+					return -1
+				}
+				j = end - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return j + 1
+				}
+			}
+		}
+		return -1
+	default:
+		j := i
+		for j < len(data) && data[j] != ',' && data[j] != '}' && data[j] != ']' &&
+			data[j] != ' ' && data[j] != '\t' && data[j] != '\n' && data[j] != '\r' {
+			j++
+		}
+		if j == i {
+			return -1
+		}
+		return j
+	}
+}
@@ -0,0 +1,266 @@
+package openairt_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/coder/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+// audioDelta returns a response.output_audio.delta of 100ms of 24kHz PCM16 audio.
+func audioDelta(tb testing.TB) (string, []byte) {
+	tb.Helper()
+	audio := make([]byte, 4800)
+	for i := range audio {
+		audio[i] = byte(i * 7)
+	}
+	data, err := json.Marshal(openairt.ResponseOutputAudioDeltaEvent{
+		ServerEventBase: openairt.ServerEventBase{
+			EventID: "event_4434",
+			Type:    openairt.ServerEventTypeResponseOutputAudioDelta,
+		},
+		ResponseID:   "resp_001",
+		ItemID:       "item_008",
+		OutputIndex:  1,
+		ContentIndex: 2,
+		Delta:        base64.StdEncoding.EncodeToString(audio),
+	})
+	require.NoError(tb, err)
+	return string(data), audio
+}
+
+func TestUnmarshalServerEventAudioDelta(t *testing.T) {
+	data, _ := audioDelta(t)
+	for _, payload := range []string{
+		data,
+		`{"type":"response.output_audio.delta","delta":"AAE=","output_index":-1,"content_index":0}`,
+		` { "delta" : "AAE=" , "item_id" : "item_1" , "type" : "response.output_audio.delta" } `,
+		// The escapes, the unknown fields and the unusual values are left to encoding/json.
+		`{"type":"response.output_audio.delta","item_id":"item_\u00e9","delta":"AAE="}`,
+		`{"type":"response.output_audio.delta","item_id":"item_é","delta":"AAE\/"}`,
+		`{"type":"response.output\u005faudio.delta","delta":"AAE="}`,
+		`{"type":"response.output_audio.delta","obfuscation":{"a":[1,"}"]},"delta":"AAE="}`,
+		`{"type":"response.output_audio.delta","response_id":null,"output_index":-0}`,
+	} {
+		var expected openairt.ResponseOutputAudioDeltaEvent
+		require.NoError(t, json.Unmarshal([]byte(payload), &expected), payload)
+		actual, err := openairt.UnmarshalServerEvent([]byte(payload))
+		require.NoError(t, err, payload)
+		require.Equal(t, expected, actual, payload)
+	}
+
+	for _, payload := range []string{
+		`{"type":"response.output_audio.delta","delta":"AAE="} x`,
+		`{"type":"response.output_audio.delta","output_index":+1}`,
+		`{"type":"response.output_audio.delta","output_index":01}`,
+		`{"type":"response.output_audio.delta","delta":"AAE="`,
+		`{"type":"response.output_audio.delta","delta" "AAE="}`,
+		`{"type":"response.output_audio.delta","content_index":"1"}`,
+	} {
+		_, err := openairt.UnmarshalServerEvent([]byte(payload))
+		require.Error(t, err, payload)
+	}
+}
+
+func TestUnmarshalServerEventType(t *testing.T) {
+	for _, payload := range []string{
+		`{"event_id":"event_1","error":{"type":"x","message":"{\"type\":\"y\"}"},"type":"error"}`,
+		`{"error":{"type":"x"},"type":"error"}`,
+		"{\n\t\"type\" : \"error\",\n\t\"error\": {}\n}",
+	} {
+		event, err := openairt.UnmarshalServerEvent([]byte(payload))
+		require.NoError(t, err, payload)
+		require.Equal(t, openairt.ServerEventTypeError, event.ServerEventType(), payload)
+	}
+
+	_, err := openairt.UnmarshalServerEvent([]byte(`{"type":"error","error":{"type":}}`))
+	require.Error(t, err)
+	_, err = openairt.UnmarshalServerEvent([]byte(`{"type":"unknown.event"}`))
+	require.ErrorContains(t, err, "unknown server event type: unknown.event")
+	_, err = openairt.UnmarshalServerEvent([]byte(`[]`))
+	require.Error(t, err)
+}
+
+func TestResponseOutputAudioDelta(t *testing.T) {
+	data, audio := audioDelta(t)
+	event, err := openairt.UnmarshalServerEvent([]byte(data))
+	require.NoError(t, err)
+	delta := event.(openairt.ResponseOutputAudioDeltaEvent) //nolint:errcheck // checked by require.Equal
+	require.Equal(t, len(audio), delta.AudioLen())
+
+	dst := []byte("head")
+	dst, err = delta.AppendAudio(dst)
+	require.NoError(t, err)
+	require.Equal(t, append([]byte("head"), audio...), dst)
+
+	// A buffer with enough capacity is reused.
+	buf := make([]byte, 0, len(audio))
+	out, err := delta.AppendAudio(buf)
+	require.NoError(t, err)
+	require.Equal(t, audio, out)
+	require.Same(t, &buf[:1][0], &out[0])
+
+	streamed, err := io.ReadAll(delta.AudioReader())
+	require.NoError(t, err)
+	require.Equal(t, audio, streamed)
+
+	invalid := openairt.ResponseOutputAudioDeltaEvent{Delta: delta.Delta[:2000] + "!" + delta.Delta[2001:]}
+	dst, err = invalid.AppendAudio([]byte("head"))
+	require.Error(t, err)
+	require.Equal(t, []byte("head"), dst)
+	_, err = io.ReadAll(invalid.AudioReader())
+	require.Error(t, err)
+	_, err = openairt.ResponseOutputAudioDeltaEvent{Delta: "AAAAA=A="}.AppendAudio(nil)
+	require.Error(t, err)
+}
+
+func TestConnReadMessagePooled(t *testing.T) {
+	var payloads []string
+	for i := 0; i < 20; i++ {
+		payloads = append(payloads, transcriptDelta("resp_1", "item_1", fmt.Sprintf("delta %d %s", i, bytes.Repeat([]byte("x"), i*100))))
+	}
+	conn := dialCoderServer(t, func(c *websocket.Conn) {
+		for _, payload := range payloads {
+			if err := c.Write(context.Background(), websocket.MessageText, []byte(payload)); err != nil {
+				return
+			}
+		}
+		_ = c.Close(websocket.StatusNormalClosure, "")
+	})
+
+	// The events don't share the pooled buffers they are read into.
+	var events []openairt.ServerEvent
+	for range payloads {
+		event, err := conn.ReadMessage(context.Background())
+		require.NoError(t, err)
+		events = append(events, event)
+	}
+	for i, event := range events {
+		expected, err := openairt.UnmarshalServerEvent([]byte(payloads[i]))
+		require.NoError(t, err)
+		require.Equal(t, expected, event)
+	}
+	_, err := conn.ReadMessage(context.Background())
+	var closeErr *openairt.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	require.Equal(t, openairt.StatusNormalClosure, closeErr.Code)
+}
+
+// doubleUnmarshal is the previous UnmarshalServerEvent, which parsed the type then the event.
+func doubleUnmarshal[T openairt.ServerEvent](data []byte) (openairt.ServerEvent, error) {
+	var eventType struct {
+		Type openairt.ServerEventType `json:"type"`
+	}
+	if err := json.Unmarshal(data, &eventType); err != nil {
+		return nil, err
+	}
+	var event T
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func BenchmarkUnmarshalServerEvent(b *testing.B) {
+	audio, _ := audioDelta(b)
+	for _, bc := range []struct {
+		name   string
+		data   string
+		legacy func([]byte) (openairt.ServerEvent, error)
+	}{
+		{
+			name:   "AudioDelta",
+			data:   audio,
+			legacy: doubleUnmarshal[openairt.ResponseOutputAudioDeltaEvent],
+		},
+		{
+			name:   "TranscriptDelta",
+			data:   transcriptDelta("resp_001", "item_008", "Hello, how can I help you today?"),
+			legacy: doubleUnmarshal[openairt.ResponseOutputAudioTranscriptDeltaEvent],
+		},
+		{
+			name:   "ResponseDone",
+			data:   responseDoneEvent("resp_001", 120, 80),
+			legacy: doubleUnmarshal[openairt.ResponseDoneEvent],
+		},
+	} {
+		data := []byte(bc.data)
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := openairt.UnmarshalServerEvent(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(bc.name+"/DoubleUnmarshal", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := bc.legacy(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkResponseOutputAudioDelta(b *testing.B) {
+	data, audio := audioDelta(b)
+	event, err := openairt.UnmarshalServerEvent([]byte(data))
+	require.NoError(b, err)
+	delta := event.(openairt.ResponseOutputAudioDeltaEvent) //nolint:errcheck // checked by require.NoError
+
+	b.Run("DecodeString", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(audio)))
+		for i := 0; i < b.N; i++ {
+			if _, err := base64.StdEncoding.DecodeString(delta.Delta); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("AppendAudio", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(audio)))
+		buf := make([]byte, 0, len(audio))
+		for i := 0; i < b.N; i++ {
+			if _, err := delta.AppendAudio(buf[:0]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkConnReadMessage(b *testing.B) {
+	delta, _ := audioDelta(b)
+	data := []byte(delta)
+	ctx, cancel := context.WithCancel(context.Background())
+	conn := dialCoderServer(b, func(c *websocket.Conn) {
+		for ctx.Err() == nil {
+			if err := c.Write(ctx, websocket.MessageText, data); err != nil {
+				return
+			}
+		}
+	})
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := conn.ReadMessage(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	cancel()
+	_ = conn.Close()
+}
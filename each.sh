@@ -3,6 +3,9 @@
 mods=(
     .
     ./contrib/ws-gorilla
+    ./contrib/trace-otel
+    ./contrib/metrics-prometheus
+    ./contrib/log-slog
 )
 
 for mod in "${mods[@]}"; do
@@ -0,0 +1,359 @@
+package openairt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultEventsBuffer = 64
+
+// ErrEventStreamEnded is returned by EventStream.Next once the stream ended without error,
+// e.g. after the response.done of a Response stream or after Close.
+var ErrEventStreamEnded = errors.New("event stream ended")
+
+// OverflowPolicy decides what happens to an event when the buffer of its EventStream is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock stops reading the connection until there's room in the buffer.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the event.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest buffered event to make room for the event.
+	OverflowDropOldest
+)
+
+// EventsOption configures Conn.Events.
+type EventsOption func(*eventsOption)
+
+type eventsOption struct {
+	buffer   int
+	overflow OverflowPolicy
+}
+
+// WithEventsBuffer sets the buffer size of the event streams. Default is 64.
+func WithEventsBuffer(size int) EventsOption {
+	return func(opts *eventsOption) {
+		opts.buffer = size
+	}
+}
+
+// WithOverflow sets the policy applied when the buffer of an event stream is full. Default is OverflowBlock.
+func WithOverflow(policy OverflowPolicy) EventsOption {
+	return func(opts *eventsOption) {
+		opts.overflow = policy
+	}
+}
+
+// EventStream is a buffered stream of server events, see Conn.Events.
+type EventStream struct {
+	root   *EventStream
+	opts   eventsOption
+	match  func(ServerEvent) bool
+	last   func(ServerEvent) bool
+	ch     chan ServerEvent
+	quit   chan struct{}
+	once   sync.Once
+	sendMu sync.Mutex
+	ended  bool
+	err    error
+
+	dropped int64
+
+	// subs are the sub-streams of the root stream, in creation order.
+	mu        sync.Mutex
+	subs      []*EventStream
+	subsEnded bool
+	endErr    error
+}
+
+// Events reads the connection in a standalone goroutine until ctx is done or the connection fails,
+// and returns the stream of the server events. It's an alternative to ConnHandler, and both
+// shouldn't be used on the same connection.
+//
+// The events matching a sub-stream, see Response, Item and Filter, are delivered to the first
+// matching sub-stream instead of this stream.
+func (c *Conn) Events(ctx context.Context, opts ...EventsOption) *EventStream {
+	options := eventsOption{buffer: defaultEventsBuffer}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.buffer <= 0 {
+		options.buffer = defaultEventsBuffer
+	}
+	s := newEventStream(nil, options)
+	handler := NewConnHandler(ctx, c, s.dispatch)
+	handler.Start()
+	go func() {
+		s.end(handler.Wait())
+	}()
+	return s
+}
+
+func newEventStream(root *EventStream, opts eventsOption) *EventStream {
+	return &EventStream{
+		root: root,
+		opts: opts,
+		ch:   make(chan ServerEvent, opts.buffer),
+		quit: make(chan struct{}),
+	}
+}
+
+// C returns the channel of the events, closed once the stream ended.
+func (s *EventStream) C() <-chan ServerEvent {
+	return s.ch
+}
+
+// Err returns the terminal error once C is closed: the error of the context or of the connection.
+// It's nil if the stream ended normally or is still running.
+func (s *EventStream) Err() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	return s.err
+}
+
+// Next returns the next event, or the terminal error once the stream ended, ErrEventStreamEnded if none.
+func (s *EventStream) Next(ctx context.Context) (ServerEvent, error) {
+	select {
+	case event, ok := <-s.ch:
+		if ok {
+			return event, nil
+		}
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrEventStreamEnded
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Dropped returns the number of events dropped by the overflow policy.
+func (s *EventStream) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+// Response returns the sub-stream of the events of the response, which ends after its response.done.
+// The events read before it's created are not included.
+func (s *EventStream) Response(responseID string) *EventStream {
+	isDone := func(event ServerEvent) bool {
+		done, ok := event.(ResponseDoneEvent)
+		return ok && done.Response.ID == responseID
+	}
+	return s.subscribe(func(event ServerEvent) bool {
+		id, _ := serverEventIDs(event)
+		return id == responseID
+	}, isDone)
+}
+
+// Item returns the sub-stream of the events of the item. It runs until Close or the end of the stream.
+func (s *EventStream) Item(itemID string) *EventStream {
+	return s.subscribe(func(event ServerEvent) bool {
+		_, id := serverEventIDs(event)
+		return id == itemID
+	}, nil)
+}
+
+// Filter returns the sub-stream of the events matching match. It runs until Close or the end of the stream.
+func (s *EventStream) Filter(match func(ServerEvent) bool) *EventStream {
+	return s.subscribe(match, nil)
+}
+
+// Close ends a sub-stream, its later events are delivered to the stream it was created from.
+// Closing the stream returned by Events only stops delivering to it, cancel its context to stop reading.
+func (s *EventStream) Close() {
+	root := s.rootStream()
+	root.mu.Lock()
+	for i, sub := range root.subs {
+		if sub == s {
+			root.subs = append(root.subs[:i], root.subs[i+1:]...)
+			break
+		}
+	}
+	root.mu.Unlock()
+	s.finish(nil)
+}
+
+func (s *EventStream) rootStream() *EventStream {
+	if s.root != nil {
+		return s.root
+	}
+	return s
+}
+
+func (s *EventStream) subscribe(match, last func(ServerEvent) bool) *EventStream {
+	root := s.rootStream()
+	sub := newEventStream(root, root.opts)
+	sub.match = match
+	sub.last = last
+
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	if root.subsEnded {
+		sub.finish(root.endErr)
+		return sub
+	}
+	root.subs = append(root.subs, sub)
+	return sub
+}
+
+// dispatch delivers the event to the first matching sub-stream, or to the root stream.
+func (s *EventStream) dispatch(ctx context.Context, event ServerEvent) {
+	target := s
+	s.mu.Lock()
+	for i, sub := range s.subs {
+		if sub.match(event) {
+			target = sub
+			if sub.last != nil && sub.last(event) {
+				s.subs = append(s.subs[:i], s.subs[i+1:]...)
+			}
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	target.send(ctx, event)
+	if target != s && target.last != nil && target.last(event) {
+		target.finish(nil)
+	}
+}
+
+func (s *EventStream) send(ctx context.Context, event ServerEvent) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	if s.ended {
+		return
+	}
+	switch s.opts.overflow {
+	case OverflowDropNewest:
+		select {
+		case s.ch <- event:
+		default:
+			atomic.AddInt64(&s.dropped, 1)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case s.ch <- event:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+				atomic.AddInt64(&s.dropped, 1)
+			default:
+			}
+		}
+	default:
+		select {
+		case s.ch <- event:
+		case <-s.quit:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// end ends the root stream and its sub-streams with the terminal error.
+func (s *EventStream) end(err error) {
+	s.mu.Lock()
+	subs := s.subs
+	s.subs = nil
+	s.subsEnded = true
+	s.endErr = err
+	s.mu.Unlock()
+	for _, sub := range subs {
+		sub.finish(err)
+	}
+	s.finish(err)
+}
+
+func (s *EventStream) finish(err error) {
+	s.once.Do(func() {
+		close(s.quit)
+		s.sendMu.Lock()
+		s.ended = true
+		s.err = err
+		close(s.ch)
+		s.sendMu.Unlock()
+	})
+}
+
+// serverEventIDs returns the IDs of the response and of the item the event refers to.
+func serverEventIDs(event ServerEvent) (responseID, itemID string) { //nolint:funlen,cyclop,gocyclo // one case per event type
+	switch e := event.(type) {
+	case InputAudioBufferCommittedEvent:
+		return "", e.ItemID
+	case InputAudioBufferSpeechStartedEvent:
+		return "", e.ItemID
+	case InputAudioBufferSpeechStoppedEvent:
+		return "", e.ItemID
+	case InputAudioBufferTimeoutTriggeredEvent:
+		return "", e.ItemID
+	case ConversationItemAddedEvent:
+		return "", messageItemID(e.Item)
+	case ConversationItemDoneEvent:
+		return "", messageItemID(e.Item)
+	case ConversationItemRetrievedEvent:
+		return "", messageItemID(e.Item)
+	case ConversationItemInputAudioTranscriptionCompletedEvent:
+		return "", e.ItemID
+	case ConversationItemInputAudioTranscriptionDeltaEvent:
+		return "", e.ItemID
+	case ConversationItemInputAudioTranscriptionSegmentEvent:
+		return "", e.ItemID
+	case ConversationItemInputAudioTranscriptionFailedEvent:
+		return "", e.ItemID
+	case ConversationItemTruncatedEvent:
+		return "", e.ItemID
+	case ConversationItemDeletedEvent:
+		return "", e.ItemID
+	case ResponseCreatedEvent:
+		return e.Response.ID, ""
+	case ResponseDoneEvent:
+		return e.Response.ID, ""
+	case ResponseOutputItemAddedEvent:
+		return e.ResponseID, messageItemID(e.Item)
+	case ResponseOutputItemDoneEvent:
+		return e.ResponseID, messageItemID(e.Item)
+	case ResponseContentPartAddedEvent:
+		return e.ResponseID, e.ItemID
+	case ResponseContentPartDoneEvent:
+		return e.ResponseID, e.ItemID
+	case ResponseOutputTextDeltaEvent:
+		return e.ResponseID, e.ItemID
+	case ResponseOutputTextDoneEvent:
+		return e.ResponseID, e.ItemID
+	case ResponseOutputAudioTranscriptDeltaEvent:
+		return e.ResponseID, e.ItemID
+	case ResponseOutputAudioTranscriptDoneEvent:
+		return e.ResponseID, e.ItemID
+	case ResponseOutputAudioDeltaEvent:
+		return e.ResponseID, e.ItemID
+	case ResponseOutputAudioDoneEvent:
+		return e.ResponseID, e.ItemID
+	case ResponseFunctionCallArgumentsDeltaEvent:
+		return e.ResponseID, e.ItemID
+	case ResponseFunctionCallArgumentsDoneEvent:
+		return e.ResponseID, e.ItemID
+	case ResponseMcpCallArgumentsDeltaEvent:
+		return e.ResponseID, e.ItemID
+	case ResponseMcpCallArgumentsDoneEvent:
+		return e.ResponseID, e.ItemID
+	case ResponseMcpCallInProgressEvent:
+		return "", e.ItemID
+	case ResponseMcpCallCompletedEvent:
+		return "", e.ItemID
+	case ResponseMcpCallFailedEvent:
+		return "", e.ItemID
+	case McpListToolsInProgressEvent:
+		return "", e.ItemID
+	case McpListToolsCompletedEvent:
+		return "", e.ItemID
+	case McpListToolsFailedEvent:
+		return "", e.ItemID
+	default:
+		return "", ""
+	}
+}
@@ -0,0 +1,178 @@
+package openairt_test
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func nextEvent(t *testing.T, stream *openairt.EventStream) openairt.ServerEvent {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	event, err := stream.Next(ctx)
+	require.NoError(t, err)
+	return event
+}
+
+func deltaOf(t *testing.T, event openairt.ServerEvent) string {
+	t.Helper()
+	delta, ok := event.(openairt.ResponseOutputAudioTranscriptDeltaEvent)
+	require.True(t, ok, "unexpected event %s", event.ServerEventType())
+	return delta.Delta
+}
+
+func TestConnEvents(t *testing.T) {
+	conn, server := newFakeServer(t, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := conn.Events(ctx)
+
+	server.push(`{"type":"response.created","response":{"id":"resp_1","status":"in_progress"}}`)
+	created, ok := nextEvent(t, events).(openairt.ResponseCreatedEvent)
+	require.True(t, ok)
+	response := events.Response(created.Response.ID)
+	item := events.Item("item_2")
+
+	server.push(
+		transcriptDelta("resp_1", "item_1", "a"),
+		transcriptDelta("resp_2", "item_2", "b"),
+		transcriptDelta("resp_3", "item_3", "c"),
+		responseDoneEvent("resp_1", 10, 10),
+		transcriptDelta("resp_1", "item_1", "d"),
+	)
+	require.Equal(t, "a", deltaOf(t, nextEvent(t, response)))
+	require.IsType(t, openairt.ResponseDoneEvent{}, nextEvent(t, response))
+	_, err := response.Next(context.Background())
+	require.ErrorIs(t, err, openairt.ErrEventStreamEnded)
+	require.NoError(t, response.Err())
+
+	require.Equal(t, "b", deltaOf(t, nextEvent(t, item)))
+	// The events of no sub-stream, and the ones after the end of a sub-stream, go to the parent.
+	require.Equal(t, "c", deltaOf(t, nextEvent(t, events)))
+	require.Equal(t, "d", deltaOf(t, nextEvent(t, events)))
+
+	// A closed sub-stream gives its events back to the parent.
+	filtered := events.Filter(func(event openairt.ServerEvent) bool {
+		return event.ServerEventType() == openairt.ServerEventTypeInputAudioBufferCleared
+	})
+	filtered.Close()
+	server.push(`{"type":"input_audio_buffer.cleared"}`)
+	require.Equal(t, openairt.ServerEventTypeInputAudioBufferCleared, nextEvent(t, events).ServerEventType())
+
+	cancel()
+	var endErr error
+	for endErr == nil {
+		_, endErr = events.Next(context.Background())
+	}
+	require.ErrorIs(t, endErr, context.Canceled)
+	require.ErrorIs(t, events.Err(), context.Canceled)
+	_, err = item.Next(context.Background())
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = events.Response("resp_4").Next(context.Background())
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestConnEventsOverflow(t *testing.T) {
+	deltas := []string{
+		transcriptDelta("resp_1", "item_1", "a"),
+		transcriptDelta("resp_1", "item_1", "b"),
+		transcriptDelta("resp_1", "item_1", "c"),
+		transcriptDelta("resp_1", "item_1", "d"),
+	}
+	for _, tc := range []struct {
+		policy  openairt.OverflowPolicy
+		dropped int64
+		want    []string
+	}{
+		{policy: openairt.OverflowBlock, want: []string{"a", "b", "c", "d"}},
+		{policy: openairt.OverflowDropNewest, dropped: 2, want: []string{"a", "b"}},
+		{policy: openairt.OverflowDropOldest, dropped: 2, want: []string{"c", "d"}},
+	} {
+		conn, server := newFakeServer(t, nil, nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		events := conn.Events(ctx, openairt.WithEventsBuffer(2), openairt.WithOverflow(tc.policy))
+		server.push(deltas...)
+		if tc.dropped > 0 {
+			require.Eventually(t, func() bool { return events.Dropped() == tc.dropped }, time.Second, time.Millisecond)
+		} else {
+			require.Eventually(t, func() bool { return len(events.C()) == 2 }, time.Second, time.Millisecond)
+		}
+		var got []string
+		for len(got) < len(tc.want) {
+			got = append(got, deltaOf(t, nextEvent(t, events)))
+		}
+		require.Equal(t, tc.want, got, "policy %d", tc.policy)
+		require.Equal(t, tc.dropped, events.Dropped())
+		cancel()
+	}
+}
+
+// TestServerEventIDsCoverage checks that the events referring to a response or an item
+// are all routed by their IDs, which serverEventIDs switches on.
+func TestServerEventIDsCoverage(t *testing.T) {
+	fset := token.NewFileSet()
+	events, err := parser.ParseFile(fset, "server_event.go", nil, 0)
+	require.NoError(t, err)
+	var withIDs []string
+	for _, decl := range events.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec) //nolint:forcetypeassert // a type declaration
+			st, ok := typeSpec.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range st.Fields.List {
+				if hasIDField(field) {
+					withIDs = append(withIDs, typeSpec.Name.Name)
+					break
+				}
+			}
+		}
+	}
+	require.Contains(t, withIDs, "ResponseOutputAudioDeltaEvent")
+
+	stream, err := parser.ParseFile(fset, "events.go", nil, 0)
+	require.NoError(t, err)
+	cases := make(map[string]bool)
+	ast.Inspect(stream, func(n ast.Node) bool {
+		fn, ok := n.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "serverEventIDs" {
+			return true
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if clause, ok := n.(*ast.CaseClause); ok {
+				for _, expr := range clause.List {
+					if ident, ok := expr.(*ast.Ident); ok {
+						cases[ident.Name] = true
+					}
+				}
+			}
+			return true
+		})
+		return false
+	})
+	for _, name := range withIDs {
+		require.True(t, cases[name], "serverEventIDs misses %s", name)
+	}
+}
+
+func hasIDField(field *ast.Field) bool {
+	for _, name := range field.Names {
+		switch name.Name {
+		case "ResponseID", "Response", "ItemID", "Item":
+			return true
+		}
+	}
+	return false
+}
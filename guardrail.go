@@ -0,0 +1,262 @@
+package openairt
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GuardrailOutput is the output of a response evaluated by an OutputGuardrail.
+type GuardrailOutput struct {
+	ResponseID   string
+	ItemID       string
+	ContentIndex int
+	// Audio reports that the text is the transcript of audio output.
+	Audio bool
+	// Text is the output of the content so far, including Delta.
+	Text  string
+	Delta string
+}
+
+// OutputGuardrail evaluates the output of a response as it streams, and returns true with a reason
+// to stop the response. It's called from the ConnHandler on every delta, so it should return quickly.
+type OutputGuardrail func(ctx context.Context, output GuardrailOutput) (reason string, tripped bool)
+
+// BlockPhrases trips on the outputs containing one of the phrases, ignoring case.
+func BlockPhrases(phrases ...string) OutputGuardrail {
+	lower := make([]string, 0, len(phrases))
+	for _, p := range phrases {
+		lower = append(lower, strings.ToLower(p))
+	}
+	return func(_ context.Context, output GuardrailOutput) (string, bool) {
+		text := strings.ToLower(output.Text)
+		for i, p := range lower {
+			if strings.Contains(text, p) {
+				return "blocked phrase: " + phrases[i], true
+			}
+		}
+		return "", false
+	}
+}
+
+// BlockPattern trips on the outputs matching the pattern.
+func BlockPattern(pattern *regexp.Regexp) OutputGuardrail {
+	return func(_ context.Context, output GuardrailOutput) (string, bool) {
+		if pattern.MatchString(output.Text) {
+			return "blocked pattern: " + pattern.String(), true
+		}
+		return "", false
+	}
+}
+
+// GuardrailEvent is the report of a tripped guardrail.
+type GuardrailEvent struct {
+	ResponseID   string
+	ItemID       string
+	ContentIndex int
+	Audio        bool
+	// Text is the output which tripped the guardrail.
+	Text   string
+	Reason string
+	// SafeReply is the reply added in place of the removed item, if any.
+	SafeReply string
+	TrippedAt time.Time
+	// Err is the error of sending the events stopping the response and removing the item, if any.
+	Err error
+}
+
+// OutputGuardrailsOptions configures OutputGuardrails.
+type OutputGuardrailsOptions struct {
+	// Guardrails are evaluated in order on every delta until one trips.
+	Guardrails []OutputGuardrail
+
+	// Truncate truncates the audio of the offending item at the played position instead of deleting the item.
+	// Text items are always deleted.
+	Truncate bool
+
+	// AudioPlayed returns how much audio of the item has been played to the user, used to truncate it.
+	// Default is none, which truncates all the audio.
+	AudioPlayed func(itemID string) time.Duration
+
+	// ClearAudioBuffer sends output_audio_buffer.clear, which is only supported by WebRTC and SIP connections.
+	// On WebSocket connections, the client stops its own playback on the GuardrailEvent.
+	ClearAudioBuffer bool
+
+	// SafeReply is added to the conversation as an assistant message in place of the removed item, if not empty.
+	SafeReply string
+
+	// SpeakSafeReply creates an out-of-band response saying the SafeReply once the stopped response is done,
+	// or once no response is in progress on the connection.
+	SpeakSafeReply bool
+
+	// OnTrip is called with the event of every tripped guardrail.
+	OnTrip func(GuardrailEvent)
+
+	// OnError is called with the errors of sending the response speaking the SafeReply,
+	// including the errors the server answers to it.
+	OnError func(err error)
+}
+
+// OutputGuardrails evaluates the output text and audio transcript of the responses as they stream.
+// When a guardrail trips, it cancels the response, clears the output audio buffer if ClearAudioBuffer
+// is set, removes the offending item, adds the safe reply, if any, and reports a GuardrailEvent. The later
+// deltas of the stopped response aren't evaluated.
+//
+// Its HandleEvent must be registered on the ConnHandler of the connection.
+type OutputGuardrails struct {
+	conn *Conn
+	opts OutputGuardrailsOptions
+
+	mu        sync.Mutex
+	responses map[string]*guardedResponse
+	events    []GuardrailEvent
+}
+
+// guardedResponse is the output of a response under evaluation.
+type guardedResponse struct {
+	texts   map[guardedContent]*strings.Builder
+	tripped bool
+}
+
+type guardedContent struct {
+	itemID       string
+	contentIndex int
+}
+
+// NewOutputGuardrails creates OutputGuardrails on conn.
+func NewOutputGuardrails(conn *Conn, opts OutputGuardrailsOptions) *OutputGuardrails {
+	return &OutputGuardrails{
+		conn:      conn,
+		opts:      opts,
+		responses: make(map[string]*guardedResponse),
+	}
+}
+
+// Events returns the events of the tripped guardrails.
+func (g *OutputGuardrails) Events() []GuardrailEvent {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]GuardrailEvent(nil), g.events...)
+}
+
+// HandleEvent is the ServerEventHandler of the OutputGuardrails.
+func (g *OutputGuardrails) HandleEvent(ctx context.Context, event ServerEvent) {
+	switch e := event.(type) {
+	case ResponseOutputTextDeltaEvent:
+		g.evaluate(ctx, GuardrailOutput{
+			ResponseID: e.ResponseID, ItemID: e.ItemID, ContentIndex: e.ContentIndex, Delta: e.Delta,
+		})
+	case ResponseOutputAudioTranscriptDeltaEvent:
+		g.evaluate(ctx, GuardrailOutput{
+			ResponseID: e.ResponseID, ItemID: e.ItemID, ContentIndex: e.ContentIndex, Delta: e.Delta, Audio: true,
+		})
+	case ResponseDoneEvent:
+		g.mu.Lock()
+		resp, ok := g.responses[e.Response.ID]
+		delete(g.responses, e.Response.ID)
+		g.mu.Unlock()
+		if ok && resp.tripped && g.opts.SpeakSafeReply && g.opts.SafeReply != "" {
+			err := g.conn.createResponse(ctx, ResponseCreateEvent{Response: ResponseCreateParams{
+				Conversation: "none",
+				Instructions: "Say exactly the following, and nothing else: " + g.opts.SafeReply,
+			}}, g.report)
+			g.report(err)
+		}
+	}
+}
+
+// report reports the error of speaking the SafeReply, if any.
+func (g *OutputGuardrails) report(err error) {
+	if err != nil && g.opts.OnError != nil {
+		g.opts.OnError(err)
+	}
+}
+
+func (g *OutputGuardrails) evaluate(ctx context.Context, output GuardrailOutput) {
+	g.mu.Lock()
+	resp, ok := g.responses[output.ResponseID]
+	if !ok {
+		resp = &guardedResponse{texts: make(map[guardedContent]*strings.Builder)}
+		g.responses[output.ResponseID] = resp
+	}
+	if resp.tripped {
+		g.mu.Unlock()
+		return
+	}
+	key := guardedContent{output.ItemID, output.ContentIndex}
+	text, ok := resp.texts[key]
+	if !ok {
+		text = &strings.Builder{}
+		resp.texts[key] = text
+	}
+	text.WriteString(output.Delta)
+	output.Text = text.String()
+	g.mu.Unlock()
+
+	for _, guardrail := range g.opts.Guardrails {
+		reason, tripped := guardrail(ctx, output)
+		if !tripped {
+			continue
+		}
+		g.mu.Lock()
+		resp.tripped = true
+		g.mu.Unlock()
+		g.trip(ctx, output, reason)
+		return
+	}
+}
+
+// trip stops the response and removes the offending item.
+func (g *OutputGuardrails) trip(ctx context.Context, output GuardrailOutput, reason string) {
+	event := GuardrailEvent{
+		ResponseID:   output.ResponseID,
+		ItemID:       output.ItemID,
+		ContentIndex: output.ContentIndex,
+		Audio:        output.Audio,
+		Text:         output.Text,
+		Reason:       reason,
+		SafeReply:    g.opts.SafeReply,
+		TrippedAt:    time.Now(),
+	}
+
+	messages := []ClientEvent{ResponseCancelEvent{ResponseID: output.ResponseID}}
+	if output.Audio && g.opts.ClearAudioBuffer {
+		messages = append(messages, OutputAudioBufferClearEvent{})
+	}
+	if output.Audio && g.opts.Truncate {
+		var played time.Duration
+		if g.opts.AudioPlayed != nil {
+			played = g.opts.AudioPlayed(output.ItemID)
+		}
+		messages = append(messages, ConversationItemTruncateEvent{
+			ItemID:       output.ItemID,
+			ContentIndex: output.ContentIndex,
+			AudioEndMs:   int(played.Milliseconds()),
+		})
+	} else {
+		messages = append(messages, ConversationItemDeleteEvent{ItemID: output.ItemID})
+	}
+	if g.opts.SafeReply != "" {
+		messages = append(messages, ConversationItemCreateEvent{Item: MessageItemUnion{
+			Assistant: &MessageItemAssistant{Content: []MessageContentOutput{{
+				Type: MessageContentTypeOutputText,
+				Text: g.opts.SafeReply,
+			}}},
+		}})
+	}
+	for _, msg := range messages {
+		if err := g.conn.SendMessage(ctx, msg); err != nil {
+			event.Err = err
+			break
+		}
+	}
+
+	g.mu.Lock()
+	g.events = append(g.events, event)
+	g.mu.Unlock()
+	if g.opts.OnTrip != nil {
+		g.opts.OnTrip(event)
+	}
+}
@@ -0,0 +1,126 @@
+package openairt_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/coder/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func transcriptDelta(responseID, itemID, delta string) string {
+	return `{"type":"response.output_audio_transcript.delta","response_id":"` + responseID +
+		`","item_id":"` + itemID + `","content_index":0,"delta":"` + delta + `"}`
+}
+
+func TestOutputGuardrailsAudio(t *testing.T) {
+	conn, server := newFakeServer(t, nil, nil)
+	var events []openairt.GuardrailEvent
+	guardrails := openairt.NewOutputGuardrails(conn, openairt.OutputGuardrailsOptions{
+		Guardrails:       []openairt.OutputGuardrail{openairt.BlockPhrases("secret code")},
+		Truncate:         true,
+		AudioPlayed:      func(string) time.Duration { return 1500 * time.Millisecond },
+		ClearAudioBuffer: true,
+		SafeReply:        "Sorry, I can't share that.",
+		SpeakSafeReply:   true,
+		OnTrip:           func(e openairt.GuardrailEvent) { events = append(events, e) },
+	})
+	handleServerEvents(t, guardrails.HandleEvent,
+		transcriptDelta("resp_1", "item_1", "The Secret "),
+		transcriptDelta("resp_2", "item_2", "code"),
+	)
+	require.Empty(t, server.events())
+
+	// The phrase is detected across the deltas of the content, the later deltas are ignored.
+	handleServerEvents(t, guardrails.HandleEvent,
+		transcriptDelta("resp_1", "item_1", "code is"),
+		transcriptDelta("resp_1", "item_1", " 1234"),
+	)
+	require.Len(t, events, 1)
+	require.Equal(t, "resp_1", events[0].ResponseID)
+	require.Equal(t, "item_1", events[0].ItemID)
+	require.True(t, events[0].Audio)
+	require.Equal(t, "The Secret code is", events[0].Text)
+	require.Equal(t, "blocked phrase: secret code", events[0].Reason)
+	require.NoError(t, events[0].Err)
+
+	sent := server.events()
+	require.Len(t, sent, 4)
+	require.Equal(t, "response.cancel", sent[0]["type"])
+	require.Equal(t, "resp_1", sent[0]["response_id"])
+	require.Equal(t, "output_audio_buffer.clear", sent[1]["type"])
+	require.Equal(t, "conversation.item.truncate", sent[2]["type"])
+	require.Equal(t, "item_1", sent[2]["item_id"])
+	require.EqualValues(t, 1500, sent[2]["audio_end_ms"])
+	require.Equal(t, map[string]any{
+		"type": "message", "role": "assistant",
+		"content": []any{map[string]any{"type": "output_text", "text": "Sorry, I can't share that."}},
+	}, sent[3]["item"])
+
+	// The safe reply is spoken once the cancelled response is done.
+	handleServerEvents(t, guardrails.HandleEvent, responseDoneEvent("resp_1", 10, 10))
+	creates := sentOfType(server, "response.create")
+	require.Len(t, creates, 1)
+	response := creates[0]["response"].(map[string]any)
+	require.Equal(t, "none", response["conversation"])
+	require.Contains(t, response["instructions"], "Sorry, I can't share that.")
+
+	handleServerEvents(t, guardrails.HandleEvent, responseDoneEvent("resp_2", 10, 10))
+	require.Len(t, sentOfType(server, "response.create"), 1)
+	require.Len(t, guardrails.Events(), 1)
+}
+
+func TestOutputGuardrailsText(t *testing.T) {
+	conn, server := newFakeServer(t, nil, nil)
+	guardrails := openairt.NewOutputGuardrails(conn, openairt.OutputGuardrailsOptions{
+		Guardrails: []openairt.OutputGuardrail{openairt.BlockPattern(regexp.MustCompile(`\d{4}-\d{4}`))},
+		Truncate:   true,
+	})
+	handleServerEvents(t, guardrails.HandleEvent,
+		`{"type":"response.output_text.delta","response_id":"resp_1","item_id":"item_1","delta":"card 1234-"}`,
+		`{"type":"response.output_text.delta","response_id":"resp_1","item_id":"item_1","delta":"5678"}`,
+	)
+	events := guardrails.Events()
+	require.Len(t, events, 1)
+	require.False(t, events[0].Audio)
+
+	// Text items are deleted, and there is no audio buffer to clear.
+	var types []any
+	for _, e := range server.events() {
+		types = append(types, e["type"])
+	}
+	require.Equal(t, []any{"response.cancel", "conversation.item.delete"}, types)
+}
+
+func TestOutputGuardrailsSafeReplyError(t *testing.T) {
+	opts := openairt.OutputGuardrailsOptions{
+		Guardrails:     []openairt.OutputGuardrail{openairt.BlockPhrases("secret")},
+		SafeReply:      "Sorry.",
+		SpeakSafeReply: true,
+	}
+	conn, server := newFakeServer(t, nil, nil)
+	handleServerEvents(t, openairt.NewOutputGuardrails(conn, opts).HandleEvent, transcriptDelta("resp_1", "item_1", "secret"))
+	// The audio buffer isn't cleared by default, WebSocket connections don't support it.
+	var types []any
+	for _, e := range server.events() {
+		types = append(types, e["type"])
+	}
+	require.Equal(t, []any{"response.cancel", "conversation.item.delete", "conversation.item.create"}, types)
+
+	// The errors of sending the spoken safe reply are reported.
+	var errs []error
+	opts.OnError = func(err error) { errs = append(errs, err) }
+	closed := dialCoderServer(t, func(c *websocket.Conn) {
+		_, _, _ = c.Read(context.Background())
+	})
+	require.NoError(t, closed.Close())
+	guardrails := openairt.NewOutputGuardrails(closed, opts)
+	handleServerEvents(t, guardrails.HandleEvent, transcriptDelta("resp_1", "item_1", "secret"))
+	require.Error(t, guardrails.Events()[0].Err)
+	require.Empty(t, errs)
+	handleServerEvents(t, guardrails.HandleEvent, responseDoneEvent("resp_1", 10, 10))
+	require.Len(t, errs, 1)
+}
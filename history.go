@@ -0,0 +1,449 @@
+package openairt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// PreviousItemIDRoot inserts an item at the beginning of the conversation.
+const PreviousItemIDRoot = "root"
+
+// itemIDLength is the length of the item ids generated by the client, the maximum of the API.
+const itemIDLength = 32
+
+// ChatMessage is a message of the OpenAI Chat Completions API.
+type ChatMessage struct {
+	// One of system, developer, user, assistant and tool.
+	Role string `json:"role"`
+
+	// The content of the message, either a string or an array of content parts.
+	Content ChatMessageContent `json:"content,omitempty"`
+
+	Name string `json:"name,omitempty"`
+
+	// The tool calls of an assistant message.
+	ToolCalls []ChatToolCall `json:"tool_calls,omitempty"`
+
+	// The tool call a tool message responds to.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+
+	// The audio response of an assistant message.
+	Audio *ChatMessageAudio `json:"audio,omitempty"`
+}
+
+// ChatMessageContent is the content of a ChatMessage, either a string or an array of content parts.
+type ChatMessageContent struct {
+	Text  string
+	Parts []ChatContentPart
+}
+
+func (c ChatMessageContent) MarshalJSON() ([]byte, error) {
+	if c.Parts != nil {
+		return json.Marshal(c.Parts)
+	}
+	return json.Marshal(c.Text)
+}
+
+func (c *ChatMessageContent) UnmarshalJSON(data []byte) error {
+	if isNull(data) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &c.Text)
+	}
+	return json.Unmarshal(data, &c.Parts)
+}
+
+// ChatContentPart is a content part of a ChatMessage.
+type ChatContentPart struct {
+	// One of text, image_url, input_audio and refusal.
+	Type       string          `json:"type"`
+	Text       string          `json:"text,omitempty"`
+	ImageURL   *ChatImageURL   `json:"image_url,omitempty"`
+	InputAudio *ChatInputAudio `json:"input_audio,omitempty"`
+	Refusal    string          `json:"refusal,omitempty"`
+}
+
+type ChatImageURL struct {
+	// Either a URL or a base64 data URL of the image.
+	URL    string      `json:"url"`
+	Detail ImageDetail `json:"detail,omitempty"`
+}
+
+type ChatInputAudio struct {
+	// Base64 encoded audio.
+	Data string `json:"data"`
+	// The format of the audio, wav or mp3.
+	Format string `json:"format"`
+}
+
+type ChatToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ChatFunctionCall `json:"function"`
+}
+
+type ChatFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type ChatMessageAudio struct {
+	ID         string `json:"id,omitempty"`
+	Data       string `json:"data,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+}
+
+// ItemsFromChatMessages translates Chat Completions messages to conversation items.
+//
+// System and developer messages become system messages, the tool calls of an assistant message
+// become function calls following it, and tool messages become function call outputs.
+// Assistant audio becomes its transcript since the audio of assistant messages can't be populated.
+// The input audio of user messages is rejected because its format can't be converted.
+func ItemsFromChatMessages(messages []ChatMessage) ([]MessageItemUnion, error) {
+	var items []MessageItemUnion
+	for i, message := range messages {
+		converted, err := itemsFromChatMessage(message)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		items = append(items, converted...)
+	}
+	return items, nil
+}
+
+func itemsFromChatMessage(message ChatMessage) ([]MessageItemUnion, error) {
+	switch message.Role {
+	case "system", "developer":
+		text, err := chatContentText(message.Content)
+		if err != nil {
+			return nil, err
+		}
+		return []MessageItemUnion{{System: &MessageItemSystem{
+			Content: []MessageContentSystem{{Text: text}},
+		}}}, nil
+	case "user":
+		var content []MessageContentInput
+		if message.Content.Parts == nil {
+			content = append(content, MessageContentInput{Type: MessageContentTypeInputText, Text: message.Content.Text})
+		}
+		for _, part := range message.Content.Parts {
+			switch part.Type {
+			case "text":
+				content = append(content, MessageContentInput{Type: MessageContentTypeInputText, Text: part.Text})
+			case "image_url":
+				if part.ImageURL == nil {
+					return nil, errors.New("image_url part without image_url")
+				}
+				content = append(content, MessageContentInput{
+					Type:     MessageContentTypeInputImage,
+					ImageURL: part.ImageURL.URL,
+					Detail:   part.ImageURL.Detail,
+				})
+			default:
+				return nil, fmt.Errorf("unsupported user content part: %s", part.Type)
+			}
+		}
+		return []MessageItemUnion{{User: &MessageItemUser{Content: content}}}, nil
+	case "assistant":
+		var items []MessageItemUnion
+		text, err := chatContentText(message.Content)
+		if err != nil {
+			return nil, err
+		}
+		if text == "" && message.Audio != nil {
+			text = message.Audio.Transcript
+		}
+		if text != "" {
+			items = append(items, MessageItemUnion{Assistant: &MessageItemAssistant{
+				Content: []MessageContentOutput{{Type: MessageContentTypeOutputText, Text: text}},
+			}})
+		}
+		for _, call := range message.ToolCalls {
+			items = append(items, MessageItemUnion{FunctionCall: &MessageItemFunctionCall{
+				CallID:    call.ID,
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			}})
+		}
+		return items, nil
+	case "tool":
+		output, err := chatContentText(message.Content)
+		if err != nil {
+			return nil, err
+		}
+		return []MessageItemUnion{{FunctionCallOutput: &MessageItemFunctionCallOutput{
+			CallID: message.ToolCallID,
+			Output: output,
+		}}}, nil
+	default:
+		return nil, fmt.Errorf("unknown role: %s", message.Role)
+	}
+}
+
+// chatContentText joins the text and refusal parts of the content.
+func chatContentText(content ChatMessageContent) (string, error) {
+	if content.Parts == nil {
+		return content.Text, nil
+	}
+	texts := make([]string, 0, len(content.Parts))
+	for _, part := range content.Parts {
+		switch part.Type {
+		case "text":
+			texts = append(texts, part.Text)
+		case "refusal":
+			texts = append(texts, part.Refusal)
+		default:
+			return "", fmt.Errorf("unsupported content part: %s", part.Type)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// HistoryImporter recreates a conversation history in a session, e.g. to resume a chat.
+//
+// Its HandleEvent must be registered on the ConnHandler of the connection.
+type HistoryImporter struct {
+	conn *Conn
+
+	// mu serializes the imports.
+	mu sync.Mutex
+
+	pendingMu sync.Mutex
+	pending   *pendingImport
+}
+
+type pendingImport struct {
+	eventID string
+	itemID  string
+	done    chan error
+}
+
+// NewHistoryImporter creates a HistoryImporter on conn.
+func NewHistoryImporter(conn *Conn) *HistoryImporter {
+	return &HistoryImporter{conn: conn}
+}
+
+// Import creates the items in order after previousItemID, chaining each item after the previous one,
+// and returns the ids of the created items, at the indexes of the items. An empty previousItemID
+// appends to the conversation, PreviousItemIDRoot inserts at the beginning.
+//
+// The items without an id get one generated by the client, so that they are told apart from the
+// items added meanwhile, e.g. by the VAD. Each item is sent once the previous one is acknowledged
+// by conversation.item.added, so responses shouldn't be created during the import. Assistant audio
+// content becomes its transcript since the audio of assistant messages can't be populated, the
+// items left without content are skipped and their ids are empty. On failure, the ids of the items
+// before the failed one are returned with the error, a *ServerError if the server rejected an item.
+func (h *HistoryImporter) Import(ctx context.Context, items []MessageItemUnion, previousItemID string) ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ids := make([]string, len(items))
+	for i, item := range items {
+		item, ok := importableItem(item)
+		if !ok {
+			continue
+		}
+		if messageItemID(item) == "" {
+			item = withMessageItemID(item, GenerateID("item_", itemIDLength))
+		}
+		id := messageItemID(item)
+		if err := h.create(ctx, item, previousItemID); err != nil {
+			return ids[:i], fmt.Errorf("import item %d: %w", i, err)
+		}
+		ids[i] = id
+		previousItemID = id
+	}
+	return ids, nil
+}
+
+// ImportChat translates Chat Completions messages with ItemsFromChatMessages and imports them.
+func (h *HistoryImporter) ImportChat(ctx context.Context, messages []ChatMessage, previousItemID string) ([]string, error) {
+	items, err := ItemsFromChatMessages(messages)
+	if err != nil {
+		return nil, err
+	}
+	return h.Import(ctx, items, previousItemID)
+}
+
+// HandleEvent is the ServerEventHandler of the HistoryImporter.
+func (h *HistoryImporter) HandleEvent(_ context.Context, event ServerEvent) {
+	h.pendingMu.Lock()
+	defer h.pendingMu.Unlock()
+	if h.pending == nil {
+		return
+	}
+	switch e := event.(type) {
+	case ConversationItemAddedEvent:
+		if messageItemID(e.Item) == h.pending.itemID {
+			h.pending.done <- nil
+			h.pending = nil
+		}
+	case ErrorEvent:
+		if e.Error.EventID == h.pending.eventID {
+			h.pending.done <- &ServerError{Detail: e.Error}
+			h.pending = nil
+		}
+	}
+}
+
+func (h *HistoryImporter) create(ctx context.Context, item MessageItemUnion, previousItemID string) error {
+	p := &pendingImport{
+		eventID: GenerateID("event_", eventIDLength),
+		itemID:  messageItemID(item),
+		done:    make(chan error, 1),
+	}
+	h.pendingMu.Lock()
+	h.pending = p
+	h.pendingMu.Unlock()
+	defer func() {
+		h.pendingMu.Lock()
+		if h.pending == p {
+			h.pending = nil
+		}
+		h.pendingMu.Unlock()
+	}()
+
+	err := h.conn.SendMessage(ctx, ConversationItemCreateEvent{
+		EventBase:      EventBase{EventID: p.eventID},
+		PreviousItemID: previousItemID,
+		Item:           item,
+	})
+	if err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-p.done:
+		return err
+	}
+}
+
+// importableItem replaces the audio of an assistant message with its transcript,
+// and returns false if nothing is left.
+func importableItem(item MessageItemUnion) (MessageItemUnion, bool) {
+	if item.Assistant == nil {
+		return item, true
+	}
+	assistant := *item.Assistant
+	assistant.Content = nil
+	for _, content := range item.Assistant.Content {
+		switch content.Type {
+		case MessageContentTypeAudio, MessageContentTypeOutputAudio:
+			if content.Transcript == "" {
+				continue
+			}
+			content = MessageContentOutput{Type: MessageContentTypeOutputText, Text: content.Transcript}
+		}
+		assistant.Content = append(assistant.Content, content)
+	}
+	if len(assistant.Content) == 0 {
+		return MessageItemUnion{}, false
+	}
+	return MessageItemUnion{Assistant: &assistant}, true
+}
+
+// messageItemText joins the text, or transcript, of the content parts of a message.
+func messageItemText(item MessageItemUnion) string {
+	var texts []string
+	switch item.Kind() {
+	case MessageItemKindSystem:
+		for _, c := range item.System.Content {
+			texts = append(texts, c.Text)
+		}
+	case MessageItemKindUser:
+		for _, c := range item.User.Content {
+			texts = appendContentText(texts, c.Text, c.Transcript)
+		}
+	case MessageItemKindAssistant:
+		for _, c := range item.Assistant.Content {
+			texts = appendContentText(texts, c.Text, c.Transcript)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func appendContentText(texts []string, text, transcript string) []string {
+	switch {
+	case text != "":
+		return append(texts, text)
+	case transcript != "":
+		return append(texts, transcript)
+	default:
+		return texts
+	}
+}
+
+func messageItemID(item MessageItemUnion) string {
+	switch item.Kind() {
+	case MessageItemKindSystem:
+		return item.System.ID
+	case MessageItemKindUser:
+		return item.User.ID
+	case MessageItemKindAssistant:
+		return item.Assistant.ID
+	case MessageItemKindFunctionCall:
+		return item.FunctionCall.ID
+	case MessageItemKindFunctionCallOutput:
+		return item.FunctionCallOutput.ID
+	case MessageItemKindMCPApprovalResponse:
+		return item.MCPApprovalResponse.ID
+	case MessageItemKindMCPListTools:
+		return item.MCPListTools.ID
+	case MessageItemKindMCPToolCall:
+		return item.MCPToolCall.ID
+	case MessageItemKindMCPApprovalRequest:
+		return item.MCPApprovalRequest.ID
+	default:
+		return ""
+	}
+}
+
+// withMessageItemID returns a copy of item with the id.
+func withMessageItemID(item MessageItemUnion, id string) MessageItemUnion {
+	switch item.Kind() {
+	case MessageItemKindSystem:
+		system := *item.System
+		system.ID = id
+		return MessageItemUnion{System: &system}
+	case MessageItemKindUser:
+		user := *item.User
+		user.ID = id
+		return MessageItemUnion{User: &user}
+	case MessageItemKindAssistant:
+		assistant := *item.Assistant
+		assistant.ID = id
+		return MessageItemUnion{Assistant: &assistant}
+	case MessageItemKindFunctionCall:
+		call := *item.FunctionCall
+		call.ID = id
+		return MessageItemUnion{FunctionCall: &call}
+	case MessageItemKindFunctionCallOutput:
+		output := *item.FunctionCallOutput
+		output.ID = id
+		return MessageItemUnion{FunctionCallOutput: &output}
+	case MessageItemKindMCPApprovalResponse:
+		response := *item.MCPApprovalResponse
+		response.ID = id
+		return MessageItemUnion{MCPApprovalResponse: &response}
+	case MessageItemKindMCPListTools:
+		list := *item.MCPListTools
+		list.ID = id
+		return MessageItemUnion{MCPListTools: &list}
+	case MessageItemKindMCPToolCall:
+		call := *item.MCPToolCall
+		call.ID = id
+		return MessageItemUnion{MCPToolCall: &call}
+	case MessageItemKindMCPApprovalRequest:
+		request := *item.MCPApprovalRequest
+		request.ID = id
+		return MessageItemUnion{MCPApprovalRequest: &request}
+	default:
+		return item
+	}
+}
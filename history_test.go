@@ -0,0 +1,169 @@
+package openairt_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// itemAdded replies to conversation.item.create with a conversation.item.added assigning an id,
+// or with an error if the item contains "fail".
+func itemAdded() func(event map[string]any) []string {
+	n := 0
+	return func(event map[string]any) []string {
+		if event["type"] != "conversation.item.create" {
+			return nil
+		}
+		item, _ := json.Marshal(event["item"])
+		if strings.Contains(string(item), "fail") {
+			return []string{fmt.Sprintf(
+				`{"type":"error","error":{"type":"invalid_request_error","code":"invalid_value","message":"bad","event_id":%q}}`,
+				event["event_id"])}
+		}
+		var obj map[string]any
+		_ = json.Unmarshal(item, &obj)
+		if obj["id"] == nil {
+			n++
+			obj["id"] = fmt.Sprintf("item_%d", n)
+		}
+		added, _ := json.Marshal(map[string]any{
+			"type":             "conversation.item.added",
+			"previous_item_id": event["previous_item_id"],
+			"item":             obj,
+		})
+		return []string{string(added)}
+	}
+}
+
+func startHistoryImporter(t *testing.T) (*openairt.HistoryImporter, *fakeServer) {
+	t.Helper()
+	conn, server := newFakeServer(t, itemAdded(), nil)
+	importer := openairt.NewHistoryImporter(conn)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	openairt.NewConnHandler(ctx, conn, importer.HandleEvent).Start()
+	return importer, server
+}
+
+func TestItemsFromChatMessages(t *testing.T) {
+	var messages []openairt.ChatMessage
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"role":"developer","content":"be nice"},
+		{"role":"user","content":[{"type":"text","text":"what's this?"},
+			{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA","detail":"low"}}]},
+		{"role":"assistant","content":null,"tool_calls":[
+			{"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{}"}}]},
+		{"role":"tool","tool_call_id":"call_1","content":"a cat"},
+		{"role":"assistant","audio":{"id":"audio_1","transcript":"It's a cat."}}
+	]`), &messages))
+
+	items, err := openairt.ItemsFromChatMessages(messages)
+	require.NoError(t, err)
+	require.Equal(t, []openairt.MessageItemUnion{
+		{System: &openairt.MessageItemSystem{Content: []openairt.MessageContentSystem{{Text: "be nice"}}}},
+		{User: &openairt.MessageItemUser{Content: []openairt.MessageContentInput{
+			{Type: openairt.MessageContentTypeInputText, Text: "what's this?"},
+			{Type: openairt.MessageContentTypeInputImage, ImageURL: "data:image/png;base64,AAAA", Detail: openairt.ImageDetailLow},
+		}}},
+		{FunctionCall: &openairt.MessageItemFunctionCall{CallID: "call_1", Name: "lookup", Arguments: "{}"}},
+		{FunctionCallOutput: &openairt.MessageItemFunctionCallOutput{CallID: "call_1", Output: "a cat"}},
+		{Assistant: &openairt.MessageItemAssistant{Content: []openairt.MessageContentOutput{
+			{Type: openairt.MessageContentTypeOutputText, Text: "It's a cat."},
+		}}},
+	}, items)
+
+	_, err = openairt.ItemsFromChatMessages([]openairt.ChatMessage{{
+		Role: "user",
+		Content: openairt.ChatMessageContent{Parts: []openairt.ChatContentPart{
+			{Type: "input_audio", InputAudio: &openairt.ChatInputAudio{Data: "AAAA", Format: "wav"}},
+		}},
+	}})
+	require.EqualError(t, err, "message 0: unsupported user content part: input_audio")
+}
+
+func TestHistoryImporterImportChat(t *testing.T) {
+	importer, server := startHistoryImporter(t)
+	ids, err := importer.ImportChat(context.Background(), []openairt.ChatMessage{
+		{Role: "system", Content: openairt.ChatMessageContent{Text: "be nice"}},
+		{Role: "user", Content: openairt.ChatMessageContent{Text: "hi"}},
+		{Role: "assistant", Content: openairt.ChatMessageContent{Text: "hello"}},
+	}, openairt.PreviousItemIDRoot)
+	require.NoError(t, err)
+	require.Len(t, ids, 3)
+
+	events := server.events()
+	require.Len(t, events, 3)
+	require.Equal(t, "root", events[0]["previous_item_id"])
+	for i, id := range ids {
+		// The ids are assigned by the client.
+		require.Len(t, id, 32)
+		require.True(t, strings.HasPrefix(id, "item_"))
+		require.Equal(t, id, events[i]["item"].(map[string]any)["id"]) //nolint:errcheck
+		if i > 0 {
+			require.Equal(t, ids[i-1], events[i]["previous_item_id"])
+		}
+	}
+}
+
+func TestHistoryImporterImport(t *testing.T) {
+	importer, server := startHistoryImporter(t)
+	ids, err := importer.Import(context.Background(), []openairt.MessageItemUnion{
+		{User: &openairt.MessageItemUser{ID: "msg_user", Content: []openairt.MessageContentInput{
+			{Type: openairt.MessageContentTypeInputText, Text: "hi"},
+		}}},
+		// Assistant audio becomes its transcript.
+		{Assistant: &openairt.MessageItemAssistant{Content: []openairt.MessageContentOutput{
+			{Type: openairt.MessageContentTypeOutputAudio, Audio: "AAAA", Transcript: "hello"},
+		}}},
+		// Nothing is left of assistant audio without a transcript.
+		{Assistant: &openairt.MessageItemAssistant{Content: []openairt.MessageContentOutput{
+			{Type: openairt.MessageContentTypeOutputAudio, Audio: "AAAA"},
+		}}},
+		{User: &openairt.MessageItemUser{Content: []openairt.MessageContentInput{
+			{Type: openairt.MessageContentTypeInputText, Text: "fail"},
+		}}},
+	}, "")
+	var serverErr *openairt.ServerError
+	require.True(t, errors.As(err, &serverErr))
+	require.Contains(t, err.Error(), "import item 3")
+	// The ids are at the indexes of the items, the skipped item has none.
+	require.Len(t, ids, 3)
+	require.Equal(t, "msg_user", ids[0])
+	require.NotEmpty(t, ids[1])
+	require.Empty(t, ids[2])
+
+	events := server.events()
+	require.Len(t, events, 3)
+	require.Nil(t, events[0]["previous_item_id"])
+	require.Equal(t, "msg_user", events[1]["previous_item_id"])
+	require.Equal(t, map[string]any{"type": "output_text", "text": "hello"},
+		events[1]["item"].(map[string]any)["content"].([]any)[0])
+}
+
+func TestHistoryImporterImportOtherItem(t *testing.T) {
+	reply := itemAdded()
+	conn, _ := newFakeServer(t, func(event map[string]any) []string {
+		// Another user message with the same text, e.g. committed by the VAD, is added before the imported one.
+		other := itemAddedEvent(`{"id":"item_vad","type":"message","role":"user",` +
+			`"content":[{"type":"input_text","text":"hi"}]}`)
+		return append([]string{other}, reply(event)...)
+	}, nil)
+	importer := openairt.NewHistoryImporter(conn)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	openairt.NewConnHandler(ctx, conn, importer.HandleEvent).Start()
+
+	item := openairt.UserMessage("hi")
+	ids, err := importer.Import(context.Background(), []openairt.MessageItemUnion{item}, "")
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+	require.NotEqual(t, "item_vad", ids[0])
+	// The item of the caller is left unchanged.
+	require.Empty(t, item.User.ID)
+}
@@ -0,0 +1,265 @@
+package openairt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const (
+	imageMimePNG  = "image/png"
+	imageMimeJPEG = "image/jpeg"
+
+	// imageMinSide is the size under which images aren't downscaled further to fit a byte budget.
+	imageMinSide = 16
+)
+
+var (
+	// ErrUnsupportedImage is returned for images which aren't PNG or JPEG.
+	ErrUnsupportedImage = errors.New("unsupported image format, only PNG and JPEG are supported")
+
+	// ErrImageTooLarge is returned when an image can't be reduced to the byte budget.
+	ErrImageTooLarge = errors.New("image can't be reduced to the byte budget")
+
+	imageMagicPNG  = []byte("\x89PNG\r\n\x1a\n")
+	imageMagicJPEG = []byte("\xff\xd8\xff")
+
+	// imageJPEGQualities are tried in order to fit a byte budget.
+	imageJPEGQualities = []int{85, 70, 55, 40}
+)
+
+// ImageOptions configures the images loaded by LoadImage, LoadImageFile and EncodeImage.
+type ImageOptions struct {
+	// Detail level of the image.
+	Detail ImageDetail
+
+	// MaxPixels downscales the images with more pixels, keeping their aspect ratio. Zero means no limit.
+	MaxPixels int
+
+	// MaxBytes re-encodes the larger images as JPEG of decreasing quality, then downscales them until they fit.
+	// Zero means no limit.
+	MaxBytes int
+}
+
+// Image is an encoded PNG or JPEG image for the model input.
+type Image struct {
+	MimeType string
+	Data     []byte
+	Width    int
+	Height   int
+	Detail   ImageDetail
+}
+
+// LoadImage loads a PNG or JPEG image, detected from its content. It's kept as is if it fits the budgets of opts.
+func LoadImage(data []byte, opts ImageOptions) (Image, error) {
+	mimeType := detectImage(data)
+	if mimeType == "" {
+		return Image{}, ErrUnsupportedImage
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, fmt.Errorf("decode image config: %w", err)
+	}
+	img := Image{MimeType: mimeType, Data: data, Width: config.Width, Height: config.Height, Detail: opts.Detail}
+	if img.fits(opts) {
+		return img, nil
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, fmt.Errorf("decode image: %w", err)
+	}
+	return encodeImage(decoded, mimeType, opts)
+}
+
+// LoadImageFile loads a PNG or JPEG image file, see LoadImage.
+func LoadImageFile(path string, opts ImageOptions) (Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Image{}, err
+	}
+	return LoadImage(data, opts)
+}
+
+// EncodeImage encodes img as PNG, or as JPEG if the PNG doesn't fit the byte budget of opts.
+func EncodeImage(img image.Image, opts ImageOptions) (Image, error) {
+	return encodeImage(img, imageMimePNG, opts)
+}
+
+// DataURI returns the image as a base64 data URI.
+func (i Image) DataURI() string {
+	return "data:" + i.MimeType + ";base64," + base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// Content returns the image as an input_image content.
+func (i Image) Content() MessageContentInput {
+	return MessageContentInput{
+		Type:     MessageContentTypeInputImage,
+		ImageURL: i.DataURI(),
+		Detail:   i.Detail,
+	}
+}
+
+// PromptVariable returns the image as an input_image prompt variable.
+func (i Image) PromptVariable() PromptVariableUnion {
+	return PromptVariableUnion{InputImage: &PromptInputImage{ImageURL: i.DataURI(), Detail: i.Detail}}
+}
+
+// NewImageMessage returns the event creating a user message with the text, if not empty, followed by the images.
+func NewImageMessage(text string, images ...Image) ConversationItemCreateEvent {
+	content := make([]MessageContentInput, 0, len(images)+1)
+	if text != "" {
+		content = append(content, MessageContentInput{Type: MessageContentTypeInputText, Text: text})
+	}
+	for _, img := range images {
+		content = append(content, img.Content())
+	}
+	return ConversationItemCreateEvent{Item: MessageItemUnion{User: &MessageItemUser{Content: content}}}
+}
+
+// FilePromptVariable returns the file data as an input_file prompt variable, with a data URI
+// whose MIME type is guessed from the filename extension or the content.
+func FilePromptVariable(filename string, data []byte) PromptVariableUnion {
+	mimeType := mime.TypeByExtension(filepath.Ext(filename))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mediaType
+	}
+	return PromptVariableUnion{InputFile: &PromptInputFile{
+		Filename: filename,
+		FileData: "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data),
+	}}
+}
+
+// LoadFilePromptVariable reads the file as an input_file prompt variable, see FilePromptVariable.
+func LoadFilePromptVariable(path string) (PromptVariableUnion, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PromptVariableUnion{}, err
+	}
+	return FilePromptVariable(filepath.Base(path), data), nil
+}
+
+func detectImage(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, imageMagicPNG):
+		return imageMimePNG
+	case bytes.HasPrefix(data, imageMagicJPEG):
+		return imageMimeJPEG
+	default:
+		return ""
+	}
+}
+
+func (i Image) fits(opts ImageOptions) bool {
+	return (opts.MaxPixels <= 0 || i.Width*i.Height <= opts.MaxPixels) &&
+		(opts.MaxBytes <= 0 || len(i.Data) <= opts.MaxBytes)
+}
+
+// encodeImage downscales img to the pixel budget and encodes it as mimeType,
+// falling back to smaller JPEGs to fit the byte budget.
+func encodeImage(img image.Image, mimeType string, opts ImageOptions) (Image, error) {
+	bounds := img.Bounds()
+	if pixels := bounds.Dx() * bounds.Dy(); opts.MaxPixels > 0 && pixels > opts.MaxPixels {
+		scale := math.Sqrt(float64(opts.MaxPixels) / float64(pixels))
+		img = resizeImage(img, int(float64(bounds.Dx())*scale), int(float64(bounds.Dy())*scale))
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if mimeType == imageMimePNG {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: imageJPEGQualities[0]})
+	}
+	if err != nil {
+		return Image{}, err
+	}
+	if opts.MaxBytes <= 0 || buf.Len() <= opts.MaxBytes {
+		return newImage(img, mimeType, buf.Bytes(), opts.Detail), nil
+	}
+
+	// JPEG has no alpha, draw transparent images on white.
+	opaque := image.NewRGBA(img.Bounds())
+	draw.Draw(opaque, opaque.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(opaque, opaque.Bounds(), img, img.Bounds().Min, draw.Over)
+	img = opaque
+	for {
+		for _, quality := range imageJPEGQualities {
+			buf.Reset()
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+				return Image{}, err
+			}
+			if buf.Len() <= opts.MaxBytes {
+				return newImage(img, imageMimeJPEG, buf.Bytes(), opts.Detail), nil
+			}
+		}
+		bounds := img.Bounds()
+		width, height := bounds.Dx()*3/4, bounds.Dy()*3/4
+		if width < imageMinSide || height < imageMinSide {
+			return Image{}, ErrImageTooLarge
+		}
+		img = resizeImage(img, width, height)
+	}
+}
+
+func newImage(img image.Image, mimeType string, data []byte, detail ImageDetail) Image {
+	bounds := img.Bounds()
+	return Image{
+		MimeType: mimeType,
+		Data:     append([]byte(nil), data...),
+		Width:    bounds.Dx(),
+		Height:   bounds.Dy(),
+		Detail:   detail,
+	}
+}
+
+// resizeImage downscales img to width x height, averaging the source pixels covered by each pixel.
+func resizeImage(img image.Image, width, height int) image.Image {
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	src := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := src.Min.Y + y*src.Dy()/height
+		y1 := src.Min.Y + (y+1)*src.Dy()/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := src.Min.X + x*src.Dx()/width
+			x1 := src.Min.X + (x+1)*src.Dx()/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
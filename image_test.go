@@ -0,0 +1,114 @@
+package openairt_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// noiseImage is an image which doesn't compress well.
+func noiseImage(width, height int) image.Image {
+	r := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(r.Intn(256)), uint8(r.Intn(256)), uint8(r.Intn(256)), 255})
+		}
+	}
+	return img
+}
+
+func noisePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, noiseImage(width, height)))
+	return buf.Bytes()
+}
+
+func TestLoadImage(t *testing.T) {
+	data := noisePNG(t, 200, 100)
+
+	img, err := openairt.LoadImage(data, openairt.ImageOptions{Detail: openairt.ImageDetailLow})
+	require.NoError(t, err)
+	require.Equal(t, "image/png", img.MimeType)
+	require.Equal(t, data, img.Data)
+	require.Equal(t, 200, img.Width)
+	require.Equal(t, 100, img.Height)
+
+	img, err = openairt.LoadImage(data, openairt.ImageOptions{MaxPixels: 5000})
+	require.NoError(t, err)
+	require.Equal(t, "image/png", img.MimeType)
+	require.Equal(t, 100, img.Width)
+	require.Equal(t, 50, img.Height)
+	decoded, err := png.Decode(bytes.NewReader(img.Data))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 100, 50), decoded.Bounds())
+
+	img, err = openairt.LoadImage(data, openairt.ImageOptions{MaxBytes: 8000})
+	require.NoError(t, err)
+	require.Equal(t, "image/jpeg", img.MimeType)
+	require.LessOrEqual(t, len(img.Data), 8000)
+
+	_, err = openairt.LoadImage(data, openairt.ImageOptions{MaxBytes: 100})
+	require.ErrorIs(t, err, openairt.ErrImageTooLarge)
+
+	_, err = openairt.LoadImage([]byte("GIF89a"), openairt.ImageOptions{})
+	require.ErrorIs(t, err, openairt.ErrUnsupportedImage)
+}
+
+func TestEncodeImage(t *testing.T) {
+	img, err := openairt.EncodeImage(noiseImage(10, 10), openairt.ImageOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "image/png", img.MimeType)
+
+	path := filepath.Join(t.TempDir(), "image.png")
+	require.NoError(t, os.WriteFile(path, img.Data, 0o600))
+	loaded, err := openairt.LoadImageFile(path, openairt.ImageOptions{})
+	require.NoError(t, err)
+	require.Equal(t, img, loaded)
+}
+
+func TestNewImageMessage(t *testing.T) {
+	img, err := openairt.LoadImage(noisePNG(t, 2, 2), openairt.ImageOptions{Detail: openairt.ImageDetailHigh})
+	require.NoError(t, err)
+	uri := img.DataURI()
+	require.True(t, strings.HasPrefix(uri, "data:image/png;base64,"))
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, "data:image/png;base64,"))
+	require.NoError(t, err)
+	require.Equal(t, img.Data, decoded)
+
+	data, err := json.Marshal(openairt.NewImageMessage("What is this?", img))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"conversation.item.create","item":{"type":"message","role":"user","content":[
+		{"type":"input_text","text":"What is this?"},
+		{"type":"input_image","image_url":"`+uri+`","detail":"high"}
+	]}}`, string(data))
+
+	data, err = json.Marshal(img.PromptVariable())
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"input_image","image_url":"`+uri+`","detail":"high"}`, string(data))
+}
+
+func TestFilePromptVariable(t *testing.T) {
+	data, err := json.Marshal(openairt.FilePromptVariable("notes.txt", []byte("hi")))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"input_file","filename":"notes.txt","file_data":"data:text/plain;base64,aGk="}`, string(data))
+
+	path := filepath.Join(t.TempDir(), "doc")
+	require.NoError(t, os.WriteFile(path, []byte("%PDF-1.7"), 0o600))
+	variable, err := openairt.LoadFilePromptVariable(path)
+	require.NoError(t, err)
+	require.Equal(t, "doc", variable.InputFile.Filename)
+	require.True(t, strings.HasPrefix(variable.InputFile.FileData, "data:application/pdf;base64,"))
+}
@@ -1,6 +1,10 @@
 package openairt
 
-import "log"
+import (
+	"fmt"
+	"log"
+	"strings"
+)
 
 type Logger interface {
 	Debugf(format string, v ...any)
@@ -42,3 +46,121 @@ func (l StdLogger) Infof(format string, v ...any) {
 func (l StdLogger) Debugf(format string, v ...any) {
 	log.Printf("[DEBUG] "+format, v...)
 }
+
+// LogLevel is the level of a structured log message.
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+// String returns the name of the level.
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "DEBUG"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
+	case LogLevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
+// StructuredLogger is a Logger that logs messages with key-value attributes.
+// If the logger passed to WithLogger implements it, Conn logs every sent and received event through it.
+type StructuredLogger interface {
+	Logger
+
+	// Enabled reports whether messages at the level are logged.
+	Enabled(level LogLevel) bool
+
+	// Log logs msg with the attributes in kv, which are alternating keys and values like slog.
+	Log(level LogLevel, msg string, kv ...any)
+
+	// With returns a logger that attaches the attributes in kv to every message.
+	With(kv ...any) StructuredLogger
+}
+
+// NewStructuredLogger returns logger if it's a StructuredLogger, otherwise it wraps logger
+// into a StructuredLogger that formats the attributes as key=value pairs after the message.
+// Printf-style loggers can't filter by level, so the wrapper drops debug messages to
+// avoid flooding them with an entry per event.
+func NewStructuredLogger(logger Logger) StructuredLogger {
+	if s, ok := logger.(StructuredLogger); ok {
+		return s
+	}
+	return &printfLogger{logger: logger}
+}
+
+// printfLogger adapts a printf-style Logger to StructuredLogger.
+type printfLogger struct {
+	logger Logger
+	attrs  string
+}
+
+func (l *printfLogger) Enabled(level LogLevel) bool {
+	switch l.logger.(type) {
+	case NopLogger, *NopLogger:
+		return false
+	default:
+		return level > LogLevelDebug
+	}
+}
+
+func (l *printfLogger) Log(level LogLevel, msg string, kv ...any) {
+	if !l.Enabled(level) {
+		return
+	}
+	line := msg + formatAttrs(kv) + l.attrs
+	switch level {
+	case LogLevelDebug:
+		l.logger.Debugf("%s", line)
+	case LogLevelInfo:
+		l.logger.Infof("%s", line)
+	case LogLevelWarn:
+		l.logger.Warnf("%s", line)
+	default:
+		l.logger.Errorf("%s", line)
+	}
+}
+
+func (l *printfLogger) With(kv ...any) StructuredLogger {
+	return &printfLogger{logger: l.logger, attrs: l.attrs + formatAttrs(kv)}
+}
+
+func (l *printfLogger) Debugf(format string, v ...any) {
+	l.logger.Debugf("%s%s", fmt.Sprintf(format, v...), l.attrs)
+}
+
+func (l *printfLogger) Infof(format string, v ...any) {
+	l.logger.Infof("%s%s", fmt.Sprintf(format, v...), l.attrs)
+}
+
+func (l *printfLogger) Warnf(format string, v ...any) {
+	l.logger.Warnf("%s%s", fmt.Sprintf(format, v...), l.attrs)
+}
+
+func (l *printfLogger) Errorf(format string, v ...any) {
+	l.logger.Errorf("%s%s", fmt.Sprintf(format, v...), l.attrs)
+}
+
+// formatAttrs formats the key-value pairs as " key=value key=value".
+func formatAttrs(kv []any) string {
+	var b strings.Builder
+	for i := 0; i < len(kv); i += 2 {
+		b.WriteByte(' ')
+		if i+1 == len(kv) {
+			fmt.Fprintf(&b, "!BADKEY=%v", kv[i])
+			break
+		}
+		fmt.Fprintf(&b, "%v=%v", kv[i], kv[i+1])
+	}
+	return b.String()
+}
@@ -0,0 +1,153 @@
+package openairt_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+type logEntry struct {
+	level openairt.LogLevel
+	msg   string
+	attrs map[string]any
+}
+
+type recordingLogger struct {
+	openairt.NopLogger
+	mu      *sync.Mutex
+	entries *[]logEntry
+	kv      []any
+}
+
+func newRecordingLogger() *recordingLogger {
+	return &recordingLogger{mu: &sync.Mutex{}, entries: &[]logEntry{}}
+}
+
+func (l *recordingLogger) Enabled(_ openairt.LogLevel) bool { return true }
+
+func (l *recordingLogger) Log(level openairt.LogLevel, msg string, kv ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	attrs := make(map[string]any)
+	all := append(append([]any{}, l.kv...), kv...)
+	for i := 0; i+1 < len(all); i += 2 {
+		attrs[fmt.Sprint(all[i])] = all[i+1]
+	}
+	*l.entries = append(*l.entries, logEntry{level: level, msg: msg, attrs: attrs})
+}
+
+func (l *recordingLogger) With(kv ...any) openairt.StructuredLogger {
+	return &recordingLogger{mu: l.mu, entries: l.entries, kv: append(append([]any{}, l.kv...), kv...)}
+}
+
+func (l *recordingLogger) messages(msg string) []logEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var entries []logEntry
+	for _, e := range *l.entries {
+		if e.msg == msg {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func TestConnStructuredLogging(t *testing.T) {
+	logger := newRecordingLogger()
+	conn := newScriptedConn(t, []string{
+		`{"type":"session.created","event_id":"event_1","session":{"type":"realtime","id":"sess_1"}}`,
+		`{"type":"response.output_audio.delta","event_id":"event_2","response_id":"resp_1","item_id":"item_1","delta":"AAECAw=="}`,
+	}, openairt.WithLogger(logger), openairt.WithLogAttrs("user", "u1"), openairt.WithLogPayload())
+
+	dials := logger.messages("dialing")
+	require.Len(t, dials, 1)
+	require.Equal(t, openairt.GPTRealtime, dials[0].attrs["model"])
+	require.Equal(t, "u1", dials[0].attrs["user"])
+	headers, ok := dials[0].attrs["headers"].(http.Header)
+	require.True(t, ok)
+	require.Equal(t, "[redacted]", headers.Get("Authorization"))
+
+	ctx := context.Background()
+	err := conn.SendMessage(ctx, openairt.InputAudioBufferAppendEvent{
+		EventBase: openairt.EventBase{EventID: "client_1"},
+		Audio:     "AAECAwQ=",
+	})
+	require.NoError(t, err)
+	sent := logger.messages("sent event")
+	require.Len(t, sent, 1)
+	require.Equal(t, "input_audio_buffer.append", sent[0].attrs["type"])
+	require.Equal(t, "client_1", sent[0].attrs["event_id"])
+	require.Equal(t, "u1", sent[0].attrs["user"])
+	require.Contains(t, sent[0].attrs["payload"], `"audio":"[redacted 5 bytes]"`)
+
+	for i := 0; i < 2; i++ {
+		_, err = conn.ReadMessage(ctx)
+		require.NoError(t, err)
+	}
+	received := logger.messages("received event")
+	require.Len(t, received, 2)
+	require.Equal(t, "session.created", received[0].attrs["type"])
+	require.Equal(t, "sess_1", received[0].attrs["session_id"])
+	require.Equal(t, "resp_1", received[1].attrs["response_id"])
+	require.Equal(t, "item_1", received[1].attrs["item_id"])
+	require.Contains(t, received[1].attrs["payload"], `"delta":"[redacted 4 bytes]"`)
+	require.NotContains(t, received[1].attrs["payload"], "AAECAw==")
+}
+
+func TestRedactPayload(t *testing.T) {
+	data := `{"type":"session.update","session":{"type":"realtime","tools":[` +
+		`{"type":"mcp","server_label":"s","authorization":"secret","headers":{"X-Key":"secret"}}]}}`
+	redacted := string(openairt.RedactPayload([]byte(data)))
+	require.NotContains(t, redacted, "secret")
+	require.Contains(t, redacted, `"server_label":"s"`)
+
+	data = `{"type":"conversation.item.create","item":{"type":"message","role":"user","content":[` +
+		`{"type":"input_image","image_url":"data:image/png;base64,AAAA"},{"type":"input_text","text":"hi"}]}}`
+	redacted = string(openairt.RedactPayload([]byte(data)))
+	require.NotContains(t, redacted, "data:image/png")
+	require.Contains(t, redacted, `"text":"hi"`)
+
+	data = `{"type":"response.output_text.delta","delta":"hello"}`
+	require.JSONEq(t, data, string(openairt.RedactPayload([]byte(data))))
+
+	require.Equal(t, `"[redacted 3 bytes]"`, string(openairt.RedactPayload([]byte("abc"))))
+}
+
+type printfRecorder struct {
+	openairt.NopLogger
+	lines []string
+}
+
+func (l *printfRecorder) Warnf(format string, v ...any) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestNewStructuredLogger(t *testing.T) {
+	recorder := &printfRecorder{}
+	logger := openairt.NewStructuredLogger(recorder).With("model", "gpt-realtime")
+	require.False(t, logger.Enabled(openairt.LogLevelDebug))
+	require.True(t, logger.Enabled(openairt.LogLevelWarn))
+
+	logger.Log(openairt.LogLevelWarn, "100% done", "key", "value", "odd")
+	logger.Warnf("read error: %s", "eof")
+	// The attributes are not formats.
+	logger.With("query", "q=a%20b").Log(openairt.LogLevelWarn, "get", "err", "100%!")
+	require.Equal(t, []string{
+		"100% done key=value !BADKEY=odd model=gpt-realtime",
+		"read error: eof model=gpt-realtime",
+		"get err=100%! model=gpt-realtime query=q=a%20b",
+	}, recorder.lines)
+
+	require.False(t, openairt.NewStructuredLogger(openairt.NopLogger{}).Enabled(openairt.LogLevelError))
+	require.False(t, openairt.NewStructuredLogger(&openairt.NopLogger{}).Enabled(openairt.LogLevelError))
+
+	structured := newRecordingLogger()
+	require.Same(t, structured, openairt.NewStructuredLogger(structured))
+	require.True(t, strings.HasPrefix(openairt.LogLevelWarn.String(), "WARN"))
+}
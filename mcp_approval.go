@@ -0,0 +1,261 @@
+package openairt
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+const defaultMCPApprovalTimeout = 30 * time.Second
+
+// MCPApprovalSource is what made an MCP approval decision.
+type MCPApprovalSource string
+
+const (
+	// MCPApprovalSourcePolicy is a decision of a policy.
+	MCPApprovalSourcePolicy MCPApprovalSource = "policy"
+	// MCPApprovalSourceDefault is the denial of a request no policy decided.
+	MCPApprovalSourceDefault MCPApprovalSource = "default"
+	// MCPApprovalSourceTimeout is the denial of a request the policies didn't decide in time.
+	MCPApprovalSourceTimeout MCPApprovalSource = "timeout"
+	// MCPApprovalSourceCanceled is the denial of a request whose context was canceled before a decision,
+	// e.g. on shutdown.
+	MCPApprovalSourceCanceled MCPApprovalSource = "canceled"
+)
+
+// MCPApprovalDecision is the answer to an MCP approval request.
+type MCPApprovalDecision struct {
+	Approve bool
+	// Reason of the decision, sent to the model.
+	Reason string
+}
+
+// MCPApprovalPolicy decides an MCP approval request, or returns false to leave it to the next policy.
+// It may block, e.g. to ask a human, until ctx is done.
+type MCPApprovalPolicy func(ctx context.Context, request MessageItemMCPApprovalRequest) (MCPApprovalDecision, bool)
+
+// MCPToolMatch matches MCP tools by server label and tool name, empty fields match any.
+type MCPToolMatch struct {
+	ServerLabel string
+	Name        string
+}
+
+func (m MCPToolMatch) match(request MessageItemMCPApprovalRequest) bool {
+	return (m.ServerLabel == "" || m.ServerLabel == request.ServerLabel) &&
+		(m.Name == "" || m.Name == request.Name)
+}
+
+// AllowMCPTools approves the requests of the matching tools.
+func AllowMCPTools(matches ...MCPToolMatch) MCPApprovalPolicy {
+	return matchMCPTools(MCPApprovalDecision{Approve: true, Reason: "allowed"}, matches)
+}
+
+// DenyMCPTools denies the requests of the matching tools.
+func DenyMCPTools(matches ...MCPToolMatch) MCPApprovalPolicy {
+	return matchMCPTools(MCPApprovalDecision{Reason: "denied"}, matches)
+}
+
+func matchMCPTools(decision MCPApprovalDecision, matches []MCPToolMatch) MCPApprovalPolicy {
+	return func(_ context.Context, request MessageItemMCPApprovalRequest) (MCPApprovalDecision, bool) {
+		for _, m := range matches {
+			if m.match(request) {
+				return decision, true
+			}
+		}
+		return MCPApprovalDecision{}, false
+	}
+}
+
+// InspectMCPArguments decides the requests of the matching tool by their decoded JSON arguments.
+// Requests whose arguments aren't a JSON object are denied.
+func InspectMCPArguments(
+	match MCPToolMatch,
+	inspect func(args map[string]any) (MCPApprovalDecision, bool),
+) MCPApprovalPolicy {
+	return func(_ context.Context, request MessageItemMCPApprovalRequest) (MCPApprovalDecision, bool) {
+		if !match.match(request) {
+			return MCPApprovalDecision{}, false
+		}
+		var args map[string]any
+		if err := json.Unmarshal([]byte(request.Arguments), &args); err != nil {
+			return MCPApprovalDecision{Reason: "invalid arguments"}, true
+		}
+		return inspect(args)
+	}
+}
+
+// MCPApprovalRecord is an entry of the audit log of an MCPApprover.
+type MCPApprovalRecord struct {
+	RequestID   string            `json:"request_id"`
+	ResponseID  string            `json:"response_id,omitempty"`
+	ServerLabel string            `json:"server_label"`
+	Name        string            `json:"name"`
+	Arguments   string            `json:"arguments"`
+	Approve     bool              `json:"approve"`
+	Reason      string            `json:"reason,omitempty"`
+	Source      MCPApprovalSource `json:"source"`
+	RequestedAt time.Time         `json:"requested_at"`
+	DecidedAt   time.Time         `json:"decided_at"`
+	// Err is the error of sending the approval response, if any.
+	Err error `json:"-"`
+}
+
+// MCPApproverOptions configures an MCPApprover.
+type MCPApproverOptions struct {
+	// Policies are asked in order until one decides, requests no policy decides are denied.
+	Policies []MCPApprovalPolicy
+
+	// Timeout of the policies, requests undecided in time are denied. Default is 30 seconds.
+	Timeout time.Duration
+
+	// NoResponse doesn't create a response to resume after the approval responses are sent.
+	NoResponse bool
+
+	// OnDecision is called with the audit record of every decision, concurrently from the goroutines of the decisions.
+	OnDecision func(MCPApprovalRecord)
+
+	// OnError is called with the errors of creating the responses to resume, including
+	// the errors the server answers to them.
+	OnError func(err error)
+}
+
+// MCPApprover answers the mcp_approval_request items output by responses with the decisions
+// of its policies, and creates a response to resume once the response which requested approvals
+// is done and all its requests are answered. The response is deferred while another response is
+// in progress, and merged with the responses to resume of the other components of the connection,
+// e.g. an MCPBridge.
+//
+// Its HandleEvent must be registered on the ConnHandler of the connection. The policies run
+// in their own goroutines so that they don't block the reading of events.
+type MCPApprover struct {
+	conn *Conn
+	opts MCPApproverOptions
+
+	resumer *mcpResumer
+
+	mu    sync.Mutex
+	audit []MCPApprovalRecord
+	wg    sync.WaitGroup
+}
+
+// NewMCPApprover creates an MCPApprover on conn.
+func NewMCPApprover(conn *Conn, opts MCPApproverOptions) *MCPApprover {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultMCPApprovalTimeout
+	}
+	a := &MCPApprover{conn: conn, opts: opts}
+	a.resumer = newMCPResumer(conn, opts.NoResponse, a.report)
+	return a
+}
+
+// Audit returns the audit log of the decisions.
+func (a *MCPApprover) Audit() []MCPApprovalRecord {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]MCPApprovalRecord(nil), a.audit...)
+}
+
+// Wait waits for the pending decisions.
+func (a *MCPApprover) Wait() {
+	a.wg.Wait()
+}
+
+// HandleEvent is the ServerEventHandler of the MCPApprover.
+func (a *MCPApprover) HandleEvent(ctx context.Context, event ServerEvent) {
+	switch e := event.(type) {
+	case ResponseOutputItemDoneEvent:
+		if e.Item.MCPApprovalRequest != nil {
+			a.request(ctx, e.ResponseID, *e.Item.MCPApprovalRequest)
+		}
+	case ResponseDoneEvent:
+		a.report(a.resumer.done(ctx, e.Response.ID))
+	}
+}
+
+func (a *MCPApprover) request(ctx context.Context, responseID string, request MessageItemMCPApprovalRequest) {
+	if !a.resumer.add(responseID, request.ID) {
+		return
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.decide(ctx, responseID, request)
+	}()
+}
+
+func (a *MCPApprover) decide(ctx context.Context, responseID string, request MessageItemMCPApprovalRequest) {
+	record := MCPApprovalRecord{
+		RequestID:   request.ID,
+		ResponseID:  responseID,
+		ServerLabel: request.ServerLabel,
+		Name:        request.Name,
+		Arguments:   request.Arguments,
+		RequestedAt: time.Now(),
+	}
+	decision, source := a.evaluate(ctx, request)
+	record.Approve, record.Reason, record.Source = decision.Approve, decision.Reason, source
+	record.DecidedAt = time.Now()
+
+	record.Err = a.conn.SendMessage(ctx, ConversationItemCreateEvent{Item: MessageItemUnion{
+		MCPApprovalResponse: &MessageItemMCPApprovalResponse{
+			ApprovalRequestID: request.ID,
+			Approve:           decision.Approve,
+			Reason:            decision.Reason,
+		},
+	}})
+
+	a.mu.Lock()
+	a.audit = append(a.audit, record)
+	a.mu.Unlock()
+	if a.opts.OnDecision != nil {
+		a.opts.OnDecision(record)
+	}
+
+	a.report(a.resumer.finish(ctx, responseID))
+}
+
+// report reports the error of creating the response to resume, if any.
+func (a *MCPApprover) report(err error) {
+	if err != nil && a.opts.OnError != nil {
+		a.opts.OnError(err)
+	}
+}
+
+// evaluate asks the policies in order within the timeout.
+func (a *MCPApprover) evaluate(
+	parent context.Context,
+	request MessageItemMCPApprovalRequest,
+) (MCPApprovalDecision, MCPApprovalSource) {
+	ctx, cancel := context.WithTimeout(parent, a.opts.Timeout)
+	defer cancel()
+
+	type result struct {
+		decision MCPApprovalDecision
+		ok       bool
+	}
+	done := make(chan result, 1)
+	go func() {
+		for _, policy := range a.opts.Policies {
+			if decision, ok := policy(ctx, request); ok {
+				done <- result{decision, true}
+				return
+			}
+		}
+		done <- result{}
+	}()
+
+	select {
+	case <-ctx.Done():
+		if parent.Err() != nil {
+			return MCPApprovalDecision{Reason: "approval canceled"}, MCPApprovalSourceCanceled
+		}
+		return MCPApprovalDecision{Reason: "approval timed out"}, MCPApprovalSourceTimeout
+	case r := <-done:
+		if !r.ok {
+			return MCPApprovalDecision{Reason: "not allowed"}, MCPApprovalSourceDefault
+		}
+		return r.decision, MCPApprovalSourcePolicy
+	}
+}
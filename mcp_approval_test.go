@@ -0,0 +1,149 @@
+package openairt_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/coder/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func approvalRequestDone(responseID, id, label, name, args string) string {
+	return fmt.Sprintf(`{"type":"response.output_item.done","response_id":%q,"item":{"type":"mcp_approval_request",`+
+		`"id":%q,"server_label":%q,"name":%q,"arguments":%q}}`, responseID, id, label, name, args)
+}
+
+func startMCPApprover(t *testing.T, opts openairt.MCPApproverOptions) (*openairt.MCPApprover, *fakeServer) {
+	t.Helper()
+	conn, server := newFakeServer(t, nil, nil)
+	approver := openairt.NewMCPApprover(conn, opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	openairt.NewConnHandler(ctx, conn, approver.HandleEvent).Start()
+	return approver, server
+}
+
+func TestMCPApprover(t *testing.T) {
+	recorded := make(chan openairt.MCPApprovalRecord, 4)
+	approver, server := startMCPApprover(t, openairt.MCPApproverOptions{
+		Policies: []openairt.MCPApprovalPolicy{
+			openairt.DenyMCPTools(openairt.MCPToolMatch{ServerLabel: "prod"}),
+			openairt.AllowMCPTools(openairt.MCPToolMatch{ServerLabel: "docs", Name: "search"}),
+			openairt.InspectMCPArguments(openairt.MCPToolMatch{Name: "fetch"},
+				func(args map[string]any) (openairt.MCPApprovalDecision, bool) {
+					url, _ := args["url"].(string)
+					if strings.Contains(url, "internal") {
+						return openairt.MCPApprovalDecision{Reason: "internal url"}, true
+					}
+					return openairt.MCPApprovalDecision{Approve: true}, true
+				}),
+		},
+		OnDecision: func(r openairt.MCPApprovalRecord) { recorded <- r },
+	})
+
+	server.push(
+		approvalRequestDone("resp_1", "req_1", "docs", "search", `{"q":"go"}`),
+		approvalRequestDone("resp_1", "req_2", "prod", "search", `{}`),
+		approvalRequestDone("resp_1", "req_3", "docs", "fetch", `{"url":"http://internal/x"}`),
+		approvalRequestDone("resp_1", "req_4", "docs", "other", `{}`),
+	)
+	for i := 0; i < 4; i++ {
+		require.NotEmpty(t, (<-recorded).Reason)
+	}
+	// The response is resumed once it's done.
+	require.Empty(t, sentOfType(server, "response.create"))
+	server.push(`{"type":"response.done","response":{"id":"resp_1","status":"completed"}}`)
+	require.Eventually(t, func() bool { return len(sentOfType(server, "response.create")) == 1 }, time.Second, time.Millisecond)
+	approver.Wait()
+
+	decisions := make(map[any]map[string]any)
+	for _, e := range sentOfType(server, "conversation.item.create") {
+		item := e["item"].(map[string]any)
+		require.Equal(t, "mcp_approval_response", item["type"])
+		decisions[item["approval_request_id"]] = item
+	}
+	require.Equal(t, true, decisions["req_1"]["approve"])
+	require.Equal(t, false, decisions["req_2"]["approve"])
+	require.Equal(t, "internal url", decisions["req_3"]["reason"])
+	require.Equal(t, false, decisions["req_4"]["approve"])
+
+	audit := approver.Audit()
+	require.Len(t, audit, 4)
+	sources := make(map[string]openairt.MCPApprovalSource)
+	for _, r := range audit {
+		sources[r.RequestID] = r.Source
+		require.Equal(t, "resp_1", r.ResponseID)
+		require.NoError(t, r.Err)
+	}
+	require.Equal(t, openairt.MCPApprovalSourcePolicy, sources["req_1"])
+	require.Equal(t, openairt.MCPApprovalSourceDefault, sources["req_4"])
+}
+
+func TestMCPApproverTimeout(t *testing.T) {
+	approver, server := startMCPApprover(t, openairt.MCPApproverOptions{
+		Policies: []openairt.MCPApprovalPolicy{
+			// Asks a human who never answers.
+			func(ctx context.Context, _ openairt.MessageItemMCPApprovalRequest) (openairt.MCPApprovalDecision, bool) {
+				<-ctx.Done()
+				return openairt.MCPApprovalDecision{}, false
+			},
+		},
+		Timeout:    10 * time.Millisecond,
+		NoResponse: true,
+	})
+	server.push(
+		`{"type":"response.done","response":{"id":"resp_0","status":"completed"}}`,
+		approvalRequestDone("resp_1", "req_1", "docs", "search", `{}`),
+		`{"type":"response.done","response":{"id":"resp_1","status":"completed"}}`,
+	)
+	require.Eventually(t, func() bool { return len(approver.Audit()) == 1 }, time.Second, time.Millisecond)
+	approver.Wait()
+
+	record := approver.Audit()[0]
+	require.False(t, record.Approve)
+	require.Equal(t, openairt.MCPApprovalSourceTimeout, record.Source)
+	require.Len(t, sentOfType(server, "conversation.item.create"), 1)
+	require.Empty(t, sentOfType(server, "response.create"))
+}
+
+func TestMCPApproverCanceled(t *testing.T) {
+	// The connection is closed, the approval response and the resumed response fail to be sent.
+	conn := dialCoderServer(t, func(c *websocket.Conn) {
+		_, _, _ = c.Read(context.Background())
+	})
+	require.NoError(t, conn.Close())
+	errs := make(chan error, 1)
+	approver := openairt.NewMCPApprover(conn, openairt.MCPApproverOptions{
+		Policies: []openairt.MCPApprovalPolicy{
+			func(ctx context.Context, _ openairt.MessageItemMCPApprovalRequest) (openairt.MCPApprovalDecision, bool) {
+				<-ctx.Done()
+				return openairt.MCPApprovalDecision{}, false
+			},
+		},
+		OnError: func(err error) { errs <- err },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, data := range []string{
+		approvalRequestDone("resp_1", "req_1", "docs", "search", `{}`),
+		`{"type":"response.done","response":{"id":"resp_1","status":"completed"}}`,
+	} {
+		event, err := openairt.UnmarshalServerEvent([]byte(data))
+		require.NoError(t, err)
+		approver.HandleEvent(ctx, event)
+	}
+	// A shutdown isn't reported as a timeout of the policies.
+	cancel()
+	approver.Wait()
+
+	record := approver.Audit()[0]
+	require.False(t, record.Approve)
+	require.Equal(t, openairt.MCPApprovalSourceCanceled, record.Source)
+	require.Equal(t, "approval canceled", record.Reason)
+	require.Error(t, record.Err)
+	require.Error(t, <-errs)
+}
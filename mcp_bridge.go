@@ -0,0 +1,286 @@
+package openairt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// maxFunctionNameLength is the maximum length of function tool names.
+const maxFunctionNameLength = 64
+
+var invalidFunctionNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// MCPBridgeCall is the record of a function call executed by an MCPBridge.
+type MCPBridgeCall struct {
+	CallID      string
+	ItemID      string
+	ResponseID  string
+	ServerLabel string
+	// Name of the MCP tool, the function name may be prefixed with the server label.
+	Name      string
+	Arguments string
+	Output    string
+	StartedAt time.Time
+	EndedAt   time.Time
+	// Err is the error of the call or of sending its output, if any.
+	Err error
+}
+
+// MCPBridgeOptions configures an MCPBridge.
+type MCPBridgeOptions struct {
+	// Timeout of the tool calls, zero means no timeout.
+	Timeout time.Duration
+
+	// NoResponse doesn't create a response after the outputs of the calls of a response are sent.
+	NoResponse bool
+
+	// OnCall is called with the record of every call, concurrently from the goroutines of the calls.
+	OnCall func(MCPBridgeCall)
+
+	// OnError is called with the errors of creating the responses to resume, including
+	// the errors the server answers to them.
+	OnError func(err error)
+}
+
+// MCPBridge exposes the tools of local MCP servers as function tools. It executes the function_call
+// items of these tools by calling the MCP servers, and sends their results as function_call_output items.
+//
+// The function names are the tool names prefixed with the server label, e.g. "docs_search",
+// or the tool names for servers without a label.
+//
+// Once a response is done and all its calls are answered, it creates a response to resume,
+// deferred while another response is in progress, and merged with the responses to resume
+// of the other components of the connection, e.g. an MCPApprover.
+//
+// Its HandleEvent must be registered on the ConnHandler of the connection. The calls run
+// in their own goroutines so that they don't block the reading of events.
+type MCPBridge struct {
+	conn    *Conn
+	servers []*MCPServer
+	opts    MCPBridgeOptions
+	resumer *mcpResumer
+
+	mu     sync.Mutex
+	routes map[string]mcpRoute
+	wg     sync.WaitGroup
+}
+
+// mcpRoute is the MCP tool of a function name.
+type mcpRoute struct {
+	server *MCPServer
+	tool   string
+}
+
+// NewMCPBridge creates an MCPBridge of servers on conn.
+func NewMCPBridge(conn *Conn, servers []*MCPServer, opts MCPBridgeOptions) *MCPBridge {
+	b := &MCPBridge{
+		conn:    conn,
+		servers: servers,
+		opts:    opts,
+		routes:  make(map[string]mcpRoute),
+	}
+	b.resumer = newMCPResumer(conn, opts.NoResponse, b.report)
+	return b
+}
+
+// Tools lists the tools of the servers as function tools, whose parameters are their input schemas.
+func (b *MCPBridge) Tools(ctx context.Context) ([]ToolUnion, error) {
+	routes := make(map[string]mcpRoute)
+	var tools []ToolUnion
+	for _, server := range b.servers {
+		list, err := server.ListTools(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list tools of mcp server %s: %w", server.Label(), err)
+		}
+		for _, tool := range list {
+			name := mcpFunctionName(server.Label(), tool.Name)
+			if _, ok := routes[name]; ok {
+				return nil, fmt.Errorf("duplicate function name %s of mcp server %s", name, server.Label())
+			}
+			parameters, err := mcpFunctionParameters(tool.InputSchema)
+			if err != nil {
+				return nil, fmt.Errorf("input schema of mcp tool %s: %w", tool.Name, err)
+			}
+			routes[name] = mcpRoute{server: server, tool: tool.Name}
+			tools = append(tools, ToolUnion{Function: &ToolFunction{
+				Name:        name,
+				Description: tool.Description,
+				Parameters:  parameters,
+			}})
+		}
+	}
+
+	b.mu.Lock()
+	b.routes = routes
+	b.mu.Unlock()
+	return tools, nil
+}
+
+// ApplySession lists the tools of the servers and applies them to the realtime session,
+// replacing the bridged tools of a previous listing and keeping the other tools.
+func (b *MCPBridge) ApplySession(ctx context.Context) (SessionUnion, error) {
+	b.mu.Lock()
+	previous := b.routes
+	b.mu.Unlock()
+
+	tools, err := b.Tools(ctx)
+	if err != nil {
+		return SessionUnion{}, err
+	}
+	session, ok := b.conn.Session()
+	if !ok {
+		return SessionUnion{}, ErrSessionUnknown
+	}
+	if session.Realtime == nil {
+		return SessionUnion{}, errors.New("mcp bridge needs a realtime session")
+	}
+	for _, tool := range session.Realtime.Tools {
+		if tool.Function != nil {
+			if _, ok := previous[tool.Function.Name]; ok {
+				continue
+			}
+			if _, ok := b.route(tool.Function.Name); ok {
+				continue
+			}
+		}
+		tools = append(tools, tool)
+	}
+	return b.conn.ApplySession(ctx, SessionUnion{Realtime: &RealtimeSession{Tools: tools}})
+}
+
+// Wait waits for the pending calls.
+func (b *MCPBridge) Wait() {
+	b.wg.Wait()
+}
+
+// HandleEvent is the ServerEventHandler of the MCPBridge.
+func (b *MCPBridge) HandleEvent(ctx context.Context, event ServerEvent) {
+	switch e := event.(type) {
+	case ResponseOutputItemDoneEvent:
+		if e.Item.FunctionCall != nil {
+			b.call(ctx, e.ResponseID, *e.Item.FunctionCall)
+		}
+	case ResponseDoneEvent:
+		b.report(b.resumer.done(ctx, e.Response.ID))
+	}
+}
+
+func (b *MCPBridge) route(name string) (mcpRoute, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	route, ok := b.routes[name]
+	return route, ok
+}
+
+func (b *MCPBridge) call(ctx context.Context, responseID string, call MessageItemFunctionCall) {
+	route, ok := b.route(call.Name)
+	if !ok || !b.resumer.add(responseID, call.CallID) {
+		return
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.execute(ctx, responseID, route, call)
+	}()
+}
+
+func (b *MCPBridge) execute(ctx context.Context, responseID string, route mcpRoute, call MessageItemFunctionCall) {
+	record := MCPBridgeCall{
+		CallID:      call.CallID,
+		ItemID:      call.ID,
+		ResponseID:  responseID,
+		ServerLabel: route.server.Label(),
+		Name:        route.tool,
+		Arguments:   call.Arguments,
+		StartedAt:   time.Now(),
+	}
+
+	callCtx := ctx
+	if b.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, b.opts.Timeout)
+		defer cancel()
+	}
+	result, err := route.server.CallTool(callCtx, route.tool, json.RawMessage(call.Arguments))
+	switch {
+	case err != nil:
+		record.Err = err
+		record.Output = functionErrorOutput(err.Error())
+	case result.IsError:
+		record.Output = functionErrorOutput(result.Text())
+	default:
+		record.Output = result.Text()
+	}
+	record.EndedAt = time.Now()
+
+	err = b.conn.SendMessage(ctx, ConversationItemCreateEvent{Item: MessageItemUnion{
+		FunctionCallOutput: &MessageItemFunctionCallOutput{
+			CallID: call.CallID,
+			Output: record.Output,
+		},
+	}})
+	if record.Err == nil {
+		record.Err = err
+	}
+
+	if b.opts.OnCall != nil {
+		b.opts.OnCall(record)
+	}
+
+	b.report(b.resumer.finish(ctx, responseID))
+}
+
+// report reports the error of creating the response to resume, if any.
+func (b *MCPBridge) report(err error) {
+	if err != nil && b.opts.OnError != nil {
+		b.opts.OnError(err)
+	}
+}
+
+// mcpFunctionName returns the function name of an MCP tool.
+func mcpFunctionName(label, tool string) string {
+	if label == "" {
+		return sanitizeFunctionName(tool)
+	}
+	return sanitizeFunctionName(label + "_" + tool)
+}
+
+// sanitizeFunctionName restricts name to the characters and length allowed in function names.
+func sanitizeFunctionName(name string) string {
+	name = invalidFunctionNameChars.ReplaceAllString(name, "_")
+	if len(name) > maxFunctionNameLength {
+		name = name[:maxFunctionNameLength]
+	}
+	return name
+}
+
+// mcpFunctionParameters translates the JSON schema of an MCP tool input into function parameters,
+// an object schema without the $schema keyword.
+func mcpFunctionParameters(schema string) (map[string]any, error) {
+	parameters := map[string]any{}
+	if schema != "" && schema != "null" {
+		if err := json.Unmarshal([]byte(schema), &parameters); err != nil {
+			return nil, err
+		}
+	}
+	delete(parameters, "$schema")
+	if _, ok := parameters["type"]; !ok {
+		parameters["type"] = "object"
+	}
+	if _, ok := parameters["properties"]; !ok {
+		parameters["properties"] = map[string]any{}
+	}
+	return parameters, nil
+}
+
+// functionErrorOutput is the output of a function call failing with message.
+func functionErrorOutput(message string) string {
+	data, _ := json.Marshal(map[string]string{"error": message})
+	return string(data)
+}
@@ -0,0 +1,208 @@
+package openairt_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// serveTestMCP is a stdio MCP server stand-in with a search tool listed on the first page,
+// a fail tool listed on the second page, and no other tool. It answers the calls of the unlisted
+// repeat tool three times.
+func serveTestMCP(r io.Reader, w io.Writer) {
+	scanner := bufio.NewScanner(r)
+	encoder := json.NewEncoder(w)
+	for scanner.Scan() {
+		var req struct {
+			ID     *int64 `json:"id"`
+			Method string `json:"method"`
+			Params struct {
+				Cursor    string         `json:"cursor"`
+				Name      string         `json:"name"`
+				Arguments map[string]any `json:"arguments"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil || req.ID == nil {
+			continue
+		}
+		var result any
+		var rpcErr any
+		switch req.Method {
+		case "initialize":
+			result = map[string]any{
+				"protocolVersion": "2025-06-18",
+				"capabilities":    map[string]any{"tools": map[string]any{}},
+				"serverInfo":      map[string]any{"name": "stand-in", "version": "1.0.0"},
+			}
+		case "tools/list":
+			if req.Params.Cursor == "" {
+				result = map[string]any{"nextCursor": "2", "tools": []any{map[string]any{
+					"name": "search", "description": "Search the docs",
+					"inputSchema": map[string]any{
+						"$schema":    "http://json-schema.org/draft-07/schema#",
+						"type":       "object",
+						"properties": map[string]any{"q": map[string]any{"type": "string"}},
+					},
+				}}}
+			} else {
+				result = map[string]any{"tools": []any{map[string]any{"name": "fail.now", "inputSchema": map[string]any{}}}}
+			}
+		case "tools/call":
+			switch req.Params.Name {
+			case "search":
+				result = map[string]any{"content": []any{
+					map[string]any{"type": "text", "text": "results for " + req.Params.Arguments["q"].(string)},
+				}}
+			case "repeat":
+				result = map[string]any{"content": []any{}}
+			case "fail.now":
+				result = map[string]any{"isError": true, "content": []any{map[string]any{"type": "text", "text": "boom"}}}
+			default:
+				rpcErr = map[string]any{"code": -32602, "message": "unknown tool"}
+			}
+		default:
+			rpcErr = map[string]any{"code": -32601, "message": "method not found"}
+		}
+		resp := map[string]any{"jsonrpc": "2.0", "id": *req.ID}
+		if rpcErr != nil {
+			resp["error"] = rpcErr
+		} else {
+			resp["result"] = result
+		}
+		_ = encoder.Encode(resp)
+		if req.Params.Name == "repeat" {
+			_ = encoder.Encode(resp)
+			_ = encoder.Encode(resp)
+		}
+	}
+}
+
+func connectTestMCP(t *testing.T, label string) *openairt.MCPServer {
+	t.Helper()
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	go func() {
+		serveTestMCP(serverR, serverW)
+		serverW.Close()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server, err := openairt.ConnectMCPServer(ctx, label, clientR, clientW)
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+// TestMCPHelperProcess isn't a real test, it's the stand-in MCP server process of TestStartMCPServer.
+func TestMCPHelperProcess(t *testing.T) {
+	if os.Getenv("OPENAIRT_MCP_HELPER_PROCESS") != "1" {
+		t.Skip("helper process")
+	}
+	serveTestMCP(os.Stdin, os.Stdout)
+	os.Exit(0)
+}
+
+func TestStartMCPServer(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestMCPHelperProcess$")
+	cmd.Env = append(os.Environ(), "OPENAIRT_MCP_HELPER_PROCESS=1")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server, err := openairt.StartMCPServer(ctx, "docs", cmd)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name":"stand-in","version":"1.0.0"}`, string(server.Info()))
+
+	result, err := server.CallTool(ctx, "search", json.RawMessage(`{"q":"go"}`))
+	require.NoError(t, err)
+	require.Equal(t, "results for go", result.Text())
+
+	require.NoError(t, server.Close())
+	_, err = server.ListTools(ctx)
+	require.ErrorIs(t, err, openairt.ErrMCPServerClosed)
+	// A deferred Close after an explicit one doesn't fail.
+	require.NoError(t, server.Close())
+}
+
+func TestMCPServer(t *testing.T) {
+	server := connectTestMCP(t, "docs")
+	ctx := context.Background()
+
+	tools, err := server.ListTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+	require.Equal(t, "search", tools[0].Name)
+	require.Equal(t, "fail.now", tools[1].Name)
+
+	result, err := server.CallTool(ctx, "fail.now", nil)
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	require.Equal(t, "boom", result.Text())
+
+	// The duplicate answers are ignored.
+	_, err = server.CallTool(ctx, "repeat", nil)
+	require.NoError(t, err)
+	result, err = server.CallTool(ctx, "fail.now", nil)
+	require.NoError(t, err)
+	require.Equal(t, "boom", result.Text())
+
+	_, err = server.CallTool(ctx, "missing", nil)
+	var rpcErr *openairt.MCPRPCError
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, -32602, rpcErr.Code)
+}
+
+func TestMCPBridge(t *testing.T) {
+	conn, server := startSessionConn(t)
+	var calls = make(chan openairt.MCPBridgeCall, 2)
+	bridge := openairt.NewMCPBridge(conn, []*openairt.MCPServer{connectTestMCP(t, "docs")}, openairt.MCPBridgeOptions{
+		OnCall: func(call openairt.MCPBridgeCall) { calls <- call },
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := bridge.ApplySession(ctx)
+	require.NoError(t, err)
+
+	updates := sentOfType(server, "session.update")
+	require.Len(t, updates, 1)
+	require.JSONEq(t, `{"type":"realtime","tools":[
+		{"type":"function","name":"docs_search","description":"Search the docs",
+			"parameters":{"type":"object","properties":{"q":{"type":"string"}}}},
+		{"type":"function","name":"docs_fail_now","description":"",
+			"parameters":{"type":"object","properties":{}}}
+	]}`, sentSession(t, updates[0]))
+
+	handleServerEvents(t, bridge.HandleEvent,
+		`{"type":"response.output_item.done","response_id":"resp_1","item":{"id":"item_1","type":"function_call",`+
+			`"call_id":"call_1","name":"docs_search","arguments":"{\"q\":\"go\"}"}}`,
+		`{"type":"response.output_item.done","response_id":"resp_1","item":{"id":"item_2","type":"function_call",`+
+			`"call_id":"call_2","name":"docs_fail_now","arguments":"{}"}}`,
+		`{"type":"response.output_item.done","response_id":"resp_1","item":{"id":"item_3","type":"function_call",`+
+			`"call_id":"call_3","name":"local_tool","arguments":"{}"}}`,
+		responseDoneEvent("resp_1", 10, 10),
+	)
+	bridge.Wait()
+	close(calls)
+
+	outputs := map[string]string{}
+	for call := range calls {
+		require.NoError(t, call.Err)
+		outputs[call.Name] = call.Output
+	}
+	require.Equal(t, map[string]string{"search": "results for go", "fail.now": `{"error":"boom"}`}, outputs)
+
+	created := sentOfType(server, "conversation.item.create")
+	require.Len(t, created, 2)
+	for _, e := range created {
+		item := e["item"].(map[string]any)
+		require.Equal(t, "function_call_output", item["type"])
+		require.NotEqual(t, "call_3", item["call_id"])
+	}
+	require.Len(t, sentOfType(server, "response.create"), 1)
+}
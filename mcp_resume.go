@@ -0,0 +1,100 @@
+package openairt
+
+import (
+	"context"
+	"sync"
+)
+
+// mcpResumer tracks the work requested by the items of responses, e.g. the MCP approvals or calls,
+// and creates a response to resume once a response is done and all its work is finished.
+type mcpResumer struct {
+	conn       *Conn
+	noResponse bool
+	report     func(err error)
+
+	mu        sync.Mutex
+	responses map[string]*mcpPendingResponse
+	// seen maps the keys of the works to the IDs of their responses, until the responses are done.
+	seen map[string]string
+}
+
+// mcpPendingResponse is the work of a response.
+type mcpPendingResponse struct {
+	pending int
+	done    bool
+}
+
+func newMCPResumer(conn *Conn, noResponse bool, report func(err error)) *mcpResumer {
+	return &mcpResumer{
+		conn:       conn,
+		noResponse: noResponse,
+		report:     report,
+		responses:  make(map[string]*mcpPendingResponse),
+		seen:       make(map[string]string),
+	}
+}
+
+// add records a work requested by the response, or returns false if the work of key was already added.
+func (r *mcpResumer) add(responseID, key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.seen[key]; ok {
+		return false
+	}
+	r.seen[key] = responseID
+	resp, ok := r.responses[responseID]
+	if !ok {
+		resp = &mcpPendingResponse{}
+		r.responses[responseID] = resp
+	}
+	resp.pending++
+	return true
+}
+
+// finish records the end of a work of the response, and resumes it if it's done.
+func (r *mcpResumer) finish(ctx context.Context, responseID string) error {
+	r.mu.Lock()
+	if resp, ok := r.responses[responseID]; ok {
+		resp.pending--
+	}
+	r.mu.Unlock()
+	return r.resume(ctx, responseID)
+}
+
+// done records that the response is done, and resumes it if its work is finished.
+// The responses which requested no work are ignored.
+func (r *mcpResumer) done(ctx context.Context, responseID string) error {
+	r.mu.Lock()
+	for key, id := range r.seen {
+		if id == responseID {
+			delete(r.seen, key)
+		}
+	}
+	resp, ok := r.responses[responseID]
+	if ok {
+		resp.done = true
+	}
+	r.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return r.resume(ctx, responseID)
+}
+
+// resume creates a response once the response is done and all its work is finished.
+// It's merged with the other requests to resume pending on the connection.
+func (r *mcpResumer) resume(ctx context.Context, responseID string) error {
+	r.mu.Lock()
+	resp, ok := r.responses[responseID]
+	if !ok || !resp.done || resp.pending > 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	delete(r.responses, responseID)
+	r.mu.Unlock()
+
+	if r.noResponse {
+		return nil
+	}
+	return r.conn.resumeResponse(ctx, r.report)
+}
@@ -0,0 +1,367 @@
+package openairt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	mcpProtocolVersion = "2025-06-18"
+	mcpClientName      = "go-openai-realtime"
+	mcpCloseTimeout    = 5 * time.Second
+
+	mcpErrorCodeMethodNotFound = -32601
+)
+
+// ErrMCPServerClosed is returned by the calls to an MCPServer whose connection is closed.
+var ErrMCPServerClosed = errors.New("mcp server closed")
+
+// MCPRPCError is a JSON-RPC error returned by an MCP server.
+type MCPRPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *MCPRPCError) Error() string {
+	return fmt.Sprintf("mcp rpc error: %d: %s", e.Code, e.Message)
+}
+
+// MCPContent is a content block of an MCP tool result.
+type MCPContent struct {
+	// The type of the content: text, image, audio, resource or resource_link.
+	Type string `json:"type"`
+
+	// The text of text content.
+	Text string `json:"text,omitempty"`
+
+	// The base64-encoded data of image and audio content.
+	Data string `json:"data,omitempty"`
+
+	// The MIME type of image, audio and resource content.
+	MimeType string `json:"mimeType,omitempty"`
+
+	// The embedded resource of resource content.
+	Resource json.RawMessage `json:"resource,omitempty"`
+
+	// The URI of resource_link content.
+	URI string `json:"uri,omitempty"`
+}
+
+// MCPToolResult is the result of an MCP tool call.
+type MCPToolResult struct {
+	Content []MCPContent `json:"content"`
+
+	// The structured result of tools with an output schema.
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+
+	// Whether the tool failed, the content describes the error.
+	IsError bool `json:"isError,omitempty"`
+}
+
+// Text returns the text of the result: the text contents joined by newlines, with the other contents
+// summarized, or the structured content if there is no content.
+func (r MCPToolResult) Text() string {
+	if len(r.Content) == 0 && len(r.StructuredContent) > 0 {
+		return string(r.StructuredContent)
+	}
+	parts := make([]string, 0, len(r.Content))
+	for _, c := range r.Content {
+		switch c.Type {
+		case "text":
+			parts = append(parts, c.Text)
+		case "resource_link":
+			parts = append(parts, fmt.Sprintf("[%s %s]", c.Type, c.URI))
+		case "resource":
+			parts = append(parts, string(c.Resource))
+		default:
+			parts = append(parts, fmt.Sprintf("[%s %s]", c.Type, c.MimeType))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// MCPServer is a client of a local MCP server speaking newline-delimited JSON-RPC over stdio.
+type MCPServer struct {
+	label string
+	info  json.RawMessage
+
+	writeMu sync.Mutex
+	w       io.Writer
+	close   func() error
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan mcpRPCMessage
+	closed  bool
+	err     error
+	done    chan struct{}
+}
+
+type mcpRPCMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  any              `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *MCPRPCError     `json:"error,omitempty"`
+}
+
+// ConnectMCPServer connects to an MCP server reading its messages from r and writing to w,
+// and initializes the MCP session. The label identifies the server, e.g. in the names of bridged tools.
+// If w is an io.Closer, it's closed by Close.
+func ConnectMCPServer(ctx context.Context, label string, r io.Reader, w io.Writer) (*MCPServer, error) {
+	s := &MCPServer{
+		label:   label,
+		w:       w,
+		pending: make(map[int64]chan mcpRPCMessage),
+		done:    make(chan struct{}),
+	}
+	s.close = func() error {
+		if c, ok := w.(io.Closer); ok {
+			return c.Close()
+		}
+		return nil
+	}
+	go s.read(r)
+
+	if err := s.initialize(ctx); err != nil {
+		_ = s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// StartMCPServer starts the command of an MCP server and connects to its stdin and stdout.
+// Close closes its stdin and waits for it to exit, killing it if it doesn't exit in time.
+func StartMCPServer(ctx context.Context, label string, cmd *exec.Cmd) (*MCPServer, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start mcp server %s: %w", label, err)
+	}
+	return ConnectMCPServer(ctx, label, stdout, &mcpProcess{WriteCloser: stdin, cmd: cmd, exited: make(chan error, 1)})
+}
+
+// mcpProcess is the stdin of an MCP server process, closing it stops the process.
+type mcpProcess struct {
+	io.WriteCloser
+	cmd    *exec.Cmd
+	exited chan error
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Close stops the process once, the later calls return the error of the first one.
+func (p *mcpProcess) Close() error {
+	p.closeOnce.Do(func() {
+		p.closeErr = p.WriteCloser.Close()
+		go func() { p.exited <- p.cmd.Wait() }()
+		select {
+		case <-p.exited:
+		case <-time.After(mcpCloseTimeout):
+			_ = p.cmd.Process.Kill()
+			<-p.exited
+		}
+	})
+	return p.closeErr
+}
+
+// Label returns the label of the server.
+func (s *MCPServer) Label() string {
+	return s.label
+}
+
+// Info returns the server info from the initialization, e.g. {"name":"docs","version":"1.0.0"}.
+func (s *MCPServer) Info() json.RawMessage {
+	return s.info
+}
+
+// ListTools lists the tools of the server, with their input schemas as JSON.
+func (s *MCPServer) ListTools(ctx context.Context) ([]MCPTool, error) {
+	var tools []MCPTool
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var result struct {
+			Tools []struct {
+				Name        string          `json:"name"`
+				Description string          `json:"description"`
+				InputSchema json.RawMessage `json:"inputSchema"`
+				Annotations any             `json:"annotations,omitempty"`
+			} `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := s.call(ctx, "tools/list", params, &result); err != nil {
+			return nil, err
+		}
+		for _, t := range result.Tools {
+			tools = append(tools, MCPTool{
+				Name:        t.Name,
+				Description: t.Description,
+				InputSchema: string(t.InputSchema),
+				Annotations: t.Annotations,
+			})
+		}
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool calls the tool with the JSON arguments. A tool failure is reported by the IsError of the result,
+// the returned error is a failure of the call itself, e.g. an *MCPRPCError.
+func (s *MCPServer) CallTool(ctx context.Context, name string, arguments json.RawMessage) (MCPToolResult, error) {
+	if len(bytes.TrimSpace(arguments)) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	var result MCPToolResult
+	err := s.call(ctx, "tools/call", map[string]any{"name": name, "arguments": arguments}, &result)
+	return result, err
+}
+
+// Close closes the connection to the server.
+func (s *MCPServer) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	return s.close()
+}
+
+func (s *MCPServer) initialize(ctx context.Context) error {
+	var result struct {
+		ServerInfo json.RawMessage `json:"serverInfo"`
+	}
+	err := s.call(ctx, "initialize", map[string]any{
+		"protocolVersion": mcpProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": mcpClientName},
+	}, &result)
+	if err != nil {
+		return fmt.Errorf("initialize mcp server %s: %w", s.label, err)
+	}
+	s.info = result.ServerInfo
+	return s.write(mcpRPCMessage{Method: "notifications/initialized"})
+}
+
+func (s *MCPServer) call(ctx context.Context, method string, params, result any) error {
+	ch := make(chan mcpRPCMessage, 1)
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrMCPServerClosed
+	}
+	if s.err != nil {
+		s.mu.Unlock()
+		return s.err
+	}
+	s.nextID++
+	id := s.nextID
+	s.pending[id] = ch
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	rawID := json.RawMessage(fmt.Sprint(id))
+	if err := s.write(mcpRPCMessage{ID: &rawID, Method: method, Params: params}); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.done:
+		return s.err
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		return json.Unmarshal(resp.Result, result)
+	}
+}
+
+func (s *MCPServer) write(msg mcpRPCMessage) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// read dispatches the responses to the pending calls until r fails.
+func (s *MCPServer) read(r io.Reader) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			s.dispatch(line)
+		}
+		if err != nil {
+			s.mu.Lock()
+			if s.closed || errors.Is(err, io.EOF) {
+				err = ErrMCPServerClosed
+			}
+			s.err = err
+			s.mu.Unlock()
+			close(s.done)
+			return
+		}
+	}
+}
+
+func (s *MCPServer) dispatch(line []byte) {
+	var msg mcpRPCMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return
+	}
+	switch {
+	case msg.Method != "" && msg.ID != nil:
+		// Requests of the server: answer pings, refuse the rest.
+		reply := mcpRPCMessage{ID: msg.ID}
+		if msg.Method == "ping" {
+			reply.Result = json.RawMessage("{}")
+		} else {
+			reply.Error = &MCPRPCError{Code: mcpErrorCodeMethodNotFound, Message: "method not found"}
+		}
+		_ = s.write(reply)
+	case msg.Method == "" && msg.ID != nil:
+		var id int64
+		if err := json.Unmarshal(*msg.ID, &id); err != nil {
+			return
+		}
+		// The pending call is removed so that a duplicate answer is ignored instead of blocking the reading.
+		s.mu.Lock()
+		ch, ok := s.pending[id]
+		delete(s.pending, id)
+		s.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+	}
+}
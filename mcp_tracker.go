@@ -0,0 +1,284 @@
+package openairt
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MCPStatus is the status of an MCP call or tool listing.
+type MCPStatus string
+
+const (
+	// MCPStatusArguments is a call whose arguments are streaming.
+	MCPStatusArguments  MCPStatus = "arguments"
+	MCPStatusInProgress MCPStatus = "in_progress"
+	MCPStatusCompleted  MCPStatus = "completed"
+	MCPStatusFailed     MCPStatus = "failed"
+)
+
+// MCPCall is the state of an MCP tool call.
+type MCPCall struct {
+	ItemID      string
+	ResponseID  string
+	ServerLabel string
+	Name        string
+	// Arguments assembled from the streamed deltas, complete once the call is in progress.
+	Arguments string
+	Status    MCPStatus
+	Output    string
+	// Error of a failed call, if reported.
+	Error     *MCPError
+	StartedAt time.Time
+	// EndedAt is zero until the call is completed or failed.
+	EndedAt time.Time
+}
+
+// MCPToolList is the state of the tool listing of an MCP server.
+type MCPToolList struct {
+	ItemID      string
+	ServerLabel string
+	Status      MCPStatus
+	Tools       []MCPTool
+}
+
+// MCPTrackerOptions configures an MCPTracker.
+type MCPTrackerOptions struct {
+	// OnCall is called with the state of a call on every change, e.g. to show "calling tool X".
+	OnCall func(MCPCall)
+
+	// OnToolList is called with the state of a tool listing on every change.
+	OnToolList func(MCPToolList)
+}
+
+// MCPTracker tracks the MCP tool calls through their arguments, progress and results,
+// and maintains the catalog of the tools of each MCP server from the mcp_list_tools items.
+//
+// Its HandleEvent must be registered on the ConnHandler of the connection.
+type MCPTracker struct {
+	opts MCPTrackerOptions
+	now  func() time.Time
+
+	mu    sync.Mutex
+	calls map[string]*MCPCall
+	// order holds the call item ids in the order they started.
+	order []string
+	lists map[string]*MCPToolList
+	// tools is the catalog of the last completed listing of each server label.
+	tools map[string][]MCPTool
+}
+
+// NewMCPTracker creates an MCPTracker.
+func NewMCPTracker(opts MCPTrackerOptions) *MCPTracker {
+	return &MCPTracker{
+		opts:  opts,
+		now:   time.Now,
+		calls: make(map[string]*MCPCall),
+		lists: make(map[string]*MCPToolList),
+		tools: make(map[string][]MCPTool),
+	}
+}
+
+// Call returns the call of the item id.
+func (t *MCPTracker) Call(itemID string) (MCPCall, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	call, ok := t.calls[itemID]
+	if !ok {
+		return MCPCall{}, false
+	}
+	return *call, true
+}
+
+// Calls returns the calls in the order they started.
+func (t *MCPTracker) Calls() []MCPCall {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	calls := make([]MCPCall, 0, len(t.order))
+	for _, id := range t.order {
+		calls = append(calls, *t.calls[id])
+	}
+	return calls
+}
+
+// ActiveCalls returns the calls neither completed nor failed, in the order they started.
+func (t *MCPTracker) ActiveCalls() []MCPCall {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var calls []MCPCall
+	for _, id := range t.order {
+		if call := t.calls[id]; call.EndedAt.IsZero() {
+			calls = append(calls, *call)
+		}
+	}
+	return calls
+}
+
+// ServerLabels returns the sorted labels of the servers whose tools are listed.
+func (t *MCPTracker) ServerLabels() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	labels := make([]string, 0, len(t.tools))
+	for label := range t.tools {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Tools returns the tools of the server label.
+func (t *MCPTracker) Tools(serverLabel string) []MCPTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]MCPTool(nil), t.tools[serverLabel]...)
+}
+
+// Tool returns the tool of the server label by name.
+func (t *MCPTracker) Tool(serverLabel, name string) (MCPTool, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tool := range t.tools[serverLabel] {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return MCPTool{}, false
+}
+
+// HandleEvent is the ServerEventHandler of the MCPTracker.
+func (t *MCPTracker) HandleEvent(_ context.Context, event ServerEvent) {
+	var (
+		call *MCPCall
+		list *MCPToolList
+	)
+
+	t.mu.Lock()
+	switch e := event.(type) {
+	case ResponseOutputItemAddedEvent:
+		call, list = t.item(e.ResponseID, e.Item)
+	case ResponseOutputItemDoneEvent:
+		call, list = t.item(e.ResponseID, e.Item)
+	case ConversationItemAddedEvent:
+		call, list = t.item("", e.Item)
+	case ConversationItemDoneEvent:
+		call, list = t.item("", e.Item)
+	case ResponseMcpCallArgumentsDeltaEvent:
+		call = t.call(e.ItemID, e.ResponseID)
+		call.Arguments += e.Delta
+		call.Status = MCPStatusArguments
+	case ResponseMcpCallArgumentsDoneEvent:
+		call = t.call(e.ItemID, e.ResponseID)
+		call.Arguments = e.Arguments
+	case ResponseMcpCallInProgressEvent:
+		call = t.call(e.ItemID, "")
+		call.Status = MCPStatusInProgress
+	case ResponseMcpCallCompletedEvent:
+		call = t.call(e.ItemID, "")
+		t.end(call, MCPStatusCompleted)
+	case ResponseMcpCallFailedEvent:
+		call = t.call(e.ItemID, "")
+		t.end(call, MCPStatusFailed)
+	case McpListToolsInProgressEvent:
+		list = t.list(e.ItemID)
+		list.Status = MCPStatusInProgress
+	case McpListToolsCompletedEvent:
+		list = t.list(e.ItemID)
+		list.Status = MCPStatusCompleted
+		t.catalog(list)
+	case McpListToolsFailedEvent:
+		list = t.list(e.ItemID)
+		list.Status = MCPStatusFailed
+	}
+	var callSnapshot MCPCall
+	var listSnapshot MCPToolList
+	if call != nil {
+		callSnapshot = *call
+	}
+	if list != nil {
+		listSnapshot = *list
+		listSnapshot.Tools = append([]MCPTool(nil), list.Tools...)
+	}
+	t.mu.Unlock()
+
+	if call != nil && t.opts.OnCall != nil {
+		t.opts.OnCall(callSnapshot)
+	}
+	if list != nil && t.opts.OnToolList != nil {
+		t.opts.OnToolList(listSnapshot)
+	}
+}
+
+// item updates the call or the tool listing of an item.
+func (t *MCPTracker) item(responseID string, item MessageItemUnion) (*MCPCall, *MCPToolList) {
+	switch {
+	case item.MCPToolCall != nil:
+		m := item.MCPToolCall
+		call := t.call(m.ID, responseID)
+		call.ServerLabel, call.Name = m.ServerLabel, m.Name
+		if m.Arguments != "" {
+			call.Arguments = m.Arguments
+		}
+		if m.Output != "" {
+			call.Output = m.Output
+			t.end(call, MCPStatusCompleted)
+		}
+		if m.CallError != nil {
+			call.Error = m.CallError
+			t.end(call, MCPStatusFailed)
+		}
+		return call, nil
+	case item.MCPListTools != nil:
+		m := item.MCPListTools
+		list := t.list(m.ID)
+		list.ServerLabel = m.ServerLabel
+		if m.Tools != nil {
+			list.Tools = m.Tools
+		}
+		if list.Status == MCPStatusCompleted {
+			t.catalog(list)
+		}
+		return nil, list
+	default:
+		return nil, nil
+	}
+}
+
+func (t *MCPTracker) call(itemID, responseID string) *MCPCall {
+	call, ok := t.calls[itemID]
+	if !ok {
+		call = &MCPCall{ItemID: itemID, Status: MCPStatusArguments, StartedAt: t.now()}
+		t.calls[itemID] = call
+		t.order = append(t.order, itemID)
+	}
+	if responseID != "" {
+		call.ResponseID = responseID
+	}
+	return call
+}
+
+// end sets the final status of a call, a failure isn't overridden by a completion.
+func (t *MCPTracker) end(call *MCPCall, status MCPStatus) {
+	if call.Status != MCPStatusFailed {
+		call.Status = status
+	}
+	if call.EndedAt.IsZero() {
+		call.EndedAt = t.now()
+	}
+}
+
+func (t *MCPTracker) list(itemID string) *MCPToolList {
+	list, ok := t.lists[itemID]
+	if !ok {
+		list = &MCPToolList{ItemID: itemID}
+		t.lists[itemID] = list
+	}
+	return list
+}
+
+// catalog replaces the tools of the server label with a completed listing.
+func (t *MCPTracker) catalog(list *MCPToolList) {
+	if list.ServerLabel != "" && list.Tools != nil {
+		t.tools[list.ServerLabel] = list.Tools
+	}
+}
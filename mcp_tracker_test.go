@@ -0,0 +1,114 @@
+package openairt_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPTrackerCalls(t *testing.T) {
+	var updates []openairt.MCPCall
+	tracker := openairt.NewMCPTracker(openairt.MCPTrackerOptions{
+		OnCall: func(call openairt.MCPCall) { updates = append(updates, call) },
+	})
+	handleServerEvents(t, tracker.HandleEvent,
+		`{"type":"response.output_item.added","response_id":"resp_1","item":`+
+			`{"id":"mcp_1","type":"mcp_call","server_label":"docs","name":"search"}}`,
+		`{"type":"response.mcp_call_arguments.delta","response_id":"resp_1","item_id":"mcp_1","delta":"{\"q\":"}`,
+		`{"type":"response.mcp_call_arguments.delta","response_id":"resp_1","item_id":"mcp_1","delta":"\"go\"}"}`,
+	)
+	call, ok := tracker.Call("mcp_1")
+	require.True(t, ok)
+	require.Equal(t, "search", call.Name)
+	require.Equal(t, "docs", call.ServerLabel)
+	require.Equal(t, "resp_1", call.ResponseID)
+	require.Equal(t, `{"q":"go"}`, call.Arguments)
+	require.Equal(t, openairt.MCPStatusArguments, call.Status)
+
+	handleServerEvents(t, tracker.HandleEvent,
+		`{"type":"response.mcp_call_arguments.done","response_id":"resp_1","item_id":"mcp_1","arguments":"{\"q\":\"go\"}"}`,
+		`{"type":"response.mcp_call.in_progress","item_id":"mcp_1"}`,
+		`{"type":"response.output_item.added","response_id":"resp_1","item":`+
+			`{"id":"mcp_2","type":"mcp_call","server_label":"docs","name":"fetch","arguments":"{}"}}`,
+	)
+	require.Len(t, tracker.ActiveCalls(), 2)
+	require.Equal(t, openairt.MCPStatusInProgress, updates[len(updates)-2].Status)
+
+	handleServerEvents(t, tracker.HandleEvent,
+		`{"type":"response.mcp_call.completed","item_id":"mcp_1"}`,
+		`{"type":"response.output_item.done","response_id":"resp_1","item":`+
+			`{"id":"mcp_1","type":"mcp_call","server_label":"docs","name":"search","arguments":"{\"q\":\"go\"}","output":"3 results"}}`,
+		`{"type":"response.mcp_call.failed","item_id":"mcp_2"}`,
+		`{"type":"response.output_item.done","response_id":"resp_1","item":`+
+			`{"id":"mcp_2","type":"mcp_call","server_label":"docs","name":"fetch","arguments":"{}",`+
+			`"error":{"type":"protocol_error","code":-32602,"message":"invalid params"}}}`,
+	)
+	require.Empty(t, tracker.ActiveCalls())
+
+	calls := tracker.Calls()
+	require.Len(t, calls, 2)
+	require.Equal(t, openairt.MCPStatusCompleted, calls[0].Status)
+	require.Equal(t, "3 results", calls[0].Output)
+	require.False(t, calls[0].EndedAt.IsZero())
+	require.Equal(t, openairt.MCPStatusFailed, calls[1].Status)
+	require.NotNil(t, calls[1].Error)
+	require.EqualError(t, calls[1].Error, "mcp protocol_error: -32602: invalid params")
+	require.Equal(t, calls[1], updates[len(updates)-1])
+}
+
+func TestMCPTrackerTools(t *testing.T) {
+	var lists []openairt.MCPToolList
+	tracker := openairt.NewMCPTracker(openairt.MCPTrackerOptions{
+		OnToolList: func(list openairt.MCPToolList) { lists = append(lists, list) },
+	})
+	handleServerEvents(t, tracker.HandleEvent,
+		`{"type":"conversation.item.added","item":{"id":"list_1","type":"mcp_list_tools","server_label":"docs"}}`,
+		`{"type":"mcp_list_tools.in_progress","item_id":"list_1"}`,
+		`{"type":"conversation.item.done","item":{"id":"list_1","type":"mcp_list_tools","server_label":"docs",`+
+			`"tools":[{"name":"search","description":"Search the docs"},{"name":"fetch"}]}}`,
+	)
+	// The tools aren't in the catalog until the listing completes.
+	require.Empty(t, tracker.ServerLabels())
+	require.Equal(t, openairt.MCPStatusInProgress, lists[len(lists)-1].Status)
+
+	handleServerEvents(t, tracker.HandleEvent,
+		`{"type":"mcp_list_tools.completed","item_id":"list_1"}`,
+		`{"type":"conversation.item.added","item":{"id":"list_2","type":"mcp_list_tools","server_label":"calendar"}}`,
+		`{"type":"mcp_list_tools.failed","item_id":"list_2"}`,
+	)
+	require.Equal(t, []string{"docs"}, tracker.ServerLabels())
+	require.Len(t, tracker.Tools("docs"), 2)
+	tool, ok := tracker.Tool("docs", "search")
+	require.True(t, ok)
+	require.Equal(t, "Search the docs", tool.Description)
+	_, ok = tracker.Tool("docs", "delete")
+	require.False(t, ok)
+
+	require.Equal(t, openairt.MCPToolList{
+		ItemID: "list_2", ServerLabel: "calendar", Status: openairt.MCPStatusFailed,
+	}, lists[len(lists)-1])
+}
+
+func TestMessageItemMCPToolCallError(t *testing.T) {
+	var call openairt.MessageItemMCPToolCall
+	require.NoError(t, json.Unmarshal([]byte(`{"type":"mcp_call","id":"call_1",`+
+		`"error":{"type":"http_error","code":502,"message":"bad gateway"}}`), &call))
+	require.Equal(t, &openairt.MCPHTTPError{Code: 502, Message: "bad gateway"}, call.CallError.HTTP)
+	// The deprecated field keeps the code and message of every type of error.
+	require.Equal(t, &openairt.MCPProtocolError{Code: 502, Message: "bad gateway"}, call.Error) //nolint:staticcheck
+
+	data, err := json.Marshal(call)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"mcp_call","id":"call_1",`+
+		`"error":{"type":"http_error","code":502,"message":"bad gateway"}}`, string(data))
+
+	// The deprecated field is still marshaled.
+	data, err = json.Marshal(openairt.MessageItemMCPToolCall{
+		ID: "call_2", Error: &openairt.MCPProtocolError{Code: -32602, Message: "invalid params"}, //nolint:staticcheck
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"mcp_call","id":"call_2",`+
+		`"error":{"type":"protocol_error","code":-32602,"message":"invalid params"}}`, string(data))
+}
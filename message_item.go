@@ -26,6 +26,7 @@ const (
 	MessageContentTypeTranscript  MessageContentType = "transcript"
 	MessageContentTypeInputText   MessageContentType = "input_text"
 	MessageContentTypeInputAudio  MessageContentType = "input_audio"
+	MessageContentTypeInputImage  MessageContentType = "input_image"
 	MessageContentTypeOutputText  MessageContentType = "output_text"
 	MessageContentTypeOutputAudio MessageContentType = "output_audio"
 )
@@ -52,6 +53,19 @@ type MessageContentImage struct {
 
 type MessageContentSystem MessageContentText
 
+func (m MessageContentSystem) MarshalJSON() ([]byte, error) {
+	type typeAlias MessageContentSystem
+	type typeWrapper struct {
+		typeAlias
+		Type MessageContentType `json:"type"`
+	}
+	shadow := typeWrapper{
+		typeAlias: typeAlias(m),
+		Type:      MessageContentTypeInputText,
+	}
+	return json.Marshal(shadow)
+}
+
 type MessageItemSystem struct {
 	// The unique ID of the item. This may be provided by the client or generated by the server.
 	ID string `json:"id,omitempty"`
@@ -276,7 +290,7 @@ type MessageItemMCPApprovalResponse struct {
 	ApprovalRequestID string `json:"approval_request_id,omitempty"`
 
 	// Whether the request was approved.
-	Approve bool `json:"approve,omitempty"`
+	Approve bool `json:"approve"`
 
 	// Optional reason for the decision.
 	Reason string `json:"reason,omitempty"`
@@ -432,6 +446,20 @@ type MCPError struct {
 	HTTP *MCPHTTPError `json:",omitempty"`
 }
 
+// Error returns the type, code and message of the error.
+func (m MCPError) Error() string {
+	switch {
+	case m.Protocol != nil:
+		return fmt.Sprintf("mcp %s: %d: %s", m.Protocol.ErrorType(), m.Protocol.Code, m.Protocol.Message)
+	case m.ToolExecution != nil:
+		return fmt.Sprintf("mcp %s: %s", m.ToolExecution.ErrorType(), m.ToolExecution.Message)
+	case m.HTTP != nil:
+		return fmt.Sprintf("mcp %s: %d: %s", m.HTTP.ErrorType(), m.HTTP.Code, m.HTTP.Message)
+	default:
+		return "mcp error"
+	}
+}
+
 func (m MCPError) MarshalJSON() ([]byte, error) {
 	if m.Protocol != nil {
 		return json.Marshal(m.Protocol)
@@ -478,8 +506,13 @@ type MessageItemMCPToolCall struct {
 	// The ID of an associated approval request, if any.
 	ApprovalRequestID string `json:"approval_request_id,omitempty"`
 
+	// The code and message of the error from the tool call, if any, whatever its type.
+	//
+	// Deprecated: Use CallError, which tells the types of errors apart.
+	Error *MCPProtocolError `json:"-"`
+
 	// The error from the tool call, if any.
-	Error *MCPProtocolError `json:"error,omitempty"`
+	CallError *MCPError `json:"-"`
 
 	// The output from the tool call.
 	Output string `json:"output,omitempty"`
@@ -493,15 +526,45 @@ func (m MessageItemMCPToolCall) MarshalJSON() ([]byte, error) {
 	type typeAlias MessageItemMCPToolCall
 	type typeWrapper struct {
 		typeAlias
-		Type MessageItemType `json:"type"`
+		Type  MessageItemType `json:"type"`
+		Error *MCPError       `json:"error,omitempty"`
 	}
 	shadow := typeWrapper{
 		typeAlias: typeAlias(m),
 		Type:      m.MessageItemType(),
+		Error:     m.CallError,
+	}
+	if shadow.Error == nil && m.Error != nil {
+		shadow.Error = &MCPError{Protocol: m.Error}
 	}
 	return json.Marshal(shadow)
 }
 
+func (m *MessageItemMCPToolCall) UnmarshalJSON(data []byte) error {
+	type typeAlias MessageItemMCPToolCall
+	type typeWrapper struct {
+		*typeAlias
+		Error *MCPError `json:"error"`
+	}
+	shadow := typeWrapper{typeAlias: (*typeAlias)(m)}
+	if err := json.Unmarshal(data, &shadow); err != nil {
+		return err
+	}
+	// Error keeps the code and message of every type of error, as it did before CallError.
+	m.CallError, m.Error = shadow.Error, nil
+	if e := shadow.Error; e != nil {
+		switch {
+		case e.Protocol != nil:
+			m.Error = e.Protocol
+		case e.ToolExecution != nil:
+			m.Error = &MCPProtocolError{Message: e.ToolExecution.Message}
+		case e.HTTP != nil:
+			m.Error = &MCPProtocolError{Code: e.HTTP.Code, Message: e.HTTP.Message}
+		}
+	}
+	return nil
+}
+
 type MessageItemMCPApprovalRequest struct {
 	// The unique ID of the approval request.
 	ID string `json:"id,omitempty"`
@@ -563,24 +626,28 @@ type MessageItemUnion struct {
 }
 
 func (m MessageItemUnion) MarshalJSON() ([]byte, error) {
-	switch {
-	case m.System != nil:
+	kind, err := m.kind()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case MessageItemKindSystem:
 		return json.Marshal(m.System)
-	case m.User != nil:
+	case MessageItemKindUser:
 		return json.Marshal(m.User)
-	case m.Assistant != nil:
+	case MessageItemKindAssistant:
 		return json.Marshal(m.Assistant)
-	case m.FunctionCall != nil:
+	case MessageItemKindFunctionCall:
 		return json.Marshal(m.FunctionCall)
-	case m.FunctionCallOutput != nil:
+	case MessageItemKindFunctionCallOutput:
 		return json.Marshal(m.FunctionCallOutput)
-	case m.MCPApprovalResponse != nil:
+	case MessageItemKindMCPApprovalResponse:
 		return json.Marshal(m.MCPApprovalResponse)
-	case m.MCPListTools != nil:
+	case MessageItemKindMCPListTools:
 		return json.Marshal(m.MCPListTools)
-	case m.MCPToolCall != nil:
+	case MessageItemKindMCPToolCall:
 		return json.Marshal(m.MCPToolCall)
-	case m.MCPApprovalRequest != nil:
+	case MessageItemKindMCPApprovalRequest:
 		return json.Marshal(m.MCPApprovalRequest)
 	default:
 		return nil, errors.New("unknown message item type")
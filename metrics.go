@@ -0,0 +1,159 @@
+package openairt
+
+import (
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements from a Conn. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// EventSent is called after a client event is sent successfully.
+	EventSent(eventType ClientEventType)
+
+	// EventReceived is called after a server event is received.
+	EventReceived(eventType ServerEventType)
+
+	// AudioSent is called with the number of audio bytes appended to the input audio buffer.
+	AudioSent(bytes int)
+
+	// AudioReceived is called with the number of audio bytes of each response.output_audio.delta.
+	AudioReceived(bytes int)
+
+	// SpeechToFirstAudio is called with the time from input_audio_buffer.speech_stopped
+	// to the first response.output_audio.delta after it.
+	SpeechToFirstAudio(d time.Duration)
+
+	// TimeToFirstText is called with the time from response.created to the first
+	// response.output_text.delta or response.output_audio_transcript.delta of the response.
+	TimeToFirstText(d time.Duration)
+
+	// Error is called with the code of each error event.
+	Error(code string)
+
+	// Reconnect is called once per dead connection replaced, by the component which replaces it:
+	// a Supervisor when it restarts a session on a new connection, a ConnPool when it replaces
+	// a dropped idle connection. A Supervisor getting its connections from a ConnPool counts
+	// the restarts of its sessions, the pool doesn't count the connections it dials to refill
+	// after handing one out. The idle connections replaced before their session expires are not counted.
+	Reconnect()
+
+	// EventDropped is called when a ConnHandler drops a server event because its dispatch queue is full.
+	EventDropped(eventType ServerEventType)
+}
+
+// NopMetrics is a Metrics that does nothing.
+type NopMetrics struct{}
+
+// EventSent does nothing.
+func (NopMetrics) EventSent(_ ClientEventType) {}
+
+// EventReceived does nothing.
+func (NopMetrics) EventReceived(_ ServerEventType) {}
+
+// AudioSent does nothing.
+func (NopMetrics) AudioSent(_ int) {}
+
+// AudioReceived does nothing.
+func (NopMetrics) AudioReceived(_ int) {}
+
+// SpeechToFirstAudio does nothing.
+func (NopMetrics) SpeechToFirstAudio(_ time.Duration) {}
+
+// TimeToFirstText does nothing.
+func (NopMetrics) TimeToFirstText(_ time.Duration) {}
+
+// Error does nothing.
+func (NopMetrics) Error(_ string) {}
+
+// Reconnect does nothing.
+func (NopMetrics) Reconnect() {}
+
+// EventDropped does nothing.
+func (NopMetrics) EventDropped(_ ServerEventType) {}
+
+// base64DecodedLen returns the exact number of bytes encoded in the padded base64 string s.
+func base64DecodedLen(s string) int {
+	n := len(s) / 4 * 3 //nolint:mnd // 4 base64 characters encode 3 bytes
+	for i := len(s) - 1; i >= 0 && i >= len(s)-2 && s[i] == '='; i-- {
+		n--
+	}
+	return n
+}
+
+// latencyTracker measures the latencies reported to Metrics.
+type latencyTracker struct {
+	mu            sync.Mutex
+	speechStopped time.Time
+	responses     map[string]*responseLatency
+}
+
+type responseLatency struct {
+	created time.Time
+	text    bool
+}
+
+// metricsEnabled reports whether the measurements should be taken.
+func (c *Conn) metricsEnabled() bool {
+	if c.metrics == nil {
+		return false
+	}
+	_, nop := c.metrics.(NopMetrics)
+	return !nop
+}
+
+func (c *Conn) observeSent(msg ClientEvent) {
+	if !c.metricsEnabled() {
+		return
+	}
+	c.metrics.EventSent(msg.ClientEventType())
+	switch e := msg.(type) {
+	case InputAudioBufferAppendEvent:
+		c.metrics.AudioSent(base64DecodedLen(e.Audio))
+	case *InputAudioBufferAppendEvent:
+		c.metrics.AudioSent(base64DecodedLen(e.Audio))
+	}
+}
+
+func (c *Conn) observeReceived(event ServerEvent) {
+	if !c.metricsEnabled() {
+		return
+	}
+	now := time.Now()
+	c.metrics.EventReceived(event.ServerEventType())
+
+	t := &c.latency
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch e := event.(type) {
+	case ErrorEvent:
+		c.metrics.Error(e.Error.Code)
+	case InputAudioBufferSpeechStoppedEvent:
+		t.speechStopped = now
+	case ResponseCreatedEvent:
+		if t.responses == nil {
+			t.responses = make(map[string]*responseLatency)
+		}
+		t.responses[e.Response.ID] = &responseLatency{created: now}
+	case ResponseDoneEvent:
+		delete(t.responses, e.Response.ID)
+	case ResponseOutputAudioDeltaEvent:
+		c.metrics.AudioReceived(base64DecodedLen(e.Delta))
+		if !t.speechStopped.IsZero() {
+			c.metrics.SpeechToFirstAudio(now.Sub(t.speechStopped))
+			t.speechStopped = time.Time{}
+		}
+	case ResponseOutputTextDeltaEvent:
+		t.firstText(c.metrics, e.ResponseID, now)
+	case ResponseOutputAudioTranscriptDeltaEvent:
+		t.firstText(c.metrics, e.ResponseID, now)
+	}
+}
+
+func (t *latencyTracker) firstText(metrics Metrics, responseID string, now time.Time) {
+	r, ok := t.responses[responseID]
+	if !ok || r.text {
+		return
+	}
+	r.text = true
+	metrics.TimeToFirstText(now.Sub(r.created))
+}
@@ -0,0 +1,150 @@
+package openairt_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMetrics struct {
+	mu                 sync.Mutex
+	sent               []openairt.ClientEventType
+	received           []openairt.ServerEventType
+	audioSent          int
+	audioReceived      int
+	speechToFirstAudio []time.Duration
+	timeToFirstText    []time.Duration
+	errors             []string
+	reconnects         int
+	dropped            []openairt.ServerEventType
+}
+
+func (m *recordingMetrics) EventSent(t openairt.ClientEventType) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, t)
+}
+
+func (m *recordingMetrics) EventReceived(t openairt.ServerEventType) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.received = append(m.received, t)
+}
+
+func (m *recordingMetrics) AudioSent(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.audioSent += n
+}
+
+func (m *recordingMetrics) AudioReceived(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.audioReceived += n
+}
+
+func (m *recordingMetrics) SpeechToFirstAudio(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.speechToFirstAudio = append(m.speechToFirstAudio, d)
+}
+
+func (m *recordingMetrics) TimeToFirstText(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timeToFirstText = append(m.timeToFirstText, d)
+}
+
+func (m *recordingMetrics) Error(code string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors = append(m.errors, code)
+}
+
+func (m *recordingMetrics) Reconnect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reconnects++
+}
+
+func (m *recordingMetrics) EventDropped(t openairt.ServerEventType) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dropped = append(m.dropped, t)
+}
+
+// newScriptedConn connects a Conn to a mock connection which returns the messages in order.
+func newScriptedConn(t *testing.T, messages []string, opts ...openairt.ConnectOption) *openairt.Conn {
+	t.Helper()
+	var mu sync.Mutex
+	dialer := &mockDialer{
+		dialFunc: func(_ context.Context, _ string, _ http.Header) (openairt.WebSocketConn, error) {
+			return &mockWebSocketConn{
+				readMessageFunc: func(ctx context.Context) (openairt.MessageType, []byte, error) {
+					mu.Lock()
+					if len(messages) == 0 {
+						mu.Unlock()
+						<-ctx.Done()
+						return 0, nil, openairt.Permanent(ctx.Err())
+					}
+					msg := messages[0]
+					messages = messages[1:]
+					mu.Unlock()
+					return openairt.MessageText, []byte(msg), nil
+				},
+				writeMessageFunc: func(_ context.Context, _ openairt.MessageType, _ []byte) error {
+					return nil
+				},
+				closeFunc: func() error { return nil },
+			}, nil
+		},
+	}
+	opts = append([]openairt.ConnectOption{openairt.WithDialer(dialer)}, opts...)
+	conn, err := openairt.NewClient("token").Connect(context.Background(), opts...)
+	require.NoError(t, err)
+	return conn
+}
+
+func TestConnMetrics(t *testing.T) {
+	metrics := &recordingMetrics{}
+	conn := newScriptedConn(t, []string{
+		`{"type":"input_audio_buffer.speech_stopped","audio_end_ms":1000,"item_id":"item_1"}`,
+		`{"type":"response.created","response":{"id":"resp_1"}}`,
+		`{"type":"response.output_audio_transcript.delta","response_id":"resp_1","delta":"Hel"}`,
+		`{"type":"response.output_audio_transcript.delta","response_id":"resp_1","delta":"lo"}`,
+		`{"type":"response.output_audio.delta","response_id":"resp_1","delta":"AAECAw=="}`,
+		`{"type":"response.output_audio.delta","response_id":"resp_1","delta":"AAEC"}`,
+		`{"type":"response.done","response":{"id":"resp_1"}}`,
+		`{"type":"error","error":{"code":"invalid_value"}}`,
+	}, openairt.WithMetrics(metrics))
+
+	ctx := context.Background()
+	err := conn.SendMessage(ctx, openairt.InputAudioBufferAppendEvent{Audio: "AAECAwQ="})
+	require.NoError(t, err)
+	err = conn.SendMessage(ctx, &openairt.InputAudioBufferAppendEvent{Audio: "AAECAwQF"})
+	require.NoError(t, err)
+	err = conn.SendMessage(ctx, openairt.InputAudioBufferCommitEvent{})
+	require.NoError(t, err)
+	for i := 0; i < 8; i++ {
+		_, err = conn.ReadMessage(ctx)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, []openairt.ClientEventType{
+		openairt.ClientEventTypeInputAudioBufferAppend,
+		openairt.ClientEventTypeInputAudioBufferAppend,
+		openairt.ClientEventTypeInputAudioBufferCommit,
+	}, metrics.sent)
+	require.Len(t, metrics.received, 8)
+	require.Equal(t, openairt.ServerEventTypeError, metrics.received[7])
+	require.Equal(t, 11, metrics.audioSent)
+	require.Equal(t, 7, metrics.audioReceived)
+	require.Len(t, metrics.speechToFirstAudio, 1)
+	require.Len(t, metrics.timeToFirstText, 1)
+	require.Equal(t, []string{"invalid_value"}, metrics.errors)
+}
@@ -0,0 +1,72 @@
+package openairt
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Middleware wraps the handlers of a ConnHandler, see ConnHandler.Use.
+type Middleware func(next ServerEventHandler) ServerEventHandler
+
+// RecoverMiddleware recovers the panics of the handlers, so that the event is skipped instead of
+// crashing the process. onPanic, if not nil, is called with the event and the recovered value from
+// the deferred function, where debug.Stack still returns the stack of the panic.
+func RecoverMiddleware(onPanic func(event ServerEvent, recovered any)) Middleware {
+	return func(next ServerEventHandler) ServerEventHandler {
+		return func(ctx context.Context, event ServerEvent) {
+			defer func() {
+				if r := recover(); r != nil && onPanic != nil {
+					onPanic(event, r)
+				}
+			}()
+			next(ctx, event)
+		}
+	}
+}
+
+// TimingMiddleware calls observe with the time the handlers took for each event.
+func TimingMiddleware(observe func(eventType ServerEventType, d time.Duration)) Middleware {
+	return func(next ServerEventHandler) ServerEventHandler {
+		return func(ctx context.Context, event ServerEvent) {
+			start := time.Now()
+			next(ctx, event)
+			observe(event.ServerEventType(), time.Since(start))
+		}
+	}
+}
+
+// FilterMiddleware passes only the events matching match to the handlers.
+func FilterMiddleware(match func(ServerEvent) bool) Middleware {
+	return func(next ServerEventHandler) ServerEventHandler {
+		return func(ctx context.Context, event ServerEvent) {
+			if match(event) {
+				next(ctx, event)
+			}
+		}
+	}
+}
+
+// SampleMiddleware passes one in every events of each of the types to the handlers, starting with the first.
+// The events of the other types are all passed.
+func SampleMiddleware(every int, types ...ServerEventType) Middleware {
+	var mu sync.Mutex
+	counts := make(map[ServerEventType]int, len(types))
+	for _, t := range types {
+		counts[t] = 0
+	}
+	return func(next ServerEventHandler) ServerEventHandler {
+		return func(ctx context.Context, event ServerEvent) {
+			eventType := event.ServerEventType()
+			mu.Lock()
+			count, sampled := counts[eventType]
+			if sampled {
+				counts[eventType] = count + 1
+			}
+			mu.Unlock()
+			if !sampled || every <= 1 || count%every == 0 {
+				next(ctx, event)
+			}
+		}
+	}
+}
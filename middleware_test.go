@@ -0,0 +1,150 @@
+package openairt_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnHandlerMiddleware(t *testing.T) {
+	conn, server := newFakeServer(t, nil, nil)
+	var mu sync.Mutex
+	var handled, panics []string
+	var timed []openairt.ServerEventType
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := openairt.NewConnHandler(ctx, conn, func(_ context.Context, event openairt.ServerEvent) {
+		if event.ServerEventType() == openairt.ServerEventTypeError {
+			panic("boom")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if delta, ok := event.(openairt.ResponseOutputAudioTranscriptDeltaEvent); ok {
+			handled = append(handled, delta.Delta)
+		} else {
+			handled = append(handled, string(event.ServerEventType()))
+		}
+	})
+	handler.Use(
+		openairt.RecoverMiddleware(func(event openairt.ServerEvent, recovered any) {
+			mu.Lock()
+			defer mu.Unlock()
+			panics = append(panics, string(event.ServerEventType())+": "+recovered.(string))
+		}),
+		openairt.TimingMiddleware(func(eventType openairt.ServerEventType, _ time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			timed = append(timed, eventType)
+		}),
+		openairt.FilterMiddleware(func(event openairt.ServerEvent) bool {
+			return event.ServerEventType() != openairt.ServerEventTypeRateLimitsUpdated
+		}),
+		openairt.SampleMiddleware(2, openairt.ServerEventTypeResponseOutputAudioTranscriptDelta),
+	)
+	handler.Start()
+
+	server.push(
+		`{"type":"error","error":{"type":"server_error","message":"oops"}}`,
+		transcriptDelta("resp_1", "item_1", "a"),
+		`{"type":"rate_limits.updated","rate_limits":[]}`,
+		transcriptDelta("resp_1", "item_1", "b"),
+		transcriptDelta("resp_1", "item_1", "c"),
+		`{"type":"input_audio_buffer.cleared"}`,
+	)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(timed) == 5
+	}, time.Second, time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{"error: boom"}, panics)
+	require.Equal(t, []string{"a", "c", "input_audio_buffer.cleared"}, handled)
+	// The timing doesn't include the panicking event, which unwinds through it.
+	require.NotContains(t, timed, openairt.ServerEventTypeError)
+}
+
+func TestConnHandlerAsyncDispatch(t *testing.T) {
+	conn, server := newFakeServer(t, nil, nil)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	deltas := make(map[string][]string)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := openairt.NewConnHandler(ctx, conn, func(_ context.Context, event openairt.ServerEvent) {
+		if event.ServerEventType() == openairt.ServerEventTypeInputAudioBufferCleared {
+			<-release
+			return
+		}
+		if delta, ok := event.(openairt.ResponseOutputAudioTranscriptDeltaEvent); ok {
+			mu.Lock()
+			deltas[delta.ResponseID] = append(deltas[delta.ResponseID], delta.Delta)
+			mu.Unlock()
+		}
+	})
+	handler.SetDispatch(openairt.DispatchOptions{Workers: 4})
+	handler.Start()
+
+	// A slow handler doesn't stall the reads.
+	server.push(`{"type":"input_audio_buffer.cleared"}`, sessionCreated)
+	require.Eventually(t, func() bool {
+		_, ok := conn.Session()
+		return ok
+	}, time.Second, time.Millisecond)
+
+	// The events of each response are handled in order.
+	var want []string
+	for i := 0; i < 20; i++ {
+		want = append(want, string(rune('a'+i)))
+		for _, id := range []string{"resp_1", "resp_2", "resp_3"} {
+			server.push(transcriptDelta(id, "item_"+id, string(rune('a'+i))))
+		}
+	}
+	close(release)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(deltas["resp_1"])+len(deltas["resp_2"])+len(deltas["resp_3"]) == 3*len(want)
+	}, time.Second, time.Millisecond)
+	require.NoError(t, handler.Stop())
+	for _, id := range []string{"resp_1", "resp_2", "resp_3"} {
+		require.Equal(t, want, deltas[id], id)
+	}
+}
+
+func TestConnHandlerDispatchOverflow(t *testing.T) {
+	metrics := &recordingMetrics{}
+	conn, server := newFakeServer(t, nil, nil, openairt.WithMetrics(metrics))
+	release := make(chan struct{})
+	var mu sync.Mutex
+	handled := 0
+	handler := openairt.NewConnHandler(context.Background(), conn, func(_ context.Context, _ openairt.ServerEvent) {
+		<-release
+		mu.Lock()
+		handled++
+		mu.Unlock()
+	})
+	handler.SetDispatch(openairt.DispatchOptions{Workers: 1, QueueSize: 1, Overflow: openairt.OverflowDropNewest})
+	handler.Start()
+
+	for i := 0; i < 4; i++ {
+		server.push(transcriptDelta("resp_1", "item_1", "a"))
+	}
+	dropped := func() int {
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+		return len(metrics.dropped)
+	}
+	require.Eventually(t, func() bool { return dropped() >= 2 }, time.Second, time.Millisecond)
+	close(release)
+	require.NoError(t, handler.Stop())
+	require.Equal(t, 4, handled+dropped())
+	metrics.mu.Lock()
+	require.Equal(t, openairt.ServerEventTypeResponseOutputAudioTranscriptDelta, metrics.dropped[0])
+	metrics.mu.Unlock()
+}
@@ -0,0 +1,84 @@
+package openairt
+
+import "encoding/json"
+
+// Optional is a field that could be unset, explicitly null, or set to a value.
+//
+// The API clears some session fields, such as turn_detection, when they are set to null,
+// which can't be expressed with an omitempty pointer. The zero value (nil) is unset and omitted
+// from the JSON when the field is tagged with omitempty; NullOptional marshals to null;
+// NewOptional marshals to the value.
+//
+// It's implemented as a map so that omitempty works with encoding/json, use the methods
+// instead of indexing it.
+type Optional[T any] map[bool]T
+
+// NewOptional returns an Optional set to v.
+func NewOptional[T any](v T) Optional[T] {
+	return Optional[T]{true: v}
+}
+
+// NullOptional returns an Optional set to null.
+func NullOptional[T any]() Optional[T] {
+	var zero T
+	return Optional[T]{false: zero}
+}
+
+// IsSet reports whether the field is set, either to null or a value.
+func (o Optional[T]) IsSet() bool {
+	return len(o) > 0
+}
+
+// IsNull reports whether the field is set to null.
+func (o Optional[T]) IsNull() bool {
+	_, ok := o[false]
+	return ok
+}
+
+// Get returns the value and true if the field is set to a value, or the zero value and false otherwise.
+func (o Optional[T]) Get() (T, bool) {
+	v, ok := o[true]
+	return v, ok
+}
+
+// Value returns the value, or the zero value if the field is unset or null.
+func (o Optional[T]) Value() T {
+	return o[true]
+}
+
+// Set sets the field to v.
+func (o *Optional[T]) Set(v T) {
+	*o = NewOptional(v)
+}
+
+// SetNull sets the field to null.
+func (o *Optional[T]) SetNull() {
+	*o = NullOptional[T]()
+}
+
+// Unset unsets the field.
+func (o *Optional[T]) Unset() {
+	*o = nil
+}
+
+// MarshalJSON marshals the value, or null if the field is unset or null.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if v, ok := o.Get(); ok {
+		return json.Marshal(v)
+	}
+	return []byte(nullString), nil
+}
+
+// UnmarshalJSON sets the field to null or the value.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if isNull(data) {
+		o.SetNull()
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	o.Set(v)
+	return nil
+}
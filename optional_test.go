@@ -0,0 +1,159 @@
+package openairt_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptional(t *testing.T) {
+	var o openairt.Optional[int]
+	require.False(t, o.IsSet())
+	require.False(t, o.IsNull())
+	_, ok := o.Get()
+	require.False(t, ok)
+
+	o.SetNull()
+	require.True(t, o.IsSet())
+	require.True(t, o.IsNull())
+	_, ok = o.Get()
+	require.False(t, ok)
+
+	o.Set(3)
+	require.True(t, o.IsSet())
+	require.False(t, o.IsNull())
+	v, ok := o.Get()
+	require.True(t, ok)
+	require.Equal(t, 3, v)
+	require.Equal(t, 3, o.Value())
+
+	o.Unset()
+	require.False(t, o.IsSet())
+	require.Equal(t, 0, o.Value())
+}
+
+func TestOptionalSessionFieldsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		session openairt.RealtimeSession
+		json    string
+	}{
+		{
+			name: "unset",
+			session: openairt.RealtimeSession{
+				Audio: &openairt.RealtimeSessionAudio{Input: &openairt.SessionAudioInput{}},
+			},
+			json: `{"type":"realtime","audio":{"input":{}}}`,
+		},
+		{
+			name: "null",
+			session: openairt.RealtimeSession{
+				Audio: &openairt.RealtimeSessionAudio{Input: &openairt.SessionAudioInput{
+					NoiseReduction: openairt.NullOptional[openairt.AudioNoiseReduction](),
+					TurnDetection:  openairt.NullOptional[openairt.TurnDetectionUnion](),
+					Transcription:  openairt.NullOptional[openairt.AudioTranscription](),
+				}},
+				Tracing: openairt.NullOptional[openairt.TracingUnion](),
+			},
+			json: `{"type":"realtime","audio":{"input":{"noise_reduction":null,"turn_detection":null,"transcription":null}},"tracing":null}`,
+		},
+		{
+			name: "value",
+			session: openairt.RealtimeSession{
+				Audio: &openairt.RealtimeSessionAudio{Input: &openairt.SessionAudioInput{
+					NoiseReduction: openairt.NewOptional(openairt.AudioNoiseReduction{Type: openairt.NoiseReductionFarField}),
+					TurnDetection: openairt.NewOptional(openairt.TurnDetectionUnion{
+						SemanticVad: &openairt.RealtimeSessionSemanticVad{Eagerness: "low"},
+					}),
+					Transcription: openairt.NewOptional(openairt.AudioTranscription{Model: openairt.Whisper1}),
+				}},
+				Tracing: openairt.NewOptional(openairt.TracingUnion{
+					Configuration: &openairt.TracingConfiguration{WorkflowName: "support"},
+				}),
+			},
+			json: `{"type":"realtime","audio":{"input":{` +
+				`"noise_reduction":{"type":"far_field"},` +
+				`"turn_detection":{"eagerness":"low","type":"semantic_vad"},` +
+				`"transcription":{"model":"whisper-1"}}},` +
+				`"tracing":{"workflow_name":"support"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.session)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.json, string(data))
+
+			var session openairt.RealtimeSession
+			require.NoError(t, json.Unmarshal(data, &session))
+			require.Equal(t, tt.session, session)
+		})
+	}
+}
+
+func TestSessionUpdateEventClearTurnDetection(t *testing.T) {
+	event := openairt.SessionUpdateEvent{
+		Session: openairt.SessionUnion{
+			Transcription: &openairt.TranscriptionSession{
+				Audio: &openairt.TranscriptionSessionAudio{Input: &openairt.SessionAudioInput{
+					TurnDetection: openairt.NullOptional[openairt.TurnDetectionUnion](),
+				}},
+			},
+		},
+	}
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	require.JSONEq(t,
+		`{"type":"session.update","session":{"type":"transcription","audio":{"input":{"turn_detection":null}}}}`,
+		string(data))
+}
+
+func TestTracingUnionJSON(t *testing.T) {
+	tests := []struct {
+		tracing openairt.TracingUnion
+		json    string
+	}{
+		{openairt.TracingUnion{Mode: openairt.TracingModeAuto}, `"auto"`},
+		{
+			openairt.TracingUnion{Configuration: &openairt.TracingConfiguration{GroupID: "g", WorkflowName: "w"}},
+			`{"group_id":"g","workflow_name":"w"}`,
+		},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.tracing)
+		require.NoError(t, err)
+		require.JSONEq(t, tt.json, string(data))
+
+		var tracing openairt.TracingUnion
+		require.NoError(t, json.Unmarshal(data, &tracing))
+		require.Equal(t, tt.tracing, tracing)
+	}
+
+	_, err := json.Marshal(openairt.TracingUnion{})
+	require.Error(t, err)
+}
+
+func TestTruncationUnionJSON(t *testing.T) {
+	tests := []struct {
+		truncation openairt.TruncationUnion
+		json       string
+	}{
+		{openairt.TruncationUnion{Strategy: openairt.TruncationStrategyAuto}, `"auto"`},
+		{openairt.TruncationUnion{Strategy: openairt.TruncationStrategyDisabled}, `"disabled"`},
+		{
+			openairt.TruncationUnion{RetentionRatioTruncation: &openairt.RetentionRatioTruncation{Ratio: 0.5}},
+			`{"type":"retention_ratio","retention_ratio":0.5}`,
+		},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.truncation)
+		require.NoError(t, err)
+		require.JSONEq(t, tt.json, string(data))
+
+		var truncation openairt.TruncationUnion
+		require.NoError(t, json.Unmarshal(data, &truncation))
+		require.Equal(t, tt.truncation, truncation)
+	}
+}
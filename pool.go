@@ -0,0 +1,485 @@
+package openairt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConnPoolRefreshBefore  = time.Minute
+	defaultConnPoolHealthInterval = 30 * time.Second
+	defaultConnPoolPingTimeout    = 5 * time.Second
+	defaultConnPoolWarmTimeout    = 30 * time.Second
+)
+
+// ErrConnPoolClosed is returned by ConnPool.Get after the pool is closed.
+var ErrConnPoolClosed = errors.New("connection pool is closed")
+
+// ConnPoolOptions configures a ConnPool.
+type ConnPoolOptions struct {
+	// Size is the number of idle connections kept warm. Default is 1.
+	Size int
+
+	// ConnectOptions are passed to Client.Connect for each connection, e.g. WithModel.
+	ConnectOptions []ConnectOption
+
+	// Session is applied with ApplySession to each connection once session.created is read.
+	// The server defaults are kept if it's empty.
+	Session SessionUnion
+
+	// RefreshBefore replaces the idle connections this long before their session expires. Default is 1 minute.
+	RefreshBefore time.Duration
+
+	// HealthInterval is the interval at which the idle connections are pinged. Default is 30 seconds.
+	HealthInterval time.Duration
+
+	// PingTimeout bounds each ping, the connection is discarded if it fails. Default is 5 seconds.
+	PingTimeout time.Duration
+
+	// WarmTimeout bounds connecting and configuring a connection in the background. Default is 30 seconds.
+	WarmTimeout time.Duration
+
+	// OnError is called with the errors of the connections warmed in the background.
+	// The failed connections are retried at the next health check.
+	OnError func(err error)
+}
+
+// ConnPool keeps connections connected and configured, so that they are ready at call pickup.
+//
+// Use one pool per model and session configuration. The pool reads the messages of the idle
+// connections: session.created and the session.updated of the configuration are consumed,
+// use Conn.Session to get the effective session. The idle connections are replaced before their
+// session expires, pinged every HealthInterval, and discarded once their connection drops.
+type ConnPool struct {
+	client *Client
+	opts   ConnPoolOptions
+	ctx    context.Context
+	cancel context.CancelFunc
+	wake   chan struct{}
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	idle    []*pooledConn
+	warming int
+	// redials is the number of dead connections not replaced yet.
+	redials int
+	closed  bool
+}
+
+// pooledConn is an idle connection of a ConnPool, whose messages are read by drain until it's handed out.
+type pooledConn struct {
+	conn      *Conn
+	expiresAt time.Time
+	cancel    context.CancelFunc
+	created   chan struct{}
+	done      chan struct{}
+	err       error
+}
+
+// NewConnPool creates a ConnPool connecting with client, and starts warming its connections.
+// Close must be called to release them.
+func NewConnPool(client *Client, opts ConnPoolOptions) *ConnPool {
+	if opts.Size <= 0 {
+		opts.Size = 1
+	}
+	if opts.RefreshBefore <= 0 {
+		opts.RefreshBefore = defaultConnPoolRefreshBefore
+	}
+	if opts.HealthInterval <= 0 {
+		opts.HealthInterval = defaultConnPoolHealthInterval
+	}
+	if opts.PingTimeout <= 0 {
+		opts.PingTimeout = defaultConnPoolPingTimeout
+	}
+	if opts.WarmTimeout <= 0 {
+		opts.WarmTimeout = defaultConnPoolWarmTimeout
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &ConnPool{
+		client: client,
+		opts:   opts,
+		ctx:    ctx,
+		cancel: cancel,
+		wake:   make(chan struct{}, 1),
+	}
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+// Get returns a warm connection, or connects and configures a new one if none is idle.
+//
+// The connection belongs to the caller, who must read its messages, e.g. with a ConnHandler, and close it.
+func (p *ConnPool) Get(ctx context.Context) (*Conn, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrConnPoolClosed
+		}
+		if len(p.idle) == 0 {
+			p.mu.Unlock()
+			break
+		}
+		e := p.idle[0]
+		p.idle = p.idle[1:]
+		p.mu.Unlock()
+		p.wakeUp()
+
+		e.cancel()
+		<-e.done
+		if !e.conn.reader.dead() && !p.expiring(e, time.Now()) {
+			return e.conn, nil
+		}
+		p.discard(e)
+	}
+
+	e, err := p.warm(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e.cancel()
+	<-e.done
+	p.wakeUp()
+	return e.conn, nil
+}
+
+// Len returns the number of idle connections.
+func (p *ConnPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle)
+}
+
+// Close closes the idle connections and waits for the background work to stop.
+// The connections handed out by Get are left open.
+func (p *ConnPool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	p.cancel()
+	for _, e := range idle {
+		p.discard(e)
+	}
+	p.wg.Wait()
+	return nil
+}
+
+func (p *ConnPool) wakeUp() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *ConnPool) run() {
+	defer p.wg.Done()
+	nextPing := time.Now().Add(p.opts.HealthInterval)
+	for {
+		now := time.Now()
+		if !now.Before(nextPing) {
+			p.ping()
+			nextPing = now.Add(p.opts.HealthInterval)
+		}
+		wait := p.maintain(now)
+		if untilPing := nextPing.Sub(now); untilPing < wait {
+			wait = untilPing
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-p.ctx.Done():
+			timer.Stop()
+			return
+		case <-p.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// maintain discards the dropped and expiring idle connections, starts warming the missing ones,
+// and returns the time until the next idle connection has to be refreshed.
+func (p *ConnPool) maintain(now time.Time) time.Duration {
+	wait := p.opts.HealthInterval
+	var discarded, dropped []*pooledConn
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return wait
+	}
+	kept := p.idle[:0]
+	for _, e := range p.idle {
+		switch {
+		case e.conn.reader.dead():
+			dropped = append(dropped, e)
+		case p.expiring(e, now):
+			discarded = append(discarded, e)
+		default:
+			kept = append(kept, e)
+			if !e.expiresAt.IsZero() {
+				if refresh := e.expiresAt.Add(-p.opts.RefreshBefore).Sub(now); refresh < wait {
+					wait = refresh
+				}
+			}
+		}
+	}
+	p.idle = kept
+	p.redials += len(dropped)
+	missing := p.opts.Size - len(p.idle) - p.warming
+	if missing > 0 {
+		p.warming += missing
+	}
+	p.mu.Unlock()
+
+	for _, e := range dropped {
+		p.discard(e)
+	}
+	for _, e := range discarded {
+		p.discard(e)
+	}
+	for i := 0; i < missing; i++ {
+		p.wg.Add(1)
+		go p.refill()
+	}
+	return wait
+}
+
+// ping pings the idle connections, and discards the ones which don't answer.
+func (p *ConnPool) ping() {
+	p.mu.Lock()
+	idle := append([]*pooledConn(nil), p.idle...)
+	p.mu.Unlock()
+
+	for _, e := range idle {
+		ctx, cancel := context.WithTimeout(p.ctx, p.opts.PingTimeout)
+		err := e.conn.Ping(ctx)
+		cancel()
+		if err == nil || p.ctx.Err() != nil {
+			continue
+		}
+		if p.remove(e) {
+			p.mu.Lock()
+			p.redials++
+			p.mu.Unlock()
+			p.discard(e)
+		}
+	}
+}
+
+// remove removes e from the idle connections, and reports whether it was still idle.
+func (p *ConnPool) remove(e *pooledConn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.idle {
+		if p.idle[i] == e {
+			p.idle = append(p.idle[:i], p.idle[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ConnPool) refill() {
+	defer p.wg.Done()
+	ctx, cancel := context.WithTimeout(p.ctx, p.opts.WarmTimeout)
+	e, err := p.warm(ctx)
+	cancel()
+
+	p.mu.Lock()
+	p.warming--
+	closed := p.closed
+	redial := false
+	if err == nil && !closed {
+		p.idle = append(p.idle, e)
+		if p.redials > 0 {
+			p.redials--
+			redial = true
+		}
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		if p.ctx.Err() == nil && p.opts.OnError != nil {
+			p.opts.OnError(err)
+		}
+		return
+	}
+	if closed {
+		p.discard(e)
+		return
+	}
+	if redial {
+		e.conn.metrics.Reconnect()
+	}
+	p.wakeUp()
+}
+
+// warm connects and configures a connection, whose messages are read by drain until it's handed out.
+func (p *ConnPool) warm(ctx context.Context) (*pooledConn, error) {
+	conn, err := p.client.Connect(ctx, p.opts.ConnectOptions...)
+	if err != nil {
+		return nil, err
+	}
+	conn.reader = startConnReader(conn.conn)
+
+	drainCtx, cancel := context.WithCancel(context.Background())
+	e := &pooledConn{
+		conn:    conn,
+		cancel:  cancel,
+		created: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go p.drain(drainCtx, e)
+
+	select {
+	case <-e.created:
+	case <-e.done:
+		p.discard(e)
+		return nil, e.err
+	case <-ctx.Done():
+		p.discard(e)
+		return nil, ctx.Err()
+	}
+
+	session, _ := conn.Session()
+	e.expiresAt = sessionExpiresAt(session)
+	if p.opts.Session.Realtime != nil || p.opts.Session.Transcription != nil {
+		if _, err := conn.ApplySession(ctx, p.opts.Session); err != nil {
+			p.discard(e)
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// drain reads the messages of an idle connection until ctx is done or the connection drops.
+func (p *ConnPool) drain(ctx context.Context, e *pooledConn) {
+	defer close(e.done)
+	created := false
+	for {
+		event, err := e.conn.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if e.conn.reader.dead() {
+				e.err = err
+				p.wakeUp()
+				return
+			}
+			e.conn.logger.Warnf("read message temporary error: %+v", err)
+			continue
+		}
+		if _, ok := event.(SessionCreatedEvent); ok && !created {
+			created = true
+			close(e.created)
+		}
+	}
+}
+
+func (p *ConnPool) expiring(e *pooledConn, now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt.Add(-p.opts.RefreshBefore))
+}
+
+func (p *ConnPool) discard(e *pooledConn) {
+	e.cancel()
+	_ = e.conn.Close()
+	<-e.done
+}
+
+func sessionExpiresAt(session SessionUnion) time.Time {
+	var expiresAt int64
+	switch {
+	case session.Realtime != nil:
+		expiresAt = session.Realtime.ExpiresAt
+	case session.Transcription != nil:
+		expiresAt = session.Transcription.ExpiresAt
+	}
+	if expiresAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(expiresAt, 0)
+}
+
+// connReader reads the messages of a WebSocketConn in its own goroutine, so that a read can be
+// cancelled without closing the connection. It's used by the pooled connections, which are read
+// by the pool while idle and by their owner once handed out.
+type connReader struct {
+	messages chan connMessage
+	cancel   context.CancelFunc
+	done     chan struct{}
+	err      error
+}
+
+type connMessage struct {
+	messageType MessageType
+	data        []byte
+	err         error
+}
+
+func startConnReader(conn WebSocketConn) *connReader {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &connReader{
+		messages: make(chan connMessage),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(r.done)
+		for {
+			messageType, data, err := conn.ReadMessage(ctx)
+			var permanent *PermanentError
+			if err != nil && (errors.As(err, &permanent) || ctx.Err() != nil) {
+				if permanent == nil {
+					err = Permanent(err)
+				}
+				r.err = err
+				return
+			}
+			select {
+			case r.messages <- connMessage{messageType: messageType, data: data, err: err}:
+			case <-ctx.Done():
+				r.err = Permanent(ctx.Err())
+				return
+			}
+		}
+	}()
+	return r
+}
+
+// read returns the next message. Cancelling ctx doesn't affect the connection.
+func (r *connReader) read(ctx context.Context) (MessageType, []byte, error) {
+	select {
+	case msg := <-r.messages:
+		return msg.messageType, msg.data, msg.err
+	case <-r.done:
+		return 0, nil, r.err
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	}
+}
+
+func (r *connReader) dead() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *connReader) stop() {
+	r.cancel()
+	<-r.done
+}
@@ -0,0 +1,213 @@
+package openairt_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// poolServer is a mock dialer whose connections send session.created, and reply to session.update
+// with session.updated.
+type poolServer struct {
+	mu        sync.Mutex
+	conns     []*poolServerConn
+	expiresAt time.Time
+	pingErr   error
+}
+
+type poolServerConn struct {
+	messages chan []byte
+	closed   chan struct{}
+	once     sync.Once
+	mu       sync.Mutex
+	sent     []string
+}
+
+func (s *poolServer) Dial(_ context.Context, _ string, _ http.Header) (openairt.WebSocketConn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := &poolServerConn{messages: make(chan []byte, 16), closed: make(chan struct{})}
+	c.messages <- []byte(fmt.Sprintf(`{"type":"session.created","session":{
+		"type":"realtime","id":"sess_%d","model":"gpt-realtime","expires_at":%d}}`,
+		len(s.conns), s.expiresAt.Unix()))
+	s.conns = append(s.conns, c)
+	return &mockWebSocketConn{
+		readMessageFunc: func(ctx context.Context) (openairt.MessageType, []byte, error) {
+			select {
+			case <-ctx.Done():
+				return 0, nil, openairt.Permanent(ctx.Err())
+			case <-c.closed:
+				return 0, nil, openairt.Permanent(net.ErrClosed)
+			case msg := <-c.messages:
+				return openairt.MessageText, msg, nil
+			}
+		},
+		writeMessageFunc: func(_ context.Context, _ openairt.MessageType, data []byte) error {
+			var event map[string]any
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			c.mu.Lock()
+			c.sent = append(c.sent, event["type"].(string))
+			c.mu.Unlock()
+			for _, msg := range sessionUpdated(event) {
+				c.messages <- []byte(msg)
+			}
+			return nil
+		},
+		closeFunc: func() error {
+			c.drop()
+			return nil
+		},
+		pingFunc: func(_ context.Context) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.pingErr
+		},
+	}, nil
+}
+
+func (s *poolServer) dials() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+func (s *poolServer) conn(i int) *poolServerConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns[i]
+}
+
+func (c *poolServerConn) drop() {
+	c.once.Do(func() { close(c.closed) })
+}
+
+func (c *poolServerConn) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *poolServerConn) sentTypes() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.sent...)
+}
+
+func TestConnPool(t *testing.T) {
+	server := &poolServer{expiresAt: time.Now().Add(time.Hour)}
+	pool := openairt.NewConnPool(openairt.NewClient("token"), openairt.ConnPoolOptions{
+		Size:           2,
+		ConnectOptions: []openairt.ConnectOption{openairt.WithDialer(server)},
+		Session:        openairt.SessionUnion{Realtime: &openairt.RealtimeSession{Instructions: "be brief"}},
+	})
+	defer pool.Close()
+	require.Eventually(t, func() bool { return pool.Len() == 2 }, time.Second, time.Millisecond)
+	require.Equal(t, 2, server.dials())
+	require.Equal(t, []string{"session.update"}, server.conn(0).sentTypes())
+
+	conn, err := pool.Get(context.Background())
+	require.NoError(t, err)
+	session, ok := conn.Session()
+	require.True(t, ok)
+	require.Equal(t, "be brief", session.Realtime.Instructions)
+
+	// The pool is refilled, and the messages of the handed out connection go to its owner.
+	require.Eventually(t, func() bool { return pool.Len() == 2 }, time.Second, time.Millisecond)
+	require.Equal(t, 3, server.dials())
+	events := make(chan openairt.ServerEvent, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	openairt.NewConnHandler(ctx, conn, func(_ context.Context, event openairt.ServerEvent) {
+		events <- event
+	}).Start()
+	// The handed out connection is either of the first two.
+	for i := 0; i < 2; i++ {
+		server.conn(i).messages <- []byte(`{"type":"input_audio_buffer.cleared","event_id":"event_2"}`)
+	}
+	select {
+	case event := <-events:
+		require.Equal(t, openairt.ServerEventTypeInputAudioBufferCleared, event.ServerEventType())
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+
+	require.NoError(t, pool.Close())
+	// The idle connections are closed, the handed out one is left open.
+	var open int
+	for i := 0; i < 3; i++ {
+		if !server.conn(i).isClosed() {
+			open++
+		}
+	}
+	require.Equal(t, 1, open)
+	_, err = pool.Get(context.Background())
+	require.ErrorIs(t, err, openairt.ErrConnPoolClosed)
+}
+
+func TestConnPoolReplace(t *testing.T) {
+	server := &poolServer{expiresAt: time.Now().Add(time.Hour)}
+	metrics := &recordingMetrics{}
+	pool := openairt.NewConnPool(openairt.NewClient("token"), openairt.ConnPoolOptions{
+		ConnectOptions: []openairt.ConnectOption{openairt.WithDialer(server), openairt.WithMetrics(metrics)},
+		HealthInterval: 10 * time.Millisecond,
+	})
+	defer pool.Close()
+	require.Eventually(t, func() bool { return pool.Len() == 1 }, time.Second, time.Millisecond)
+	require.Empty(t, server.conn(0).sentTypes())
+
+	// A connection dropped while idle is replaced.
+	server.conn(0).drop()
+	require.Eventually(t, func() bool { return server.dials() == 2 && pool.Len() == 1 }, time.Second, time.Millisecond)
+
+	// A connection which doesn't answer the pings is replaced.
+	server.mu.Lock()
+	server.pingErr = errors.New("timeout")
+	server.mu.Unlock()
+	require.Eventually(t, func() bool { return server.dials() >= 3 }, time.Second, time.Millisecond)
+	require.Eventually(t, server.conn(1).isClosed, time.Second, time.Millisecond)
+	server.mu.Lock()
+	server.pingErr = nil
+	server.mu.Unlock()
+
+	// Each dead connection is counted once its replacement is dialed.
+	require.Eventually(t, func() bool {
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+		return pool.Len() == 1 && metrics.reconnects == server.dials()-1
+	}, time.Second, time.Millisecond)
+}
+
+func TestConnPoolExpiry(t *testing.T) {
+	server := &poolServer{expiresAt: time.Now().Add(time.Minute + 1500*time.Millisecond)}
+	metrics := &recordingMetrics{}
+	pool := openairt.NewConnPool(openairt.NewClient("token"), openairt.ConnPoolOptions{
+		ConnectOptions: []openairt.ConnectOption{openairt.WithDialer(server), openairt.WithMetrics(metrics)},
+	})
+	defer pool.Close()
+	require.Eventually(t, func() bool { return pool.Len() == 1 }, time.Second, time.Millisecond)
+
+	// The connection is replaced a minute before its session expires.
+	server.mu.Lock()
+	server.expiresAt = time.Now().Add(time.Hour)
+	server.mu.Unlock()
+	require.Eventually(t, func() bool { return server.dials() == 2 && pool.Len() == 1 }, 3*time.Second, time.Millisecond)
+	require.True(t, server.conn(0).isClosed())
+	// Replacing a live connection isn't a reconnect.
+	metrics.mu.Lock()
+	require.Zero(t, metrics.reconnects)
+	metrics.mu.Unlock()
+}
@@ -0,0 +1,139 @@
+package openairt
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// sensitiveHeaders are the headers carrying credentials.
+func sensitiveHeaders() []string {
+	return []string{"Authorization", "Api-Key", "Cookie", "Openai-Beta-Secret"}
+}
+
+// RedactHeaders returns a copy of the headers with the credentials redacted.
+func RedactHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, key := range sensitiveHeaders() {
+		if _, ok := redacted[key]; ok {
+			redacted.Set(key, "[redacted]")
+		}
+	}
+	return redacted
+}
+
+// RedactPayload returns the JSON event with base64 audio, inline images and files,
+// and MCP credentials replaced by a placeholder, so that it could be logged.
+// Data that is not valid JSON is replaced entirely.
+func RedactPayload(data []byte) []byte {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return []byte(fmt.Sprintf("%q", redactedBytes(len(data))))
+	}
+	redacted, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return []byte(fmt.Sprintf("%q", redactedBytes(len(data))))
+	}
+	return redacted
+}
+
+func redactedBytes(n int) string {
+	return fmt.Sprintf("[redacted %d bytes]", n)
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		return redactObject(v)
+	case []any:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func redactObject(obj map[string]any) map[string]any {
+	typ, _ := obj["type"].(string)
+	for key, value := range obj {
+		s, isString := value.(string)
+		switch {
+		case key == "audio" && isString:
+			obj[key] = redactedBytes(base64DecodedLen(s))
+		case key == "delta" && isString && typ == string(ServerEventTypeResponseOutputAudioDelta):
+			obj[key] = redactedBytes(base64DecodedLen(s))
+		case (key == "image_url" || key == "file_data") && isString && strings.HasPrefix(s, "data:"):
+			obj[key] = redactedBytes(len(s))
+		case key == "authorization" && isString && typ == string(ToolTypeMCP):
+			obj[key] = "[redacted]"
+		case key == "headers" && typ == string(ToolTypeMCP):
+			if headers, ok := value.(map[string]any); ok {
+				for k := range headers {
+					headers[k] = "[redacted]"
+				}
+			}
+		default:
+			obj[key] = redactValue(value)
+		}
+	}
+	return obj
+}
+
+// eventLogIDs are the identifiers of an event worth logging.
+type eventLogIDs struct {
+	Type           string `json:"type"`
+	EventID        string `json:"event_id"`
+	ItemID         string `json:"item_id"`
+	PreviousItemID string `json:"previous_item_id"`
+	ResponseID     string `json:"response_id"`
+	CallID         string `json:"call_id"`
+	Item           *struct {
+		ID string `json:"id"`
+	} `json:"item"`
+	Response *struct {
+		ID string `json:"id"`
+	} `json:"response"`
+	Session *struct {
+		ID string `json:"id"`
+	} `json:"session"`
+	Error *struct {
+		Code    string `json:"code"`
+		EventID string `json:"event_id"`
+	} `json:"error"`
+}
+
+// eventLogAttrs returns the type and identifiers of the JSON event as key-value pairs.
+func eventLogAttrs(data []byte) []any {
+	var ids eventLogIDs
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return []any{"bytes", len(data)}
+	}
+	if ids.Item != nil && ids.ItemID == "" {
+		ids.ItemID = ids.Item.ID
+	}
+	if ids.Response != nil && ids.ResponseID == "" {
+		ids.ResponseID = ids.Response.ID
+	}
+	kv := []any{"type", ids.Type}
+	add := func(key, value string) {
+		if value != "" {
+			kv = append(kv, key, value)
+		}
+	}
+	add("event_id", ids.EventID)
+	if ids.Session != nil {
+		add("session_id", ids.Session.ID)
+	}
+	add("item_id", ids.ItemID)
+	add("previous_item_id", ids.PreviousItemID)
+	add("response_id", ids.ResponseID)
+	add("call_id", ids.CallID)
+	if ids.Error != nil {
+		add("error_code", ids.Error.Code)
+		add("error_event_id", ids.Error.EventID)
+	}
+	return append(kv, "bytes", len(data))
+}
@@ -0,0 +1,147 @@
+package openairt
+
+import (
+	"context"
+	"sync"
+)
+
+// responseCoordinator serializes the responses created by the components sharing a Conn,
+// e.g. MCPApprover, MCPBridge, AgentRunner and OutputGuardrails, since the server refuses
+// to create a response while another one is in progress. The out-of-band responses, which
+// the server may run in parallel, are serialized as well.
+//
+// A response requested while one is in progress, or being created, is created once no response
+// is in progress. The requests to resume the conversation made meanwhile are merged into one,
+// so that components resuming after the same response.done create a single response.
+type responseCoordinator struct {
+	mu     sync.Mutex
+	active map[string]bool
+	// creating is the request whose response.create is sent and not answered yet, if any.
+	creating *responseRequest
+	queue    []*responseRequest
+}
+
+type responseRequest struct {
+	ctx    context.Context
+	event  ResponseCreateEvent
+	resume bool
+	// report reports the error of a deferred request.
+	report func(err error)
+}
+
+// busy reports whether a response is in progress or being created. It must be called with mu held.
+func (r *responseCoordinator) busy() bool {
+	return r.creating != nil || len(r.active) > 0
+}
+
+// resumePending reports whether a request to resume is queued or being created. It must be called with mu held.
+func (r *responseCoordinator) resumePending() bool {
+	if r.creating != nil && r.creating.resume {
+		return true
+	}
+	for _, q := range r.queue {
+		if q.resume {
+			return true
+		}
+	}
+	return false
+}
+
+// next pops the next request to send, if no response is in progress. It must be called with mu held.
+func (r *responseCoordinator) next() *responseRequest {
+	if r.busy() || len(r.queue) == 0 {
+		return nil
+	}
+	req := r.queue[0]
+	r.queue = r.queue[1:]
+	r.creating = req
+	return req
+}
+
+// observe tracks the responses in progress, and returns the request which failed to create one, if any,
+// and the next request to send.
+func (r *responseCoordinator) observe(event ServerEvent) (failed, next *responseRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch e := event.(type) {
+	case ResponseCreatedEvent:
+		if r.active == nil {
+			r.active = make(map[string]bool)
+		}
+		r.active[e.Response.ID] = true
+		// The server answers in order, the response being created is this one or is created next.
+		r.creating = nil
+	case ResponseDoneEvent:
+		delete(r.active, e.Response.ID)
+	case ErrorEvent:
+		if r.creating == nil || e.Error.EventID != r.creating.event.EventID {
+			return nil, nil
+		}
+		failed, r.creating = r.creating, nil
+	default:
+		return nil, nil
+	}
+	return failed, r.next()
+}
+
+// resumeResponse creates a response to resume the conversation, merged with the other requests
+// to resume pending on the connection. See createResponse.
+func (c *Conn) resumeResponse(ctx context.Context, report func(err error)) error {
+	return c.requestResponse(&responseRequest{ctx: ctx, resume: true, report: report})
+}
+
+// createResponse sends the response.create event, or defers it until no response is in progress
+// on the connection. The error of sending it right away is returned, the errors of sending it
+// once deferred, or of the server refusing it, are passed to report.
+func (c *Conn) createResponse(ctx context.Context, event ResponseCreateEvent, report func(err error)) error {
+	return c.requestResponse(&responseRequest{ctx: ctx, event: event, report: report})
+}
+
+func (c *Conn) requestResponse(req *responseRequest) error {
+	if req.event.EventID == "" {
+		req.event.EventID = GenerateID("event_", eventIDLength)
+	}
+	r := &c.responses
+	r.mu.Lock()
+	if r.busy() {
+		if !req.resume || !r.resumePending() {
+			r.queue = append(r.queue, req)
+		}
+		r.mu.Unlock()
+		return nil
+	}
+	r.creating = req
+	r.mu.Unlock()
+
+	err := c.SendMessage(req.ctx, req.event)
+	if err != nil {
+		c.sendDeferredResponses(c.responseFailed(req))
+	}
+	return err
+}
+
+// sendDeferredResponses sends the deferred requests, starting with req, until one is sent.
+func (c *Conn) sendDeferredResponses(req *responseRequest) {
+	for req != nil {
+		err := c.SendMessage(req.ctx, req.event)
+		if err == nil {
+			return
+		}
+		if req.report != nil {
+			req.report(err)
+		}
+		req = c.responseFailed(req)
+	}
+}
+
+// responseFailed forgets the request whose response.create couldn't be sent,
+// and returns the next request to send, if any.
+func (c *Conn) responseFailed(req *responseRequest) *responseRequest {
+	r := &c.responses
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.creating == req {
+		r.creating = nil
+	}
+	return r.next()
+}
@@ -0,0 +1,84 @@
+package openairt_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnCoordinatesResponses(t *testing.T) {
+	conn, server := newFakeServer(t, nil, nil)
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	onError := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}
+	decided := make(chan struct{}, 2)
+	approver := openairt.NewMCPApprover(conn, openairt.MCPApproverOptions{
+		Policies:   []openairt.MCPApprovalPolicy{openairt.AllowMCPTools()},
+		OnDecision: func(openairt.MCPApprovalRecord) { decided <- struct{}{} },
+		OnError:    onError,
+	})
+	guardrails := openairt.NewOutputGuardrails(conn, openairt.OutputGuardrailsOptions{
+		Guardrails:     []openairt.OutputGuardrail{openairt.BlockPhrases("secret")},
+		SafeReply:      "Sorry.",
+		SpeakSafeReply: true,
+		OnError:        onError,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	openairt.NewConnHandler(ctx, conn, guardrails.HandleEvent, approver.HandleEvent).Start()
+
+	creates := func(n int) []map[string]any {
+		t.Helper()
+		require.Eventually(t, func() bool { return len(sentOfType(server, "response.create")) >= n }, time.Second, time.Millisecond)
+		// Leave time to the duplicates, if any.
+		time.Sleep(20 * time.Millisecond)
+		sent := sentOfType(server, "response.create")
+		require.Len(t, sent, n)
+		return sent
+	}
+
+	// The response requests an approval and trips the guardrail: the safe reply is created
+	// once it's done, the response to resume once the safe reply is done.
+	server.push(
+		`{"type":"response.created","response":{"id":"resp_1","status":"in_progress"}}`,
+		approvalRequestDone("resp_1", "req_1", "docs", "search", `{}`),
+		transcriptDelta("resp_1", "item_1", "the secret"),
+	)
+	<-decided
+	server.push(responseDoneEvent("resp_1", 10, 10))
+	sent := creates(1)
+	require.Equal(t, "none", sent[0]["response"].(map[string]any)["conversation"]) //nolint:errcheck
+	server.push(
+		`{"type":"response.created","response":{"id":"resp_2","status":"in_progress"}}`,
+		responseDoneEvent("resp_2", 10, 10),
+	)
+	sent = creates(2)
+	require.NotContains(t, sent[1]["response"], "conversation")
+
+	// The requests to resume made while one is being created are merged.
+	server.push(approvalRequestDone("resp_3", "req_2", "docs", "search", `{}`))
+	<-decided
+	server.push(responseDoneEvent("resp_3", 10, 10))
+	creates(2)
+
+	// The server refusing a deferred response is reported.
+	server.push(`{"type":"error","error":{"type":"invalid_request_error",` +
+		`"code":"conversation_already_has_active_response","event_id":"` + sent[1]["event_id"].(string) + `"}}`) //nolint:errcheck
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) == 1
+	}, time.Second, time.Millisecond)
+	var serverErr *openairt.ServerError
+	require.ErrorAs(t, errs[0], &serverErr)
+}
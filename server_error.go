@@ -0,0 +1,15 @@
+package openairt
+
+import "fmt"
+
+// ServerError is an error event returned by the server in reply to a client event.
+type ServerError struct {
+	Detail Error
+}
+
+func (e *ServerError) Error() string {
+	if e.Detail.Code == "" {
+		return fmt.Sprintf("server error: %s: %s", e.Detail.Type, e.Detail.Message)
+	}
+	return fmt.Sprintf("server error: %s: %s", e.Detail.Code, e.Detail.Message)
+}
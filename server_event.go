@@ -691,15 +691,20 @@ func unmarshalServerEvent[T ServerEventInterface](data []byte) (T, error) {
 }
 
 // UnmarshalServerEvent unmarshals the server event from the given JSON data.
-func UnmarshalServerEvent(data []byte) (ServerEvent, error) { //nolint:funlen,cyclop,gocyclo // TODO: optimize
-	var eventType struct {
-		Type ServerEventType `json:"type"`
+// The type is found with a single scan of the top-level fields, so that the data is parsed once.
+// The data is not retained and may be reused after it returns.
+func UnmarshalServerEvent(data []byte) (ServerEvent, error) { //nolint:funlen,cyclop,gocyclo // one case per event type
+	eventType, ok := scanServerEventType(data)
+	if !ok {
+		var typed struct {
+			Type ServerEventType `json:"type"`
+		}
+		if err := json.Unmarshal(data, &typed); err != nil {
+			return nil, err
+		}
+		eventType = typed.Type
 	}
-	err := json.Unmarshal(data, &eventType)
-	if err != nil {
-		return nil, err
-	}
-	switch eventType.Type {
+	switch eventType {
 	case ServerEventTypeError:
 		return unmarshalServerEvent[ErrorEvent](data)
 
@@ -782,6 +787,9 @@ func UnmarshalServerEvent(data []byte) (ServerEvent, error) { //nolint:funlen,cy
 		return unmarshalServerEvent[ResponseOutputAudioTranscriptDoneEvent](data)
 
 	case ServerEventTypeResponseOutputAudioDelta:
+		if event, ok := decodeAudioDelta(data); ok {
+			return event, nil
+		}
 		return unmarshalServerEvent[ResponseOutputAudioDeltaEvent](data)
 
 	case ServerEventTypeResponseOutputAudioDone:
@@ -822,6 +830,6 @@ func UnmarshalServerEvent(data []byte) (ServerEvent, error) { //nolint:funlen,cy
 
 	default:
 		// This should never happen.
-		return nil, fmt.Errorf("unknown server event type: %s", eventType.Type)
+		return nil, fmt.Errorf("unknown server event type: %s", eventType)
 	}
 }
@@ -0,0 +1,293 @@
+package openairt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrSessionUnknown is returned by ApplySession before the session.created event is read.
+	ErrSessionUnknown = errors.New("session is not created yet")
+
+	// ErrSessionTypeChange is returned by ApplySession when the session type differs from the current one.
+	ErrSessionTypeChange = errors.New("session type can't be changed")
+
+	// ErrModelChange is returned by ApplySession when the model differs from the current one.
+	ErrModelChange = errors.New("model can't be changed")
+
+	// ErrVoiceChange is returned by ApplySession when the voice is changed after the model responded with audio.
+	ErrVoiceChange = errors.New("voice can't be changed after the model responded with audio")
+
+	// ErrTracingChange is returned by ApplySession when the tracing is changed after it is enabled.
+	ErrTracingChange = errors.New("tracing can't be changed once enabled")
+)
+
+const eventIDLength = 32
+
+// sessionState mirrors the effective session configuration reported by the server.
+type sessionState struct {
+	mu          sync.Mutex
+	session     *SessionUnion
+	audioOutput bool
+	pending     []*pendingSessionUpdate
+}
+
+type pendingSessionUpdate struct {
+	eventID string
+	done    chan sessionUpdateResult
+}
+
+type sessionUpdateResult struct {
+	session SessionUnion
+	err     error
+}
+
+func (s *sessionState) observe(event ServerEvent) {
+	switch e := event.(type) {
+	case SessionCreatedEvent:
+		s.mu.Lock()
+		s.session = &e.Session
+		s.mu.Unlock()
+	case SessionUpdatedEvent:
+		s.mu.Lock()
+		s.session = &e.Session
+		if len(s.pending) > 0 {
+			// session.updated doesn't carry the event_id of the session.update, the server replies in order.
+			s.pending[0].done <- sessionUpdateResult{session: e.Session}
+			s.pending = s.pending[1:]
+		}
+		s.mu.Unlock()
+	case ResponseOutputAudioDeltaEvent:
+		s.mu.Lock()
+		s.audioOutput = true
+		s.mu.Unlock()
+	case ErrorEvent:
+		if e.Error.EventID == "" {
+			return
+		}
+		s.mu.Lock()
+		for i, p := range s.pending {
+			if p.eventID == e.Error.EventID {
+				p.done <- sessionUpdateResult{err: &ServerError{Detail: e.Error}}
+				s.pending = append(s.pending[:i], s.pending[i+1:]...)
+				break
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *sessionState) cancel(p *pendingSessionUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.pending {
+		if s.pending[i] == p {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// Session returns a copy of the latest effective session configuration from session.created
+// or session.updated, and false if none has been read yet.
+func (c *Conn) Session() (SessionUnion, bool) {
+	c.session.mu.Lock()
+	defer c.session.mu.Unlock()
+	if c.session.session == nil {
+		return SessionUnion{}, false
+	}
+	session, err := cloneSession(*c.session.session)
+	if err != nil {
+		return *c.session.session, true
+	}
+	return session, true
+}
+
+// AudioOutputStarted reports whether the model has responded with audio, after which the voice can't be changed.
+func (c *Conn) AudioOutputStarted() bool {
+	c.session.mu.Lock()
+	defer c.session.mu.Unlock()
+	return c.session.audioOutput
+}
+
+// ApplySession sends a session.update with the fields of desired which differ from the current session,
+// and waits for the session.updated confirming it. It returns the current session without sending
+// anything if nothing differs.
+//
+// Fields left unset in desired are kept as is. The read-only fields (id, object, expires_at) are ignored.
+// Changes the API forbids are refused before sending: the session type, the model, the voice after
+// the model responded with audio, and the tracing once enabled.
+//
+// The confirmation is read by ReadMessage, so the messages must be read in another goroutine,
+// e.g. by a ConnHandler. If the server replies with an error event, it's returned as *ServerError.
+func (c *Conn) ApplySession(ctx context.Context, desired SessionUnion) (SessionUnion, error) {
+	c.session.mu.Lock()
+	if c.session.session == nil {
+		c.session.mu.Unlock()
+		return SessionUnion{}, ErrSessionUnknown
+	}
+	current := *c.session.session
+	audioOutput := c.session.audioOutput
+	c.session.mu.Unlock()
+
+	update, err := sessionDiff(current, desired, audioOutput)
+	if err != nil {
+		return SessionUnion{}, err
+	}
+	if update == nil {
+		return cloneSession(current)
+	}
+
+	p := &pendingSessionUpdate{
+		eventID: GenerateID("event_", eventIDLength),
+		done:    make(chan sessionUpdateResult, 1),
+	}
+	c.session.mu.Lock()
+	c.session.pending = append(c.session.pending, p)
+	c.session.mu.Unlock()
+
+	err = c.SendMessage(ctx, SessionUpdateEvent{EventBase: EventBase{EventID: p.eventID}, Session: *update})
+	if err != nil {
+		c.session.cancel(p)
+		return SessionUnion{}, err
+	}
+	select {
+	case <-ctx.Done():
+		c.session.cancel(p)
+		return SessionUnion{}, ctx.Err()
+	case result := <-p.done:
+		return result.session, result.err
+	}
+}
+
+func cloneSession(session SessionUnion) (SessionUnion, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return SessionUnion{}, err
+	}
+	var clone SessionUnion
+	err = json.Unmarshal(data, &clone)
+	return clone, err
+}
+
+// sessionContainers are the objects whose fields are compared one by one,
+// other objects are sent as a whole if any of their fields differs.
+func sessionContainers() map[string]bool {
+	return map[string]bool{
+		"audio":        true,
+		"audio.input":  true,
+		"audio.output": true,
+	}
+}
+
+func sessionReadOnlyFields() map[string]bool {
+	return map[string]bool{
+		"id":         true,
+		"object":     true,
+		"expires_at": true,
+		"type":       true,
+	}
+}
+
+// modelSnapshotSuffix is the date suffix of the model snapshots, e.g. -2025-08-28.
+var modelSnapshotSuffix = regexp.MustCompile(`^-\d{4}-\d{2}-\d{2}$`)
+
+// isModelSnapshot reports whether snapshot is a dated snapshot of the model alias,
+// e.g. gpt-realtime-2025-08-28 of gpt-realtime, but not gpt-realtime-mini-2025-10-06.
+func isModelSnapshot(snapshot, alias string) bool {
+	return strings.HasPrefix(snapshot, alias) && modelSnapshotSuffix.MatchString(snapshot[len(alias):])
+}
+
+// sessionDiff returns the session.update changing current to desired, or nil if nothing differs.
+func sessionDiff(current, desired SessionUnion, audioOutput bool) (*SessionUnion, error) {
+	if (current.Realtime != nil) != (desired.Realtime != nil) ||
+		(current.Transcription != nil) != (desired.Transcription != nil) {
+		return nil, ErrSessionTypeChange
+	}
+	cur, err := toJSONObject(current)
+	if err != nil {
+		return nil, err
+	}
+	des, err := toJSONObject(desired)
+	if err != nil {
+		return nil, err
+	}
+	diff := diffJSONObject(cur, des, "", sessionContainers(), sessionReadOnlyFields())
+	if len(diff) == 0 {
+		return nil, nil //nolint:nilnil // nothing to update
+	}
+
+	if model, ok := diff["model"].(string); ok {
+		currentModel, _ := cur["model"].(string)
+		if currentModel != "" && !isModelSnapshot(currentModel, model) {
+			return nil, fmt.Errorf("%w: %s to %s", ErrModelChange, currentModel, model)
+		}
+		// The desired model is an alias of the current snapshot.
+		delete(diff, "model")
+	}
+	if _, ok := diff["tracing"]; ok && cur["tracing"] != nil {
+		return nil, ErrTracingChange
+	}
+	if audio, ok := diff["audio"].(map[string]any); ok && audioOutput {
+		if output, ok := audio["output"].(map[string]any); ok {
+			if _, ok := output["voice"]; ok {
+				return nil, ErrVoiceChange
+			}
+		}
+	}
+	if len(diff) == 0 {
+		return nil, nil //nolint:nilnil // nothing to update
+	}
+
+	diff["type"] = des["type"]
+	data, err := json.Marshal(diff)
+	if err != nil {
+		return nil, err
+	}
+	var update SessionUnion
+	if err := json.Unmarshal(data, &update); err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
+
+func toJSONObject(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]any
+	err = json.Unmarshal(data, &obj)
+	return obj, err
+}
+
+func diffJSONObject(cur, des map[string]any, path string, containers, skip map[string]bool) map[string]any {
+	diff := make(map[string]any)
+	for key, desValue := range des {
+		fieldPath := joinPath(path, key)
+		if skip[fieldPath] {
+			continue
+		}
+		curValue, ok := cur[key]
+		if containers[fieldPath] {
+			curObj, curOK := curValue.(map[string]any)
+			desObj, desOK := desValue.(map[string]any)
+			if curOK && desOK {
+				if sub := diffJSONObject(curObj, desObj, fieldPath, containers, skip); len(sub) > 0 {
+					diff[key] = sub
+				}
+				continue
+			}
+		}
+		if !ok || !reflect.DeepEqual(curValue, desValue) {
+			diff[key] = desValue
+		}
+	}
+	return diff
+}
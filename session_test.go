@@ -0,0 +1,184 @@
+package openairt_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const sessionCreated = `{"type":"session.created","event_id":"event_1","session":{
+	"type":"realtime","id":"sess_1","object":"realtime.session","model":"gpt-realtime-2025-08-28",
+	"instructions":"be nice","tracing":null,
+	"audio":{
+		"input":{"format":{"type":"audio/pcm","rate":24000},"turn_detection":{"type":"server_vad","threshold":0.5}},
+		"output":{"format":{"type":"audio/pcm","rate":24000},"voice":"alloy"}
+	}
+}}`
+
+// sessionUpdated replies to session.update with a session.updated applying instructions and voice,
+// or with an error if the instructions are "fail".
+func sessionUpdated(event map[string]any) []string {
+	if event["type"] != "session.update" {
+		return nil
+	}
+	session, _ := event["session"].(map[string]any)
+	if session["instructions"] == "fail" {
+		return []string{fmt.Sprintf(
+			`{"type":"error","error":{"type":"invalid_request_error","code":"invalid_value","message":"bad","event_id":%q}}`,
+			event["event_id"])}
+	}
+	instructions := "be nice"
+	if s, ok := session["instructions"].(string); ok {
+		instructions = s
+	}
+	return []string{fmt.Sprintf(`{"type":"session.updated","session":{
+		"type":"realtime","id":"sess_1","model":"gpt-realtime-2025-08-28","instructions":%q,"tracing":"auto",
+		"audio":{"output":{"voice":"alloy"}}}}`, instructions)}
+}
+
+func startSessionConn(t *testing.T) (*openairt.Conn, *fakeServer) {
+	t.Helper()
+	conn, server := newFakeServer(t, sessionUpdated, []string{sessionCreated})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	openairt.NewConnHandler(ctx, conn).Start()
+	require.Eventually(t, func() bool {
+		_, ok := conn.Session()
+		return ok
+	}, time.Second, time.Millisecond)
+	return conn, server
+}
+
+func sentSession(t *testing.T, event map[string]any) string {
+	t.Helper()
+	data, err := json.Marshal(event["session"])
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestConnSession(t *testing.T) {
+	conn, _ := newFakeServer(t, nil, []string{sessionCreated})
+	_, ok := conn.Session()
+	require.False(t, ok)
+	_, err := conn.ApplySession(context.Background(), openairt.SessionUnion{Realtime: &openairt.RealtimeSession{}})
+	require.ErrorIs(t, err, openairt.ErrSessionUnknown)
+
+	_, err = conn.ReadMessage(context.Background())
+	require.NoError(t, err)
+	session, ok := conn.Session()
+	require.True(t, ok)
+	require.Equal(t, "sess_1", session.Realtime.ID)
+	require.Equal(t, openairt.VoiceAlloy, session.Realtime.Audio.Output.Voice)
+	require.True(t, session.Realtime.Tracing.IsNull())
+
+	// The accessor returns a copy.
+	session.Realtime.Instructions = "changed"
+	session, _ = conn.Session()
+	require.Equal(t, "be nice", session.Realtime.Instructions)
+}
+
+func TestConnApplySession(t *testing.T) {
+	conn, server := startSessionConn(t)
+	ctx := context.Background()
+
+	// Unchanged fields, read-only fields and the model alias are not sent.
+	session, err := conn.ApplySession(ctx, openairt.SessionUnion{Realtime: &openairt.RealtimeSession{
+		ID:           "sess_other",
+		Model:        openairt.GPTRealtime,
+		Instructions: "be concise",
+		Audio: &openairt.RealtimeSessionAudio{
+			Input: &openairt.SessionAudioInput{
+				TurnDetection: openairt.NullOptional[openairt.TurnDetectionUnion](),
+			},
+			Output: &openairt.SessionAudioOutput{Voice: openairt.VoiceAlloy},
+		},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, "be concise", session.Realtime.Instructions)
+	events := server.events()
+	require.Len(t, events, 1)
+	require.NotEmpty(t, events[0]["event_id"])
+	require.JSONEq(t,
+		`{"type":"realtime","instructions":"be concise","audio":{"input":{"turn_detection":null}}}`,
+		sentSession(t, events[0]))
+
+	current, ok := conn.Session()
+	require.True(t, ok)
+	require.Equal(t, "be concise", current.Realtime.Instructions)
+
+	// Nothing differs, nothing is sent.
+	_, err = conn.ApplySession(ctx, openairt.SessionUnion{Realtime: &openairt.RealtimeSession{Instructions: "be concise"}})
+	require.NoError(t, err)
+	require.Len(t, server.events(), 1)
+
+	// The server rejects the update.
+	_, err = conn.ApplySession(ctx, openairt.SessionUnion{Realtime: &openairt.RealtimeSession{Instructions: "fail"}})
+	var serverErr *openairt.ServerError
+	require.True(t, errors.As(err, &serverErr))
+	require.Equal(t, "invalid_value", serverErr.Detail.Code)
+}
+
+func TestConnApplySessionForbiddenChanges(t *testing.T) {
+	conn, server := startSessionConn(t)
+	ctx := context.Background()
+
+	_, err := conn.ApplySession(ctx, openairt.SessionUnion{Realtime: &openairt.RealtimeSession{Model: openairt.GPTRealtimeMini}})
+	require.ErrorIs(t, err, openairt.ErrModelChange)
+
+	_, err = conn.ApplySession(ctx, openairt.SessionUnion{Transcription: &openairt.TranscriptionSession{}})
+	require.ErrorIs(t, err, openairt.ErrSessionTypeChange)
+
+	// A model sharing the prefix of the alias isn't one of its snapshots.
+	mini, _ := newFakeServer(t, sessionUpdated, []string{
+		strings.Replace(sessionCreated, "gpt-realtime-2025-08-28", "gpt-realtime-mini-2025-10-06", 1),
+	})
+	miniCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	openairt.NewConnHandler(miniCtx, mini).Start()
+	require.Eventually(t, func() bool {
+		_, ok := mini.Session()
+		return ok
+	}, time.Second, time.Millisecond)
+	_, err = mini.ApplySession(ctx, openairt.SessionUnion{Realtime: &openairt.RealtimeSession{Model: openairt.GPTRealtime}})
+	require.ErrorIs(t, err, openairt.ErrModelChange)
+	_, err = mini.ApplySession(ctx, openairt.SessionUnion{Realtime: &openairt.RealtimeSession{Model: openairt.GPTRealtimeMini}})
+	require.NoError(t, err)
+
+	// The voice could be changed until the model responds with audio.
+	voice := func(v openairt.Voice) openairt.SessionUnion {
+		return openairt.SessionUnion{Realtime: &openairt.RealtimeSession{
+			Audio: &openairt.RealtimeSessionAudio{Output: &openairt.SessionAudioOutput{Voice: v}},
+		}}
+	}
+	_, err = conn.ApplySession(ctx, voice(openairt.VoiceMarin))
+	require.NoError(t, err)
+	server.push(`{"type":"response.output_audio.delta","response_id":"resp_1","delta":"AAAA"}`)
+	require.Eventually(t, conn.AudioOutputStarted, time.Second, time.Millisecond)
+	_, err = conn.ApplySession(ctx, voice(openairt.VoiceCedar))
+	require.ErrorIs(t, err, openairt.ErrVoiceChange)
+
+	// The tracing is enabled by the session.updated of the previous update.
+	_, err = conn.ApplySession(ctx, openairt.SessionUnion{Realtime: &openairt.RealtimeSession{
+		Tracing: openairt.NullOptional[openairt.TracingUnion](),
+	}})
+	require.ErrorIs(t, err, openairt.ErrTracingChange)
+	require.Len(t, server.events(), 1)
+}
+
+func TestConnApplySessionContextDone(t *testing.T) {
+	conn, _ := newFakeServer(t, nil, []string{sessionCreated})
+	_, err := conn.ReadMessage(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = conn.ApplySession(ctx, openairt.SessionUnion{Realtime: &openairt.RealtimeSession{Instructions: "x"}})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
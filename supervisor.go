@@ -0,0 +1,435 @@
+package openairt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRestartBackoff    = time.Second
+	defaultRestartMaxBackoff = 30 * time.Second
+	supervisedIDLength       = 24
+)
+
+var (
+	// ErrSupervisorClosed is returned by Supervisor.Start after Shutdown.
+	ErrSupervisorClosed = errors.New("supervisor is shut down")
+
+	// ErrSupervisorFull is returned by Supervisor.Start when MaxSessions sessions are running.
+	ErrSupervisorFull = errors.New("supervisor is running the maximum number of sessions")
+
+	// ErrDuplicateSession is returned by Supervisor.Start when a session with the same ID is running.
+	ErrDuplicateSession = errors.New("session ID is already running")
+)
+
+// SupervisedSessionState is the state of a SupervisedSession.
+type SupervisedSessionState string
+
+const (
+	SupervisedSessionStateConnecting SupervisedSessionState = "connecting"
+	SupervisedSessionStateRunning    SupervisedSessionState = "running"
+	SupervisedSessionStateRestarting SupervisedSessionState = "restarting"
+	SupervisedSessionStateStopping   SupervisedSessionState = "stopping"
+	SupervisedSessionStateEnded      SupervisedSessionState = "ended"
+)
+
+// RestartPolicy decides whether a session is reconnected after its connection fails.
+// The zero value never restarts.
+type RestartPolicy struct {
+	// MaxRestarts is the number of reconnects over the life of the session. Negative means no limit.
+	MaxRestarts int
+
+	// Backoff is the delay before the first restart, doubled after each restart. Default is 1 second.
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay between restarts. Default is 30 seconds.
+	MaxBackoff time.Duration
+}
+
+// SessionSpec describes a session run by a Supervisor.
+type SessionSpec struct {
+	// ID identifies the session in the supervisor. A random one is generated if empty.
+	ID string
+
+	// Connect returns a new connection, e.g. with Client.Connect or ConnPool.Get.
+	Connect func(ctx context.Context) (*Conn, error)
+
+	// Setup is called with each new connection, before its messages are read, and returns
+	// the handlers of its messages. It's typically used to create the components bound to the connection.
+	Setup func(ctx context.Context, conn *Conn) ([]ServerEventHandler, error)
+
+	// Restart is the restart policy after the connection fails.
+	Restart RestartPolicy
+}
+
+// SessionInfo is a snapshot of a SupervisedSession.
+type SessionInfo struct {
+	ID        string
+	State     SupervisedSessionState
+	StartedAt time.Time
+
+	// SessionID is the ID of the server session of the current connection.
+	SessionID string
+
+	// Restarts is the number of reconnects so far.
+	Restarts int
+
+	// ActiveResponses are the IDs of the responses created and not done yet on the current connection.
+	ActiveResponses []string
+
+	// Err is the last connection error, or the terminal error once ended.
+	Err error
+}
+
+// SupervisorOptions configures a Supervisor.
+type SupervisorOptions struct {
+	// MaxSessions is the number of concurrent sessions. Zero means no limit.
+	MaxSessions int
+
+	// OnSessionEnd is called with the final info of each session once it ended.
+	OnSessionEnd func(info SessionInfo)
+}
+
+// Supervisor owns the lifecycle of many concurrent sessions: each runs with its own context,
+// connection and ConnHandler, is restarted on failure according to its RestartPolicy,
+// and is drained on Shutdown.
+type Supervisor struct {
+	opts     SupervisorOptions
+	mu       sync.Mutex
+	sessions map[string]*SupervisedSession
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// SupervisedSession is a session run by a Supervisor.
+type SupervisedSession struct {
+	spec      SessionSpec
+	sup       *Supervisor
+	ctx       context.Context
+	cancel    context.CancelFunc
+	startedAt time.Time
+	done      chan struct{}
+
+	mu       sync.Mutex
+	state    SupervisedSessionState
+	conn     *Conn
+	handler  *ConnHandler
+	restarts int
+	stopping bool
+	err      error
+}
+
+// NewSupervisor creates a Supervisor.
+func NewSupervisor(opts SupervisorOptions) *Supervisor {
+	return &Supervisor{
+		opts:     opts,
+		sessions: make(map[string]*SupervisedSession),
+	}
+}
+
+// Start starts running a session in the background. The session is stopped when ctx is done.
+func (s *Supervisor) Start(ctx context.Context, spec SessionSpec) (*SupervisedSession, error) {
+	if spec.Connect == nil {
+		return nil, errors.New("session spec has no Connect")
+	}
+	if spec.ID == "" {
+		spec.ID = GenerateID("session_", supervisedIDLength)
+	}
+	if spec.Restart.Backoff <= 0 {
+		spec.Restart.Backoff = defaultRestartBackoff
+	}
+	if spec.Restart.MaxBackoff <= 0 {
+		spec.Restart.MaxBackoff = defaultRestartMaxBackoff
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrSupervisorClosed
+	}
+	if _, ok := s.sessions[spec.ID]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateSession, spec.ID)
+	}
+	if s.opts.MaxSessions > 0 && len(s.sessions) >= s.opts.MaxSessions {
+		return nil, ErrSupervisorFull
+	}
+
+	sessionCtx, cancel := context.WithCancel(ctx)
+	session := &SupervisedSession{
+		spec:      spec,
+		sup:       s,
+		ctx:       sessionCtx,
+		cancel:    cancel,
+		startedAt: time.Now(),
+		done:      make(chan struct{}),
+		state:     SupervisedSessionStateConnecting,
+	}
+	s.sessions[spec.ID] = session
+	s.wg.Add(1)
+	go session.run()
+	return session, nil
+}
+
+// Session returns the running session with the ID.
+func (s *Supervisor) Session(id string) (*SupervisedSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	return session, ok
+}
+
+// Sessions returns the info of the running sessions, sorted by start time.
+func (s *Supervisor) Sessions() []SessionInfo {
+	s.mu.Lock()
+	sessions := make([]*SupervisedSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	s.mu.Unlock()
+
+	infos := make([]SessionInfo, len(sessions))
+	for i, session := range sessions {
+		infos[i] = session.Info()
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].StartedAt.Before(infos[j].StartedAt)
+	})
+	return infos
+}
+
+// Len returns the number of running sessions.
+func (s *Supervisor) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// Shutdown stops accepting sessions and stops the running ones gracefully, see SupervisedSession.Stop.
+// It returns ctx.Err() if some sessions had to be closed before their responses were done.
+func (s *Supervisor) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	sessions := make([]*SupervisedSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(sessions))
+	for _, session := range sessions {
+		wg.Add(1)
+		go func(session *SupervisedSession) {
+			defer wg.Done()
+			if err := session.Stop(ctx); err != nil {
+				errs <- err
+			}
+		}(session)
+	}
+	wg.Wait()
+	s.wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// ID returns the ID of the session in the supervisor.
+func (s *SupervisedSession) ID() string {
+	return s.spec.ID
+}
+
+// Conn returns the current connection, or nil while connecting.
+func (s *SupervisedSession) Conn() *Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn
+}
+
+// Done returns a channel closed once the session ended.
+func (s *SupervisedSession) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the terminal error of the session once ended: nil if it was stopped,
+// or the last connection error if it was not restarted.
+func (s *SupervisedSession) Err() error {
+	select {
+	case <-s.done:
+	default:
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Info returns a snapshot of the session.
+func (s *SupervisedSession) Info() SessionInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := SessionInfo{
+		ID:        s.spec.ID,
+		State:     s.state,
+		StartedAt: s.startedAt,
+		Restarts:  s.restarts,
+		Err:       s.err,
+	}
+	if s.conn != nil {
+		if session, ok := s.conn.Session(); ok {
+			switch {
+			case session.Realtime != nil:
+				info.SessionID = session.Realtime.ID
+			case session.Transcription != nil:
+				info.SessionID = session.Transcription.ID
+			}
+		}
+	}
+	if s.handler != nil {
+		info.ActiveResponses = s.handler.ActiveResponses()
+	}
+	return info
+}
+
+// Stop stops the session gracefully: it's not restarted anymore, the responses in progress are
+// waited for until ctx is done, then the connection is closed. It returns ctx.Err() if the connection
+// was closed before the responses were done.
+func (s *SupervisedSession) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if s.state != SupervisedSessionStateEnded {
+		s.stopping = true
+		s.state = SupervisedSessionStateStopping
+	}
+	handler := s.handler
+	s.mu.Unlock()
+
+	var err error
+	if handler != nil {
+		// The close errors are ignored, only the responses cut short are reported.
+		if shutdownErr := handler.Shutdown(ctx, ShutdownOptions{WaitResponse: true}); shutdownErr != nil {
+			err = ctx.Err()
+		}
+	}
+	s.cancel()
+	<-s.done
+	return err
+}
+
+func (s *SupervisedSession) run() {
+	defer s.sup.wg.Done()
+	defer s.end()
+
+	backoff := s.spec.Restart.Backoff
+	for {
+		err := s.runConn()
+		if s.stopped() {
+			return
+		}
+
+		s.mu.Lock()
+		s.err = err
+		s.conn = nil
+		s.handler = nil
+		restart := s.spec.Restart.MaxRestarts < 0 || s.restarts < s.spec.Restart.MaxRestarts
+		if restart {
+			s.state = SupervisedSessionStateRestarting
+		}
+		s.mu.Unlock()
+		if !restart {
+			return
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > s.spec.Restart.MaxBackoff {
+			backoff = s.spec.Restart.MaxBackoff
+		}
+
+		s.mu.Lock()
+		s.restarts++
+		s.state = SupervisedSessionStateConnecting
+		s.mu.Unlock()
+	}
+}
+
+// runConn connects, sets up and reads a connection until it fails.
+func (s *SupervisedSession) runConn() error {
+	conn, err := s.spec.Connect(s.ctx)
+	if err != nil {
+		return err
+	}
+	var handlers []ServerEventHandler
+	if s.spec.Setup != nil {
+		handlers, err = s.spec.Setup(s.ctx, conn)
+		if err != nil {
+			_ = conn.Close()
+			return err
+		}
+	}
+
+	// The handler is started under the lock, so that Stop either sees it started or
+	// runConn sees the session stopping.
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		_ = conn.Close()
+		return nil
+	}
+	handler := NewConnHandler(s.ctx, conn, handlers...)
+	handler.Start()
+	s.conn = conn
+	s.handler = handler
+	s.state = SupervisedSessionStateRunning
+	restarted := s.restarts > 0
+	s.mu.Unlock()
+	if restarted {
+		conn.metrics.Reconnect()
+	}
+
+	err = handler.Wait()
+	_ = conn.Close()
+	if err == nil {
+		err = errors.New("connection handler stopped")
+	}
+	return err
+}
+
+func (s *SupervisedSession) stopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopping || s.ctx.Err() != nil
+}
+
+func (s *SupervisedSession) end() {
+	s.mu.Lock()
+	if s.stopping {
+		s.err = nil
+	} else if err := s.ctx.Err(); err != nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.cancel()
+
+	s.mu.Lock()
+	s.state = SupervisedSessionStateEnded
+	s.conn = nil
+	s.handler = nil
+	s.mu.Unlock()
+
+	s.sup.mu.Lock()
+	delete(s.sup.sessions, s.spec.ID)
+	s.sup.mu.Unlock()
+	close(s.done)
+
+	if s.sup.opts.OnSessionEnd != nil {
+		s.sup.opts.OnSessionEnd(s.Info())
+	}
+}
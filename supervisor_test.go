@@ -0,0 +1,163 @@
+package openairt_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func supervisedSpec(server *poolServer, id string, opts ...openairt.ConnectOption) openairt.SessionSpec {
+	opts = append([]openairt.ConnectOption{openairt.WithDialer(server)}, opts...)
+	return openairt.SessionSpec{
+		ID: id,
+		Connect: func(ctx context.Context) (*openairt.Conn, error) {
+			return openairt.NewClient("token").Connect(ctx, opts...)
+		},
+	}
+}
+
+func TestSupervisor(t *testing.T) {
+	server := &poolServer{expiresAt: time.Now().Add(time.Hour)}
+	supervisor := openairt.NewSupervisor(openairt.SupervisorOptions{MaxSessions: 2})
+	events := make(chan openairt.ServerEventType, 16)
+	spec := supervisedSpec(server, "call_1")
+	spec.Setup = func(_ context.Context, _ *openairt.Conn) ([]openairt.ServerEventHandler, error) {
+		return []openairt.ServerEventHandler{func(_ context.Context, event openairt.ServerEvent) {
+			events <- event.ServerEventType()
+		}}, nil
+	}
+	session, err := supervisor.Start(context.Background(), spec)
+	require.NoError(t, err)
+	require.Equal(t, "call_1", session.ID())
+	require.Equal(t, openairt.ServerEventTypeSessionCreated, <-events)
+
+	_, err = supervisor.Start(context.Background(), supervisedSpec(server, "call_1"))
+	require.ErrorIs(t, err, openairt.ErrDuplicateSession)
+	ctx, cancel := context.WithCancel(context.Background())
+	second, err := supervisor.Start(ctx, supervisedSpec(server, ""))
+	require.NoError(t, err)
+	_, err = supervisor.Start(context.Background(), supervisedSpec(server, "call_3"))
+	require.ErrorIs(t, err, openairt.ErrSupervisorFull)
+
+	require.Eventually(t, func() bool {
+		infos := supervisor.Sessions()
+		return len(infos) == 2 && infos[1].State == openairt.SupervisedSessionStateRunning && infos[1].SessionID != ""
+	}, time.Second, time.Millisecond)
+	infos := supervisor.Sessions()
+	require.Equal(t, "call_1", infos[0].ID)
+	require.Equal(t, "sess_0", infos[0].SessionID)
+	found, ok := supervisor.Session(second.ID())
+	require.True(t, ok)
+	require.Same(t, second, found)
+
+	// Cancelling the context of a session ends it.
+	cancel()
+	<-second.Done()
+	require.ErrorIs(t, second.Err(), context.Canceled)
+	require.Equal(t, 1, supervisor.Len())
+
+	// Shutdown waits for the response in progress.
+	conn := server.conn(0)
+	conn.messages <- []byte(`{"type":"response.created","response":{"id":"resp_1","status":"in_progress"}}`)
+	require.Eventually(t, func() bool {
+		return len(session.Info().ActiveResponses) == 1
+	}, time.Second, time.Millisecond)
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- supervisor.Shutdown(context.Background()) }()
+	require.Eventually(t, func() bool {
+		return session.Info().State == openairt.SupervisedSessionStateStopping
+	}, time.Second, time.Millisecond)
+	_, err = supervisor.Start(context.Background(), supervisedSpec(server, "call_4"))
+	require.ErrorIs(t, err, openairt.ErrSupervisorClosed)
+	require.False(t, conn.isClosed())
+
+	conn.messages <- []byte(responseDoneEvent("resp_1", 10, 10))
+	require.NoError(t, <-shutdown)
+	require.True(t, conn.isClosed())
+	require.NoError(t, session.Err())
+	require.Equal(t, 0, supervisor.Len())
+}
+
+func TestSupervisorRestart(t *testing.T) {
+	server := &poolServer{expiresAt: time.Now().Add(time.Hour)}
+	ended := make(chan openairt.SessionInfo, 1)
+	supervisor := openairt.NewSupervisor(openairt.SupervisorOptions{
+		OnSessionEnd: func(info openairt.SessionInfo) { ended <- info },
+	})
+	metrics := &recordingMetrics{}
+	spec := supervisedSpec(server, "call_1", openairt.WithMetrics(metrics))
+	spec.Restart = openairt.RestartPolicy{MaxRestarts: 1, Backoff: time.Millisecond}
+	session, err := supervisor.Start(context.Background(), spec)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return session.Conn() != nil }, time.Second, time.Millisecond)
+
+	// The dropped connection is replaced once.
+	server.conn(0).drop()
+	require.Eventually(t, func() bool {
+		info := session.Info()
+		return info.Restarts == 1 && info.State == openairt.SupervisedSessionStateRunning
+	}, time.Second, time.Millisecond)
+	require.Equal(t, 2, server.dials())
+	require.Error(t, session.Info().Err)
+	metrics.mu.Lock()
+	require.Equal(t, 1, metrics.reconnects)
+	metrics.mu.Unlock()
+
+	server.conn(1).drop()
+	info := <-ended
+	require.Equal(t, "call_1", info.ID)
+	require.Equal(t, openairt.SupervisedSessionStateEnded, info.State)
+	require.Error(t, info.Err)
+	require.Error(t, session.Err())
+	require.Equal(t, 0, supervisor.Len())
+}
+
+func TestSupervisorPoolReconnect(t *testing.T) {
+	server := &poolServer{expiresAt: time.Now().Add(time.Hour)}
+	metrics := &recordingMetrics{}
+	pool := openairt.NewConnPool(openairt.NewClient("token"), openairt.ConnPoolOptions{
+		ConnectOptions: []openairt.ConnectOption{openairt.WithDialer(server), openairt.WithMetrics(metrics)},
+	})
+	defer pool.Close()
+	require.Eventually(t, func() bool { return pool.Len() == 1 }, time.Second, time.Millisecond)
+	supervisor := openairt.NewSupervisor(openairt.SupervisorOptions{})
+	session, err := supervisor.Start(context.Background(), openairt.SessionSpec{
+		ID:      "call_1",
+		Connect: pool.Get,
+		Restart: openairt.RestartPolicy{MaxRestarts: 1, Backoff: time.Millisecond},
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return session.Conn() != nil && server.dials() == 2 && pool.Len() == 1
+	}, time.Second, time.Millisecond)
+
+	// The outage of the session is counted once, by the supervisor.
+	server.conn(0).drop()
+	require.Eventually(t, func() bool {
+		return session.Info().Restarts == 1 && server.dials() == 3 && pool.Len() == 1
+	}, time.Second, time.Millisecond)
+	metrics.mu.Lock()
+	require.Equal(t, 1, metrics.reconnects)
+	metrics.mu.Unlock()
+	require.NoError(t, supervisor.Shutdown(context.Background()))
+}
+
+func TestSupervisorShutdownTimeout(t *testing.T) {
+	server := &poolServer{expiresAt: time.Now().Add(time.Hour)}
+	supervisor := openairt.NewSupervisor(openairt.SupervisorOptions{})
+	session, err := supervisor.Start(context.Background(), supervisedSpec(server, "call_1"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return session.Conn() != nil }, time.Second, time.Millisecond)
+	server.conn(0).messages <- []byte(`{"type":"response.created","response":{"id":"resp_1","status":"in_progress"}}`)
+	require.Eventually(t, func() bool {
+		return len(session.Info().ActiveResponses) == 1
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, supervisor.Shutdown(ctx), context.DeadlineExceeded)
+	require.True(t, server.conn(0).isClosed())
+}
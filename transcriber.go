@@ -0,0 +1,466 @@
+package openairt
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTranscriberChunkDuration = 100 * time.Millisecond
+
+	// errorCodeCommitEmpty is the error code of an input_audio_buffer.commit with an empty buffer.
+	errorCodeCommitEmpty = "input_audio_buffer_commit_empty"
+
+	includeTranscriptionLogprobs = "item.input_audio_transcription.logprobs"
+)
+
+// TranscriptionEventType is the type of a TranscriptionEvent.
+type TranscriptionEventType string
+
+const (
+	// TranscriptionEventDelta is an interim text delta of an utterance.
+	TranscriptionEventDelta TranscriptionEventType = "delta"
+	// TranscriptionEventSegment is a segment of an utterance, with timing and speaker label.
+	TranscriptionEventSegment TranscriptionEventType = "segment"
+	// TranscriptionEventCompleted is the final transcript of an utterance.
+	TranscriptionEventCompleted TranscriptionEventType = "completed"
+	// TranscriptionEventFailed is the failure of an utterance.
+	TranscriptionEventFailed TranscriptionEventType = "failed"
+)
+
+// TranscriptionSegment is a segment of an utterance, reported by diarization models.
+type TranscriptionSegment struct {
+	ID      string
+	Speaker string
+	// Start and End of the segment in seconds.
+	Start    float64
+	End      float64
+	Text     string
+	Logprobs []Logprobs
+}
+
+// Utterance is the transcription of an input audio buffer commit, i.e. a user message item.
+type Utterance struct {
+	ItemID         string
+	PreviousItemID string
+
+	// Milliseconds since the session started when speech started and stopped, zero without turn detection.
+	AudioStartMs int64
+	AudioEndMs   int64
+
+	// Text is the transcript so far, it's the final transcript once Final is true.
+	Text     string
+	Segments []TranscriptionSegment
+	Logprobs []Logprobs
+	Usage    *UsageUnion
+
+	// Final reports whether the transcription is completed or failed.
+	Final bool
+	// Err is the *ServerError of a failed transcription.
+	Err error
+}
+
+// TranscriptionEvent is an update of an utterance.
+type TranscriptionEvent struct {
+	Type TranscriptionEventType
+	// Utterance is a snapshot of the utterance after the update.
+	Utterance Utterance
+	// Delta is the text delta of a TranscriptionEventDelta.
+	Delta string
+	// Segment is the segment of a TranscriptionEventSegment.
+	Segment *TranscriptionSegment
+}
+
+// TranscriberOptions configures a Transcriber.
+type TranscriberOptions struct {
+	// Format of the audio read from the reader. Default is 24kHz PCM.
+	Format AudioFormatUnion
+
+	// Transcription configures the model, language and prompt. Default model is gpt-4o-transcribe.
+	Transcription AudioTranscription
+
+	// TurnDetection of the session. Unset keeps the session's, null disables it so that the audio
+	// is only transcribed when committed, e.g. at the end of a file.
+	TurnDetection Optional[TurnDetectionUnion]
+
+	// NoiseReduction of the session. Unset keeps the session's, null disables it.
+	NoiseReduction Optional[AudioNoiseReduction]
+
+	// Logprobs includes the log probabilities of the transcripts.
+	Logprobs bool
+
+	// ChunkDuration is the duration of audio sent in each input_audio_buffer.append. Default is 100ms.
+	ChunkDuration time.Duration
+
+	// Speed of the pacing relative to real time, e.g. 2 sends the audio twice as fast as it plays.
+	// Default is 1. Negative disables the pacing, e.g. for a reader which is already paced like a microphone.
+	Speed float64
+
+	// File commits the input audio buffer at the end of the reader so that the trailing audio
+	// is transcribed, even without turn detection.
+	File bool
+
+	// OnEvent is called with the updates of the utterances. Deltas and segments are delivered
+	// as they arrive, completions and failures are delivered in the order of the utterances.
+	OnEvent func(TranscriptionEvent)
+}
+
+// Transcriber streams audio to a transcription session and collects the utterances.
+//
+// Its HandleEvent must be registered on the ConnHandler of the connection.
+type Transcriber struct {
+	conn *Conn
+	opts TranscriberOptions
+
+	mu         sync.Mutex
+	changed    chan struct{}
+	utterances map[string]*Utterance
+	// order holds the item ids in the order of the commits.
+	order []string
+	// emitted is the number of utterances in order whose completion is delivered.
+	emitted int
+	usage   Usage
+
+	// stopped holds the items ended by the turn detection, whose commits aren't the client's.
+	stopped map[string]struct{}
+
+	commitEventID string
+	commitPending bool
+	commitErr     error
+}
+
+// NewTranscriber creates a Transcriber on conn, which should be connected with WithIntent().
+func NewTranscriber(conn *Conn, opts TranscriberOptions) *Transcriber {
+	if opts.Format.PCM == nil && opts.Format.PCMU == nil && opts.Format.PCMA == nil {
+		opts.Format.PCM = &AudioFormatPCM{Rate: 24000}
+	}
+	if opts.Transcription.Model == "" {
+		opts.Transcription.Model = GPT4oTranscribe
+	}
+	if opts.ChunkDuration <= 0 {
+		opts.ChunkDuration = defaultTranscriberChunkDuration
+	}
+	if opts.Speed == 0 {
+		opts.Speed = 1
+	}
+	return &Transcriber{
+		conn:       conn,
+		opts:       opts,
+		changed:    make(chan struct{}),
+		utterances: make(map[string]*Utterance),
+		stopped:    make(map[string]struct{}),
+	}
+}
+
+// Session returns the transcription session configured by the options.
+func (t *Transcriber) Session() SessionUnion {
+	format := t.opts.Format
+	session := &TranscriptionSession{
+		Audio: &TranscriptionSessionAudio{Input: &SessionAudioInput{
+			Format:         &format,
+			Transcription:  NewOptional(t.opts.Transcription),
+			TurnDetection:  t.opts.TurnDetection,
+			NoiseReduction: t.opts.NoiseReduction,
+		}},
+	}
+	if t.opts.Logprobs {
+		session.Include = []string{includeTranscriptionLogprobs}
+	}
+	return SessionUnion{Transcription: session}
+}
+
+// Transcribe applies the session, streams the audio of r with pacing, and returns the utterances
+// committed while streaming once they are final. In file mode, the trailing audio is committed first.
+func (t *Transcriber) Transcribe(ctx context.Context, r io.Reader) ([]Utterance, error) {
+	if err := t.waitSession(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := t.conn.ApplySession(ctx, t.Session()); err != nil {
+		return nil, err
+	}
+	if err := t.stream(ctx, r); err != nil {
+		return nil, err
+	}
+	if t.opts.File {
+		if err := t.commit(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	t.mu.Lock()
+	ids := append([]string(nil), t.order...)
+	t.mu.Unlock()
+	return t.wait(ctx, ids)
+}
+
+// Utterances returns the utterances in order, including the ones not final yet.
+func (t *Transcriber) Utterances() []Utterance {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	utterances := make([]Utterance, 0, len(t.order))
+	for _, id := range t.order {
+		utterances = append(utterances, t.utterances[id].snapshot())
+	}
+	return utterances
+}
+
+// Usage returns the usage of the completed transcriptions.
+func (t *Transcriber) Usage() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage
+}
+
+// HandleEvent is the ServerEventHandler of the Transcriber.
+func (t *Transcriber) HandleEvent(_ context.Context, event ServerEvent) {
+	var events []TranscriptionEvent
+
+	t.mu.Lock()
+	switch e := event.(type) {
+	case SessionCreatedEvent, SessionUpdatedEvent:
+		t.notify()
+	case InputAudioBufferSpeechStartedEvent:
+		t.utterance(e.ItemID).AudioStartMs = e.AudioStartMs
+	case InputAudioBufferSpeechStoppedEvent:
+		t.utterance(e.ItemID).AudioEndMs = e.AudioEndMs
+		t.stopped[e.ItemID] = struct{}{}
+	case InputAudioBufferCommittedEvent:
+		u := t.utterance(e.ItemID)
+		u.PreviousItemID = e.PreviousItemID
+		if !t.committed(e.ItemID) {
+			t.order = append(t.order, e.ItemID)
+		}
+		// The committed event doesn't identify the commit event of the client, but the commits of
+		// the turn detection follow a speech_stopped of their item, the client's don't.
+		if _, ok := t.stopped[e.ItemID]; ok {
+			delete(t.stopped, e.ItemID)
+		} else {
+			t.commitPending = false
+		}
+		events = t.flush()
+		t.notify()
+	case ConversationItemInputAudioTranscriptionDeltaEvent:
+		u := t.utterance(e.ItemID)
+		u.Text += e.Delta
+		u.Logprobs = append(u.Logprobs, e.Logprobs...)
+		events = append(events, TranscriptionEvent{Type: TranscriptionEventDelta, Utterance: u.snapshot(), Delta: e.Delta})
+	case ConversationItemInputAudioTranscriptionSegmentEvent:
+		u := t.utterance(e.ItemID)
+		segment := TranscriptionSegment{
+			ID:       e.ID,
+			Speaker:  e.Speaker,
+			Start:    e.Start,
+			End:      e.End,
+			Text:     e.Text,
+			Logprobs: e.Logprobs,
+		}
+		u.Segments = append(u.Segments, segment)
+		events = append(events, TranscriptionEvent{Type: TranscriptionEventSegment, Utterance: u.snapshot(), Segment: &segment})
+	case ConversationItemInputAudioTranscriptionCompletedEvent:
+		u := t.utterance(e.ItemID)
+		u.Text = e.Transcript
+		if len(e.Logprobs) > 0 {
+			u.Logprobs = e.Logprobs
+		}
+		u.Usage = e.Usage
+		u.Final = true
+		if e.Usage != nil {
+			if usage, ok := newUsageFromTranscription(*e.Usage); ok {
+				t.usage.Add(usage)
+			}
+		}
+		events = t.flush()
+		t.notify()
+	case ConversationItemInputAudioTranscriptionFailedEvent:
+		u := t.utterance(e.ItemID)
+		u.Err = &ServerError{Detail: e.Error}
+		u.Final = true
+		events = t.flush()
+		t.notify()
+	case ErrorEvent:
+		if t.commitPending && e.Error.EventID != "" && e.Error.EventID == t.commitEventID {
+			t.commitPending = false
+			if e.Error.Code != errorCodeCommitEmpty {
+				t.commitErr = &ServerError{Detail: e.Error}
+			}
+			t.notify()
+		}
+	}
+	t.mu.Unlock()
+
+	if t.opts.OnEvent != nil {
+		for _, e := range events {
+			t.opts.OnEvent(e)
+		}
+	}
+}
+
+// utterance returns the utterance of itemID, creating it if needed. It must be called with t.mu held.
+func (t *Transcriber) utterance(itemID string) *Utterance {
+	u, ok := t.utterances[itemID]
+	if !ok {
+		u = &Utterance{ItemID: itemID}
+		t.utterances[itemID] = u
+	}
+	return u
+}
+
+func (t *Transcriber) committed(itemID string) bool {
+	for _, id := range t.order {
+		if id == itemID {
+			return true
+		}
+	}
+	return false
+}
+
+// flush returns the completions of the final utterances which follow the delivered ones.
+func (t *Transcriber) flush() []TranscriptionEvent {
+	var events []TranscriptionEvent
+	for t.emitted < len(t.order) {
+		u := t.utterances[t.order[t.emitted]]
+		if !u.Final {
+			break
+		}
+		eventType := TranscriptionEventCompleted
+		if u.Err != nil {
+			eventType = TranscriptionEventFailed
+		}
+		events = append(events, TranscriptionEvent{Type: eventType, Utterance: u.snapshot()})
+		t.emitted++
+	}
+	return events
+}
+
+// notify wakes up the waiters. It must be called with t.mu held.
+func (t *Transcriber) notify() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+// waitFor waits until cond, which is called with t.mu held, returns true.
+func (t *Transcriber) waitFor(ctx context.Context, cond func() bool) error {
+	for {
+		t.mu.Lock()
+		ok := cond()
+		changed := t.changed
+		t.mu.Unlock()
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (t *Transcriber) waitSession(ctx context.Context) error {
+	return t.waitFor(ctx, func() bool {
+		_, ok := t.conn.Session()
+		return ok
+	})
+}
+
+func (t *Transcriber) stream(ctx context.Context, r io.Reader) error {
+	bytesPerSecond := audioBytesPerSecond(t.opts.Format)
+	chunkSize := int(int64(bytesPerSecond) * int64(t.opts.ChunkDuration) / int64(time.Second))
+	chunkSize -= chunkSize % 2
+	if chunkSize <= 0 {
+		chunkSize = 2
+	}
+
+	buf := make([]byte, chunkSize)
+	start := time.Now()
+	var sent int64
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if t.opts.Speed > 0 {
+				played := time.Duration(float64(sent) / float64(bytesPerSecond) / t.opts.Speed * float64(time.Second))
+				if err := sleepContext(ctx, time.Until(start.Add(played))); err != nil {
+					return err
+				}
+			}
+			event := InputAudioBufferAppendEvent{Audio: base64.StdEncoding.EncodeToString(buf[:n])}
+			if err := t.conn.SendMessage(ctx, event); err != nil {
+				return err
+			}
+			sent += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// commit commits the input audio buffer and waits for the committed event, an empty buffer is ignored.
+func (t *Transcriber) commit(ctx context.Context) error {
+	t.mu.Lock()
+	t.commitEventID = GenerateID("event_", eventIDLength)
+	t.commitPending = true
+	t.commitErr = nil
+	eventID := t.commitEventID
+	t.mu.Unlock()
+
+	if err := t.conn.SendMessage(ctx, InputAudioBufferCommitEvent{EventBase: EventBase{EventID: eventID}}); err != nil {
+		return err
+	}
+	if err := t.waitFor(ctx, func() bool { return !t.commitPending }); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.commitErr
+}
+
+// wait waits until the utterances of ids are final and returns them.
+func (t *Transcriber) wait(ctx context.Context, ids []string) ([]Utterance, error) {
+	err := t.waitFor(ctx, func() bool {
+		for _, id := range ids {
+			if !t.utterances[id].Final {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	utterances := make([]Utterance, 0, len(ids))
+	for _, id := range ids {
+		utterances = append(utterances, t.utterances[id].snapshot())
+	}
+	return utterances, nil
+}
+
+func (u *Utterance) snapshot() Utterance {
+	s := *u
+	s.Segments = append([]TranscriptionSegment(nil), u.Segments...)
+	s.Logprobs = append([]Logprobs(nil), u.Logprobs...)
+	return s
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
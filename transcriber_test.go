@@ -0,0 +1,217 @@
+package openairt_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const transcriptionSessionCreated = `{"type":"session.created","session":{
+	"type":"transcription","id":"sess_1","object":"realtime.transcription_session",
+	"audio":{"input":{"format":{"type":"audio/pcm","rate":24000},"transcription":{"model":"whisper-1"},
+		"turn_detection":{"type":"server_vad"}}}
+}}`
+
+func transcriptionSessionUpdated(event map[string]any) []string {
+	if event["type"] != "session.update" {
+		return nil
+	}
+	return []string{`{"type":"session.updated","session":{
+		"type":"transcription","id":"sess_1","include":["item.input_audio_transcription.logprobs"],
+		"audio":{"input":{"format":{"type":"audio/pcm","rate":24000},"transcription":{"model":"gpt-4o-transcribe-diarize"},
+			"turn_detection":null}}}}`}
+}
+
+type transcriptionRecorder struct {
+	mu     sync.Mutex
+	events []openairt.TranscriptionEvent
+}
+
+func (r *transcriptionRecorder) record(e openairt.TranscriptionEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *transcriptionRecorder) types() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var types []string
+	for _, e := range r.events {
+		types = append(types, string(e.Type)+":"+e.Utterance.ItemID)
+	}
+	return types
+}
+
+func startTranscriber(
+	t *testing.T,
+	respond func(map[string]any) []string,
+	opts openairt.TranscriberOptions,
+) (*openairt.Transcriber, *fakeServer) {
+	t.Helper()
+	conn, server := newFakeServer(t, respond, []string{transcriptionSessionCreated}, openairt.WithIntent())
+	transcriber := openairt.NewTranscriber(conn, opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	openairt.NewConnHandler(ctx, conn, transcriber.HandleEvent).Start()
+	return transcriber, server
+}
+
+func TestTranscriberFile(t *testing.T) {
+	respond := func(event map[string]any) []string {
+		if event["type"] != "input_audio_buffer.commit" {
+			return transcriptionSessionUpdated(event)
+		}
+		return []string{
+			`{"type":"input_audio_buffer.committed","item_id":"item_1"}`,
+			`{"type":"conversation.item.input_audio_transcription.delta","item_id":"item_1","delta":"Hello",` +
+				`"logprobs":[{"token":"Hello","logprob":-0.1}]}`,
+			`{"type":"conversation.item.input_audio_transcription.segment","item_id":"item_1","id":"seg_1",` +
+				`"speaker":"A","start":0,"end":0.5,"text":"Hello world"}`,
+			`{"type":"conversation.item.input_audio_transcription.completed","item_id":"item_1",` +
+				`"transcript":"Hello world","usage":{"type":"tokens","total_tokens":12,"input_tokens":10,"output_tokens":2,` +
+				`"input_token_details":{"audio_tokens":10}}}`,
+		}
+	}
+	recorder := &transcriptionRecorder{}
+	transcriber, server := startTranscriber(t, respond, openairt.TranscriberOptions{
+		Transcription: openairt.AudioTranscription{Model: "gpt-4o-transcribe-diarize"},
+		TurnDetection: openairt.NullOptional[openairt.TurnDetectionUnion](),
+		Logprobs:      true,
+		Speed:         -1,
+		File:          true,
+		OnEvent:       recorder.record,
+	})
+
+	// 250ms of 24kHz PCM, sent in chunks of 100ms.
+	audio := make([]byte, 12000)
+	utterances, err := transcriber.Transcribe(context.Background(), bytes.NewReader(audio))
+	require.NoError(t, err)
+	require.Len(t, utterances, 1)
+	u := utterances[0]
+	require.Equal(t, "item_1", u.ItemID)
+	require.Equal(t, "Hello world", u.Text)
+	require.True(t, u.Final)
+	require.NoError(t, u.Err)
+	require.Equal(t, []openairt.Logprobs{{Token: "Hello", Logprob: -0.1}}, u.Logprobs)
+	require.Equal(t, []openairt.TranscriptionSegment{{ID: "seg_1", Speaker: "A", End: 0.5, Text: "Hello world"}}, u.Segments)
+	require.Equal(t, 12, transcriber.Usage().TotalTokens)
+	require.Equal(t, 1, transcriber.Usage().Transcriptions)
+	require.Equal(t, []string{"delta:item_1", "segment:item_1", "completed:item_1"}, recorder.types())
+
+	events := server.events()
+	var types []any
+	for _, e := range events {
+		types = append(types, e["type"])
+	}
+	require.Equal(t, []any{
+		"session.update",
+		"input_audio_buffer.append", "input_audio_buffer.append", "input_audio_buffer.append",
+		"input_audio_buffer.commit",
+	}, types)
+	require.JSONEq(t, `{"type":"transcription","include":["item.input_audio_transcription.logprobs"],
+		"audio":{"input":{"transcription":{"model":"gpt-4o-transcribe-diarize"},"turn_detection":null}}}`,
+		sentSession(t, events[0]))
+}
+
+func TestTranscriberOrder(t *testing.T) {
+	recorder := &transcriptionRecorder{}
+	transcriber, server := startTranscriber(t, nil, openairt.TranscriberOptions{OnEvent: recorder.record})
+
+	server.push(
+		`{"type":"input_audio_buffer.speech_started","item_id":"item_1","audio_start_ms":100}`,
+		`{"type":"input_audio_buffer.speech_stopped","item_id":"item_1","audio_end_ms":900}`,
+		`{"type":"input_audio_buffer.committed","item_id":"item_1"}`,
+		`{"type":"input_audio_buffer.committed","item_id":"item_2","previous_item_id":"item_1"}`,
+		// The second utterance completes first, its completion is delivered after the first one.
+		`{"type":"conversation.item.input_audio_transcription.completed","item_id":"item_2","transcript":"two"}`,
+		`{"type":"conversation.item.input_audio_transcription.delta","item_id":"item_1","delta":"o"}`,
+		`{"type":"conversation.item.input_audio_transcription.failed","item_id":"item_1",`+
+			`"error":{"type":"server_error","code":"transcription_failed","message":"failed"}}`,
+	)
+	require.Eventually(t, func() bool { return len(recorder.types()) == 3 }, time.Second, time.Millisecond)
+	require.Equal(t, []string{"delta:item_1", "failed:item_1", "completed:item_2"}, recorder.types())
+
+	utterances := transcriber.Utterances()
+	require.Len(t, utterances, 2)
+	require.Equal(t, int64(100), utterances[0].AudioStartMs)
+	require.Equal(t, int64(900), utterances[0].AudioEndMs)
+	var serverErr *openairt.ServerError
+	require.ErrorAs(t, utterances[0].Err, &serverErr)
+	require.Equal(t, "transcription_failed", serverErr.Detail.Code)
+	require.Equal(t, "item_1", utterances[1].PreviousItemID)
+	require.Equal(t, "two", utterances[1].Text)
+}
+
+func TestTranscriberFileEmptyCommit(t *testing.T) {
+	respond := func(event map[string]any) []string {
+		if event["type"] != "input_audio_buffer.commit" {
+			return nil
+		}
+		return []string{fmt.Sprintf(`{"type":"error","error":{"type":"invalid_request_error",`+
+			`"code":"input_audio_buffer_commit_empty","message":"empty","event_id":%q}}`, event["event_id"])}
+	}
+	transcriber, server := startTranscriber(t, respond, openairt.TranscriberOptions{
+		Transcription: openairt.AudioTranscription{Model: openairt.Whisper1},
+		File:          true,
+	})
+	utterances, err := transcriber.Transcribe(context.Background(), bytes.NewReader(nil))
+	require.NoError(t, err)
+	require.Empty(t, utterances)
+	require.Len(t, server.events(), 1)
+}
+
+func TestTranscriberFileVADCommit(t *testing.T) {
+	var server *fakeServer
+	respond := func(event map[string]any) []string {
+		if event["type"] != "input_audio_buffer.commit" {
+			return nil
+		}
+		// The commit of the turn detection is in flight when the client commits, the reply comes after it.
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			server.push(
+				`{"type":"input_audio_buffer.committed","item_id":"item_2","previous_item_id":"item_1"}`,
+				`{"type":"conversation.item.input_audio_transcription.completed","item_id":"item_2","transcript":"two"}`,
+			)
+		}()
+		return []string{
+			`{"type":"input_audio_buffer.speech_stopped","item_id":"item_1","audio_end_ms":900}`,
+			`{"type":"input_audio_buffer.committed","item_id":"item_1"}`,
+			`{"type":"conversation.item.input_audio_transcription.completed","item_id":"item_1","transcript":"one"}`,
+		}
+	}
+	transcriber, server := startTranscriber(t, respond, openairt.TranscriberOptions{
+		Transcription: openairt.AudioTranscription{Model: openairt.Whisper1},
+		Speed:         -1,
+		File:          true,
+	})
+	utterances, err := transcriber.Transcribe(context.Background(), bytes.NewReader(make([]byte, 4800)))
+	require.NoError(t, err)
+	require.Len(t, utterances, 2)
+	require.Equal(t, "one", utterances[0].Text)
+	require.Equal(t, "two", utterances[1].Text)
+}
+
+func TestTranscriberPacing(t *testing.T) {
+	transcriber, server := startTranscriber(t, transcriptionSessionUpdated, openairt.TranscriberOptions{
+		Transcription: openairt.AudioTranscription{Model: openairt.Whisper1},
+		Format:        openairt.AudioFormatUnion{PCMU: &openairt.AudioFormatPCMU{}},
+		ChunkDuration: 20 * time.Millisecond,
+	})
+
+	// 100ms of G.711 audio is paced in real time, the last chunk is sent after 80ms.
+	start := time.Now()
+	utterances, err := transcriber.Transcribe(context.Background(), bytes.NewReader(make([]byte, 800)))
+	require.NoError(t, err)
+	require.Empty(t, utterances)
+	require.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+	// The session.update of the format and 5 chunks.
+	require.Len(t, server.events(), 6)
+}
@@ -0,0 +1,554 @@
+package openairt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	wavFormatPCM  = 1
+	wavFormatALaw = 6
+	wavFormatULaw = 7
+)
+
+// Transcript is the record of a conversation.
+type Transcript struct {
+	SessionID string    `json:"session_id,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+
+	// Items in the order of the conversation.
+	Items     []TranscriptItem     `json:"items"`
+	Responses []TranscriptResponse `json:"responses,omitempty"`
+
+	// Usage of the responses and the input audio transcriptions.
+	Usage Usage `json:"usage"`
+}
+
+// TranscriptItem is a conversation item of a Transcript.
+type TranscriptItem struct {
+	ID             string          `json:"id"`
+	PreviousItemID string          `json:"previous_item_id,omitempty"`
+	Type           MessageItemType `json:"type"`
+	Role           MessageRole     `json:"role,omitempty"`
+	// ResponseID is the response which created the item.
+	ResponseID string `json:"response_id,omitempty"`
+
+	// Text of a message, the transcript of its audio if it has no text.
+	Text string `json:"text,omitempty"`
+
+	// Function and MCP calls.
+	Name        string `json:"name,omitempty"`
+	CallID      string `json:"call_id,omitempty"`
+	ServerLabel string `json:"server_label,omitempty"`
+	Arguments   string `json:"arguments,omitempty"`
+	Output      string `json:"output,omitempty"`
+
+	// Milliseconds since the session started when the speech of a user message started and stopped.
+	AudioStartMs int64 `json:"audio_start_ms,omitempty"`
+	AudioEndMs   int64 `json:"audio_end_ms,omitempty"`
+
+	CreatedAt time.Time  `json:"created_at"`
+	DoneAt    *time.Time `json:"done_at,omitempty"`
+
+	// Usage of the transcription of a user message.
+	Usage *Usage `json:"usage,omitempty"`
+
+	// Audio of the message in the input or output format of the session, see WriteWAV.
+	Audio []byte `json:"-"`
+	// AudioBytes is the length of Audio.
+	AudioBytes int `json:"audio_bytes,omitempty"`
+}
+
+// TranscriptResponse is a response of a Transcript.
+type TranscriptResponse struct {
+	ID        string         `json:"id"`
+	Status    ResponseStatus `json:"status,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	DoneAt    *time.Time     `json:"done_at,omitempty"`
+	Usage     Usage          `json:"usage"`
+}
+
+// TranscriptRecorder records a conversation from the server events, and the input audio
+// from the client events, to export it as JSON, Markdown and WAV files at the end of a call.
+//
+// Register its HandleEvent on the ConnHandler, and its HandleClientEvent with WithClientEventHandler
+// to record the user audio. The input audio is kept in memory for the whole session.
+type TranscriptRecorder struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	transcript   Transcript
+	inputFormat  AudioFormatUnion
+	outputFormat AudioFormatUnion
+	items        []*TranscriptItem
+	byID         map[string]*TranscriptItem
+	responses    map[string]*TranscriptResponse
+	// responseIDs are the response ids in order of creation.
+	responseIDs []string
+
+	// input is the audio appended to the input audio buffer.
+	input []byte
+	// inputCommitted is the offset in input of the end of the last committed item.
+	inputCommitted int
+	// commits are the offsets in input of the commits sent by the client, not yet committed.
+	commits []int
+}
+
+// NewTranscriptRecorder creates a TranscriptRecorder.
+func NewTranscriptRecorder() *TranscriptRecorder {
+	return &TranscriptRecorder{
+		now:          time.Now,
+		inputFormat:  AudioFormatUnion{PCM: &AudioFormatPCM{Rate: 24000}},
+		outputFormat: AudioFormatUnion{PCM: &AudioFormatPCM{Rate: 24000}},
+		byID:         make(map[string]*TranscriptItem),
+		responses:    make(map[string]*TranscriptResponse),
+	}
+}
+
+// HandleClientEvent is the ClientEventHandler of the TranscriptRecorder, it records the input audio.
+func (r *TranscriptRecorder) HandleClientEvent(_ context.Context, event ClientEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch e := event.(type) {
+	case InputAudioBufferAppendEvent:
+		audio, err := base64.StdEncoding.DecodeString(e.Audio)
+		if err == nil {
+			r.input = append(r.input, audio...)
+		}
+	case InputAudioBufferCommitEvent:
+		r.commits = append(r.commits, len(r.input))
+	case InputAudioBufferClearEvent:
+		r.inputCommitted = len(r.input)
+	}
+}
+
+// HandleEvent is the ServerEventHandler of the TranscriptRecorder.
+func (r *TranscriptRecorder) HandleEvent(_ context.Context, event ServerEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if r.transcript.StartedAt.IsZero() {
+		r.transcript.StartedAt = now
+	}
+	r.transcript.EndedAt = now
+
+	switch e := event.(type) {
+	case SessionCreatedEvent:
+		r.session(e.Session)
+	case SessionUpdatedEvent:
+		r.session(e.Session)
+	case InputAudioBufferSpeechStartedEvent:
+		r.item(e.ItemID, now).AudioStartMs = e.AudioStartMs
+	case InputAudioBufferSpeechStoppedEvent:
+		r.item(e.ItemID, now).AudioEndMs = e.AudioEndMs
+	case InputAudioBufferCommittedEvent:
+		r.committed(r.item(e.ItemID, now))
+	case ConversationItemAddedEvent:
+		r.update(e.Item, e.PreviousItemID, now, false)
+	case ConversationItemDoneEvent:
+		r.update(e.Item, e.PreviousItemID, now, true)
+	case ConversationItemDeletedEvent:
+		r.remove(e.ItemID)
+	case ConversationItemInputAudioTranscriptionCompletedEvent:
+		item := r.item(e.ItemID, now)
+		item.Text = e.Transcript
+		if e.Usage != nil {
+			if usage, ok := newUsageFromTranscription(*e.Usage); ok {
+				item.Usage = &usage
+				r.transcript.Usage.Add(usage)
+			}
+		}
+	case ResponseOutputItemAddedEvent:
+		r.item(messageItemID(e.Item), now).ResponseID = e.ResponseID
+	case ResponseOutputAudioDeltaEvent:
+		var audio []byte
+		if item, ok := r.byID[e.ItemID]; ok {
+			audio = item.Audio
+		}
+		if audio, err := e.AppendAudio(audio); err == nil {
+			item := r.item(e.ItemID, now)
+			item.ResponseID = e.ResponseID
+			item.Audio = audio
+		}
+	case ResponseCreatedEvent:
+		r.response(e.Response.ID, now).Status = e.Response.Status
+	case ResponseDoneEvent:
+		resp := r.response(e.Response.ID, now)
+		resp.Status = e.Response.Status
+		resp.DoneAt = &now
+		if e.Response.Usage != nil {
+			resp.Usage = newUsageFromTokens(*e.Response.Usage)
+			resp.Usage.Responses = 1
+			r.transcript.Usage.Add(resp.Usage)
+		}
+	}
+}
+
+// Transcript returns the recorded conversation.
+func (r *TranscriptRecorder) Transcript() Transcript {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.transcript
+	t.Items = make([]TranscriptItem, 0, len(r.items))
+	for _, item := range r.items {
+		i := *item
+		i.Audio = append([]byte(nil), item.Audio...)
+		i.AudioBytes = len(i.Audio)
+		t.Items = append(t.Items, i)
+	}
+	t.Responses = make([]TranscriptResponse, 0, len(r.responseIDs))
+	for _, id := range r.responseIDs {
+		t.Responses = append(t.Responses, *r.responses[id])
+	}
+	return t
+}
+
+// WriteJSON writes the transcript as an indented JSON document, without the audio.
+func (r *TranscriptRecorder) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.Transcript())
+}
+
+// WriteMarkdown writes the transcript as a human readable Markdown document.
+func (r *TranscriptRecorder) WriteMarkdown(w io.Writer) error {
+	t := r.Transcript()
+	var sb strings.Builder
+	sb.WriteString("# Transcript\n\n")
+	if t.SessionID != "" {
+		fmt.Fprintf(&sb, "- Session: %s\n", t.SessionID)
+	}
+	if t.Model != "" {
+		fmt.Fprintf(&sb, "- Model: %s\n", t.Model)
+	}
+	if !t.StartedAt.IsZero() {
+		fmt.Fprintf(&sb, "- Started: %s\n", t.StartedAt.Format(time.RFC3339))
+		fmt.Fprintf(&sb, "- Duration: %s\n", t.EndedAt.Sub(t.StartedAt).Round(time.Millisecond))
+	}
+	fmt.Fprintf(&sb, "- Tokens: %d, transcriptions: %d\n\n", t.Usage.TotalTokens, t.Usage.Transcriptions)
+
+	for _, item := range t.Items {
+		switch item.Type {
+		case MessageItemTypeMessage:
+			fmt.Fprintf(&sb, "**%s**", transcriptSpeaker(item.Role))
+			if item.AudioEndMs > 0 {
+				fmt.Fprintf(&sb, " (%s–%s)",
+					formatCaptionTime(time.Duration(item.AudioStartMs)*time.Millisecond, '.'),
+					formatCaptionTime(time.Duration(item.AudioEndMs)*time.Millisecond, '.'))
+			}
+			fmt.Fprintf(&sb, ": %s\n\n", item.Text)
+		case MessageItemTypeFunctionCall:
+			fmt.Fprintf(&sb, "**Tool call** `%s` (%s):\n\n```json\n%s\n```\n\n", item.Name, item.CallID, item.Arguments)
+		case MessageItemTypeFunctionCallOutput:
+			fmt.Fprintf(&sb, "**Tool result** (%s):\n\n```\n%s\n```\n\n", item.CallID, item.Output)
+		case MessageItemTypeMCPCall:
+			fmt.Fprintf(&sb, "**MCP call** `%s.%s`:\n\n```json\n%s\n```\n\n```\n%s\n```\n\n",
+				item.ServerLabel, item.Name, item.Arguments, item.Output)
+		default:
+			fmt.Fprintf(&sb, "**%s**\n\n", item.Type)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteWAV writes the audio of every message having audio to a WAV file in dir,
+// named by its position, role and id, e.g. 001-user-item_1.wav, and returns the paths.
+func (r *TranscriptRecorder) WriteWAV(dir string) ([]string, error) {
+	t := r.Transcript()
+	r.mu.Lock()
+	inputFormat, outputFormat := r.inputFormat, r.outputFormat
+	r.mu.Unlock()
+
+	var paths []string
+	for i, item := range t.Items {
+		if len(item.Audio) == 0 {
+			continue
+		}
+		format := outputFormat
+		if item.Role == MessageRoleUser {
+			format = inputFormat
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%03d-%s-%s.wav", i+1, item.Role, item.ID))
+		if err := writeWAVFile(path, format, item.Audio); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func writeWAVFile(path string, format AudioFormatUnion, audio []byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteWAV(f, format, audio); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// wavFormatChunk is the fmt chunk of a PCM WAV file.
+type wavFormatChunk struct {
+	Format        uint16
+	Channels      uint16
+	SampleRate    uint32
+	ByteRate      uint32
+	BlockAlign    uint16
+	BitsPerSample uint16
+}
+
+// wavFormatChunkEx is the fmt chunk of the non-PCM formats, ending with the size of its extension.
+type wavFormatChunkEx struct {
+	wavFormatChunk
+	ExtensionSize uint16
+}
+
+// wavChunk is a chunk of a WAV file, whose data is binary encoded.
+type wavChunk struct {
+	id   [4]byte
+	data any
+}
+
+// WriteWAV writes audio in format as a WAV file: 16-bit mono PCM, or 8kHz G.711 μ-law or A-law.
+// The G.711 files have the extended fmt chunk and the fact chunk required by the non-PCM formats.
+func WriteWAV(w io.Writer, format AudioFormatUnion, audio []byte) error {
+	fmtChunk := wavFormatChunk{Format: wavFormatPCM, Channels: 1, SampleRate: 24000, BitsPerSample: 16}
+	switch format.Kind() {
+	case AudioFormatTypePCMU:
+		fmtChunk.Format, fmtChunk.SampleRate, fmtChunk.BitsPerSample = wavFormatULaw, 8000, 8
+	case AudioFormatTypePCMA:
+		fmtChunk.Format, fmtChunk.SampleRate, fmtChunk.BitsPerSample = wavFormatALaw, 8000, 8
+	case AudioFormatTypePCM:
+		if format.PCM.Rate > 0 {
+			fmtChunk.SampleRate = uint32(format.PCM.Rate)
+		}
+	}
+	fmtChunk.BlockAlign = fmtChunk.BitsPerSample / 8 //nolint:mnd // bits per byte
+	fmtChunk.ByteRate = fmtChunk.SampleRate * uint32(fmtChunk.BlockAlign)
+
+	chunks := []wavChunk{{id: [4]byte{'f', 'm', 't', ' '}, data: fmtChunk}}
+	if fmtChunk.Format != wavFormatPCM {
+		chunks = []wavChunk{
+			{id: [4]byte{'f', 'm', 't', ' '}, data: wavFormatChunkEx{wavFormatChunk: fmtChunk}},
+			// The number of samples.
+			{id: [4]byte{'f', 'a', 'c', 't'}, data: uint32(len(audio) / int(fmtChunk.BlockAlign))},
+		}
+	}
+
+	// The size of the RIFF chunk counts its WAVE id, and the id, size and data of each subchunk.
+	size := 4 + 8 + len(audio) //nolint:mnd // WAVE id and the header of the data chunk
+	for _, c := range chunks {
+		size += 8 + binary.Size(c.data) //nolint:mnd // header of the chunk
+	}
+	var header bytes.Buffer
+	header.WriteString("RIFF")
+	_ = binary.Write(&header, binary.LittleEndian, uint32(size))
+	header.WriteString("WAVE")
+	for _, c := range chunks {
+		header.Write(c.id[:])
+		_ = binary.Write(&header, binary.LittleEndian, uint32(binary.Size(c.data)))
+		_ = binary.Write(&header, binary.LittleEndian, c.data)
+	}
+	header.WriteString("data")
+	_ = binary.Write(&header, binary.LittleEndian, uint32(len(audio)))
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(audio)
+	return err
+}
+
+func (r *TranscriptRecorder) session(session SessionUnion) {
+	var input, output *AudioFormatUnion
+	switch {
+	case session.Realtime != nil:
+		r.transcript.SessionID = session.Realtime.ID
+		r.transcript.Model = session.Realtime.Model
+		if audio := session.Realtime.Audio; audio != nil {
+			if audio.Input != nil {
+				input = audio.Input.Format
+			}
+			if audio.Output != nil {
+				output = audio.Output.Format
+			}
+		}
+	case session.Transcription != nil:
+		r.transcript.SessionID = session.Transcription.ID
+		if audio := session.Transcription.Audio; audio != nil && audio.Input != nil {
+			input = audio.Input.Format
+		}
+	}
+	if input != nil {
+		r.inputFormat = *input
+	}
+	if output != nil {
+		r.outputFormat = *output
+	}
+}
+
+// item returns the item of id, creating it if needed. Items created before conversation.item.added
+// are placed by it.
+func (r *TranscriptRecorder) item(id string, now time.Time) *TranscriptItem {
+	item, ok := r.byID[id]
+	if !ok {
+		item = &TranscriptItem{ID: id, CreatedAt: now}
+		r.byID[id] = item
+	}
+	return item
+}
+
+func (r *TranscriptRecorder) response(id string, now time.Time) *TranscriptResponse {
+	resp, ok := r.responses[id]
+	if !ok {
+		resp = &TranscriptResponse{ID: id, CreatedAt: now}
+		r.responses[id] = resp
+		r.responseIDs = append(r.responseIDs, id)
+	}
+	return resp
+}
+
+// committed assigns the input audio of a committed user message, by the speech times with turn
+// detection, or up to the commit sent by the client otherwise.
+func (r *TranscriptRecorder) committed(item *TranscriptItem) {
+	if item.AudioEndMs > 0 {
+		bytesPerMs := audioBytesPerSecond(r.inputFormat) / 1000 //nolint:mnd // milliseconds per second
+		start := clampInt(int(item.AudioStartMs)*bytesPerMs, 0, len(r.input))
+		end := clampInt(int(item.AudioEndMs)*bytesPerMs, start, len(r.input))
+		item.Audio = append([]byte(nil), r.input[start:end]...)
+		r.inputCommitted = end
+		return
+	}
+	if len(r.commits) == 0 {
+		return
+	}
+	offset := r.commits[0]
+	r.commits = r.commits[1:]
+	if offset > r.inputCommitted {
+		item.Audio = append([]byte(nil), r.input[r.inputCommitted:offset]...)
+	}
+	r.inputCommitted = offset
+}
+
+// update places an item in the conversation after previousItemID and records its content.
+func (r *TranscriptRecorder) update(m MessageItemUnion, previousItemID string, now time.Time, done bool) {
+	item := r.item(messageItemID(m), now)
+	if !r.placed(item) {
+		item.PreviousItemID = previousItemID
+		r.insert(item, previousItemID)
+	}
+	if done {
+		item.DoneAt = &now
+	}
+
+	if text := messageItemText(m); text != "" {
+		// A user audio message has no text until its transcript arrives.
+		item.Text = text
+	}
+	switch m.Kind() {
+	case MessageItemKindSystem:
+		item.Type, item.Role = m.System.MessageItemType(), m.System.Role()
+	case MessageItemKindUser:
+		item.Type, item.Role = m.User.MessageItemType(), m.User.Role()
+	case MessageItemKindAssistant:
+		item.Type, item.Role = m.Assistant.MessageItemType(), m.Assistant.Role()
+	case MessageItemKindFunctionCall:
+		item.Type = m.FunctionCall.MessageItemType()
+		item.Name, item.CallID, item.Arguments = m.FunctionCall.Name, m.FunctionCall.CallID, m.FunctionCall.Arguments
+	case MessageItemKindFunctionCallOutput:
+		item.Type = m.FunctionCallOutput.MessageItemType()
+		item.CallID, item.Output = m.FunctionCallOutput.CallID, m.FunctionCallOutput.Output
+	case MessageItemKindMCPToolCall:
+		item.Type = m.MCPToolCall.MessageItemType()
+		item.ServerLabel, item.Name = m.MCPToolCall.ServerLabel, m.MCPToolCall.Name
+		item.Arguments, item.Output = m.MCPToolCall.Arguments, m.MCPToolCall.Output
+	case MessageItemKindMCPApprovalRequest:
+		item.Type = m.MCPApprovalRequest.MessageItemType()
+		item.ServerLabel, item.Name = m.MCPApprovalRequest.ServerLabel, m.MCPApprovalRequest.Name
+		item.Arguments = m.MCPApprovalRequest.Arguments
+	case MessageItemKindMCPApprovalResponse:
+		item.Type = m.MCPApprovalResponse.MessageItemType()
+	case MessageItemKindMCPListTools:
+		item.Type = m.MCPListTools.MessageItemType()
+		item.ServerLabel = m.MCPListTools.ServerLabel
+	}
+}
+
+func transcriptSpeaker(role MessageRole) string {
+	if role == "" {
+		return "Unknown"
+	}
+	return strings.ToUpper(string(role[:1])) + string(role[1:])
+}
+
+func (r *TranscriptRecorder) placed(item *TranscriptItem) bool {
+	for _, i := range r.items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *TranscriptRecorder) insert(item *TranscriptItem, previousItemID string) {
+	index := len(r.items)
+	if previousItemID == PreviousItemIDRoot {
+		index = 0
+	} else if previousItemID != "" {
+		for i, prev := range r.items {
+			if prev.ID == previousItemID {
+				index = i + 1
+				break
+			}
+		}
+	}
+	r.items = append(r.items, nil)
+	copy(r.items[index+1:], r.items[index:])
+	r.items[index] = item
+}
+
+func (r *TranscriptRecorder) remove(id string) {
+	for i, item := range r.items {
+		if item.ID == id {
+			r.items = append(r.items[:i], r.items[i+1:]...)
+			break
+		}
+	}
+	delete(r.byID, id)
+}
+
+// audioBytesPerSecond returns the bytes per second of 16-bit mono PCM or 8kHz G.711.
+func audioBytesPerSecond(format AudioFormatUnion) int {
+	switch {
+	case format.PCMU != nil, format.PCMA != nil:
+		return 8000 //nolint:mnd // 8kHz 8-bit
+	case format.PCM != nil && format.PCM.Rate > 0:
+		return format.PCM.Rate * 2 //nolint:mnd // 16-bit samples
+	default:
+		return 24000 * 2 //nolint:mnd // 24kHz 16-bit samples
+	}
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
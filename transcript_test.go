@@ -0,0 +1,132 @@
+package openairt_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func recordTranscript(t *testing.T) *openairt.TranscriptRecorder {
+	t.Helper()
+	recorder := openairt.NewTranscriptRecorder()
+	conn, _ := newFakeServer(t, nil, nil, openairt.WithClientEventHandler(recorder.HandleClientEvent))
+	ctx := context.Background()
+
+	handleServerEvents(t, recorder.HandleEvent, sessionCreated)
+	// 10ms of audio committed by the client.
+	audio := bytes.Repeat([]byte{1, 2}, 240)
+	require.NoError(t, conn.SendMessage(ctx, openairt.InputAudioBufferAppendEvent{
+		Audio: base64.StdEncoding.EncodeToString(audio),
+	}))
+	require.NoError(t, conn.SendMessage(ctx, openairt.InputAudioBufferCommitEvent{}))
+	handleServerEvents(t, recorder.HandleEvent,
+		`{"type":"input_audio_buffer.committed","item_id":"item_1"}`,
+		`{"type":"conversation.item.added","item":{"id":"item_1","type":"message","role":"user",`+
+			`"content":[{"type":"input_audio"}]}}`,
+		`{"type":"conversation.item.input_audio_transcription.completed","item_id":"item_1","transcript":"What's the weather?",`+
+			`"usage":{"type":"duration","seconds":1.5}}`,
+		`{"type":"response.created","response":{"id":"resp_1","status":"in_progress"}}`,
+		`{"type":"response.output_item.added","response_id":"resp_1",`+
+			`"item":{"id":"item_2","type":"function_call","call_id":"call_1","name":"weather"}}`,
+		`{"type":"conversation.item.done","previous_item_id":"item_1",`+
+			`"item":{"id":"item_2","type":"function_call","call_id":"call_1","name":"weather","arguments":"{\"city\":\"Paris\"}"}}`,
+		`{"type":"conversation.item.added","previous_item_id":"item_2",`+
+			`"item":{"id":"item_3","type":"function_call_output","call_id":"call_1","output":"sunny"}}`,
+		`{"type":"response.output_audio.delta","response_id":"resp_2","item_id":"item_4","delta":"AAEC"}`,
+		`{"type":"conversation.item.done","previous_item_id":"item_3","item":{"id":"item_4","type":"message","role":"assistant",`+
+			`"content":[{"type":"output_audio","transcript":"It's sunny."}]}}`,
+		`{"type":"response.done","response":{"id":"resp_1","status":"completed","usage":{"total_tokens":20}}}`,
+	)
+	return recorder
+}
+
+func TestTranscriptRecorder(t *testing.T) {
+	transcript := recordTranscript(t).Transcript()
+	require.Equal(t, "sess_1", transcript.SessionID)
+	require.Equal(t, 20, transcript.Usage.TotalTokens)
+	require.Equal(t, 1.5, transcript.Usage.DurationSeconds)
+	require.Len(t, transcript.Responses, 1)
+	require.Equal(t, openairt.ResponseStatusCompleted, transcript.Responses[0].Status)
+
+	require.Len(t, transcript.Items, 4)
+	user, call, output, assistant := transcript.Items[0], transcript.Items[1], transcript.Items[2], transcript.Items[3]
+	require.Equal(t, openairt.MessageRoleUser, user.Role)
+	require.Equal(t, "What's the weather?", user.Text)
+	require.Len(t, user.Audio, 480)
+	require.Equal(t, "weather", call.Name)
+	require.Equal(t, "resp_1", call.ResponseID)
+	require.Equal(t, "sunny", output.Output)
+	require.Equal(t, "It's sunny.", assistant.Text)
+	require.Equal(t, []byte{0, 1, 2}, assistant.Audio)
+}
+
+func TestTranscriptRecorderExport(t *testing.T) {
+	recorder := recordTranscript(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, recorder.WriteJSON(&buf))
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	items := doc["items"].([]any)
+	require.Len(t, items, 4)
+	require.Equal(t, float64(480), items[0].(map[string]any)["audio_bytes"])
+	require.NotContains(t, buf.String(), "AAEC")
+
+	buf.Reset()
+	require.NoError(t, recorder.WriteMarkdown(&buf))
+	md := buf.String()
+	require.Contains(t, md, "- Session: sess_1\n")
+	require.Contains(t, md, "**User**: What's the weather?\n\n"+
+		"**Tool call** `weather` (call_1):\n\n```json\n{\"city\":\"Paris\"}\n```\n\n"+
+		"**Tool result** (call_1):\n\n```\nsunny\n```\n\n"+
+		"**Assistant**: It's sunny.\n\n")
+
+	dir := t.TempDir()
+	paths, err := recorder.WriteWAV(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "001-user-item_1.wav"),
+		filepath.Join(dir, "004-assistant-item_4.wav"),
+	}, paths)
+	data, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	require.Len(t, data, 44+480)
+	require.Equal(t, "RIFF", string(data[:4]))
+	require.Equal(t, "WAVE", string(data[8:12]))
+	require.Equal(t, uint32(24000), binary.LittleEndian.Uint32(data[24:28]))
+}
+
+func TestWriteWAVG711(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, openairt.WriteWAV(&buf, openairt.AudioFormatUnion{PCMU: &openairt.AudioFormatPCMU{}}, []byte{1, 2, 3}))
+	data := buf.Bytes()
+	// The fmt chunk has an empty extension, and is followed by a fact chunk.
+	require.Len(t, data, 58+3)
+	require.Equal(t, "RIFF", string(data[:4]))
+	require.Equal(t, uint32(len(data)-8), binary.LittleEndian.Uint32(data[4:8]))
+	require.Equal(t, "fmt ", string(data[12:16]))
+	require.Equal(t, uint32(18), binary.LittleEndian.Uint32(data[16:20]))
+	require.Equal(t, uint16(7), binary.LittleEndian.Uint16(data[20:22]))
+	require.Equal(t, uint32(8000), binary.LittleEndian.Uint32(data[24:28]))
+	require.Equal(t, uint16(8), binary.LittleEndian.Uint16(data[34:36]))
+	require.Equal(t, uint16(0), binary.LittleEndian.Uint16(data[36:38]))
+	require.Equal(t, "fact", string(data[38:42]))
+	require.Equal(t, uint32(4), binary.LittleEndian.Uint32(data[42:46]))
+	require.Equal(t, uint32(3), binary.LittleEndian.Uint32(data[46:50]))
+	require.Equal(t, "data", string(data[50:54]))
+	require.Equal(t, uint32(3), binary.LittleEndian.Uint32(data[54:58]))
+	require.Equal(t, []byte{1, 2, 3}, data[58:])
+
+	buf.Reset()
+	require.NoError(t, openairt.WriteWAV(&buf, openairt.AudioFormatOfPCMA(), []byte{1, 2}))
+	require.Equal(t, uint16(6), binary.LittleEndian.Uint16(buf.Bytes()[20:22]))
+	require.Len(t, buf.Bytes(), 58+2)
+}
@@ -330,13 +330,18 @@ type ToolUnion struct {
 }
 
 func (t ToolUnion) MarshalJSON() ([]byte, error) {
-	if t.Function != nil {
-		return json.Marshal(t.Function)
+	kind, err := t.kind()
+	if err != nil {
+		return nil, err
 	}
-	if t.MCP != nil {
+	switch kind {
+	case ToolTypeFunction:
+		return json.Marshal(t.Function)
+	case ToolTypeMCP:
 		return json.Marshal(t.MCP)
+	default:
+		return nil, errors.New("no tool")
 	}
-	return nil, errors.New("no tool")
 }
 
 func (t *ToolUnion) UnmarshalJSON(data []byte) error {
@@ -368,6 +373,26 @@ type TracingUnion struct {
 	Configuration *TracingConfiguration `json:",omitempty"`
 }
 
+func (t TracingUnion) MarshalJSON() ([]byte, error) {
+	if t.Configuration != nil {
+		return json.Marshal(t.Configuration)
+	}
+	if t.Mode != "" {
+		return json.Marshal(t.Mode)
+	}
+	return nil, errors.New("no tracing")
+}
+
+func (t *TracingUnion) UnmarshalJSON(data []byte) error {
+	if isNull(data) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &t.Mode)
+	}
+	return json.Unmarshal(data, &t.Configuration)
+}
+
 type TruncationStrategy string
 
 const (
@@ -388,11 +413,34 @@ func (t RetentionRatioTruncation) TruncationStrategy() string {
 	return string(TruncationStrategyRetentionRatio)
 }
 
+func (t RetentionRatioTruncation) MarshalJSON() ([]byte, error) {
+	type typeAlias RetentionRatioTruncation
+	type typeWrapper struct {
+		typeAlias
+		Type string `json:"type"`
+	}
+	shadow := typeWrapper{
+		typeAlias: typeAlias(t),
+		Type:      t.TruncationStrategy(),
+	}
+	return json.Marshal(shadow)
+}
+
 type TruncationUnion struct {
 	Strategy                 TruncationStrategy        `json:",omitempty"`
 	RetentionRatioTruncation *RetentionRatioTruncation `json:",omitempty"`
 }
 
+func (t TruncationUnion) MarshalJSON() ([]byte, error) {
+	if t.RetentionRatioTruncation != nil {
+		return json.Marshal(t.RetentionRatioTruncation)
+	}
+	if t.Strategy != "" {
+		return json.Marshal(t.Strategy)
+	}
+	return nil, errors.New("no truncation strategy")
+}
+
 const nullString = "null"
 
 func isNull(data []byte) bool {
@@ -412,7 +460,7 @@ func (t *TruncationUnion) UnmarshalJSON(data []byte) error {
 	case TruncationStrategyRetentionRatio:
 		return json.Unmarshal(data, &t.RetentionRatioTruncation)
 	case TruncationStrategyDisabled, TruncationStrategyAuto:
-		t.Strategy = TruncationStrategy(data)
+		t.Strategy = TruncationStrategy(u.Type)
 	default:
 		return fmt.Errorf("unknown truncation strategy: %s", u.Type)
 	}
@@ -547,16 +595,20 @@ type AudioFormatUnion struct {
 }
 
 func (r AudioFormatUnion) MarshalJSON() ([]byte, error) {
-	if r.PCM != nil {
-		return json.Marshal(r.PCM)
+	kind, err := r.kind()
+	if err != nil {
+		return nil, err
 	}
-	if r.PCMU != nil {
+	switch kind {
+	case AudioFormatTypePCM:
+		return json.Marshal(r.PCM)
+	case AudioFormatTypePCMU:
 		return json.Marshal(r.PCMU)
-	}
-	if r.PCMA != nil {
+	case AudioFormatTypePCMA:
 		return json.Marshal(r.PCMA)
+	default:
+		return nil, errors.New("no audio format")
 	}
-	return nil, errors.New("no audio format")
 }
 
 func (r *AudioFormatUnion) UnmarshalJSON(data []byte) error {
@@ -668,13 +720,18 @@ type TurnDetectionUnion struct {
 }
 
 func (r TurnDetectionUnion) MarshalJSON() ([]byte, error) {
-	if r.ServerVad != nil {
-		return json.Marshal(r.ServerVad)
+	kind, err := r.kind()
+	if err != nil {
+		return nil, err
 	}
-	if r.SemanticVad != nil {
+	switch kind {
+	case TurnDetectionTypeServerVad:
+		return json.Marshal(r.ServerVad)
+	case TurnDetectionTypeSemanticVad:
 		return json.Marshal(r.SemanticVad)
+	default:
+		return nil, errors.New("no turn detection")
 	}
-	return nil, errors.New("no turn detection")
 }
 
 func (r *TurnDetectionUnion) UnmarshalJSON(data []byte) error {
@@ -710,17 +767,17 @@ type SessionAudioInput struct {
 	Format *AudioFormatUnion `json:"format,omitempty"`
 
 	// Configuration for input audio noise reduction. This can be set to null to turn off. Noise reduction filters audio added to the input audio buffer before it is sent to VAD and the model. Filtering the audio can improve VAD and turn detection accuracy (reducing false positives) and model performance by improving perception of the input audio.
-	NoiseReduction *AudioNoiseReduction `json:"noise_reduction,omitempty"`
-
-	// Configuration for input audio transcription, defaults to off and can be set to null to turn off once on. Input audio transcription is not native to the model, since the model consumes audio directly. Transcription runs asynchronously through the /audio/transcriptions endpoint and should be treated as guidance of input audio content rather than precisely what the model heard. The client can optionally set the language and prompt for transcription, these offer additional guidance to the transcription service.
-	TurnDetection *TurnDetectionUnion `json:"turn_detection,omitempty"`
+	NoiseReduction Optional[AudioNoiseReduction] `json:"noise_reduction,omitempty"`
 
 	// Configuration for turn detection, ether Server VAD or Semantic VAD. This can be set to null to turn off, in which case the client must manually trigger model response.
 	//
 	// Server VAD means that the model will detect the start and end of speech based on audio volume and respond at the end of user speech.
 	//
 	// Semantic VAD is more advanced and uses a turn detection model (in conjunction with VAD) to semantically estimate whether the user has finished speaking, then dynamically sets a timeout based on this probability. For example, if user audio trails off with "uhhm", the model will score a low probability of turn end and wait longer for the user to continue speaking. This can be useful for more natural conversations, but may have a higher latency.
-	Transcription *AudioTranscription `json:"transcription,omitempty"`
+	TurnDetection Optional[TurnDetectionUnion] `json:"turn_detection,omitempty"`
+
+	// Configuration for input audio transcription, defaults to off and can be set to null to turn off once on. Input audio transcription is not native to the model, since the model consumes audio directly. Transcription runs asynchronously through the /audio/transcriptions endpoint and should be treated as guidance of input audio content rather than precisely what the model heard. The client can optionally set the language and prompt for transcription, these offer additional guidance to the transcription service.
+	Transcription Optional[AudioTranscription] `json:"transcription,omitempty"`
 }
 
 type SessionAudioOutput struct {
@@ -835,10 +892,26 @@ type typeStruct struct {
 	Type string `json:"type"`
 }
 
+func (u PromptVariableUnion) MarshalJSON() ([]byte, error) {
+	if u.InputText != nil {
+		return json.Marshal(u.InputText)
+	}
+	if u.InputImage != nil {
+		return json.Marshal(u.InputImage)
+	}
+	if u.InputFile != nil {
+		return json.Marshal(u.InputFile)
+	}
+	return json.Marshal(u.String)
+}
+
 func (u *PromptVariableUnion) UnmarshalJSON(data []byte) error {
 	if isNull(data) {
 		return nil
 	}
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &u.String)
+	}
 	var t typeStruct
 	if err := json.Unmarshal(data, &t); err != nil {
 		return err
@@ -923,7 +996,7 @@ type RealtimeSession struct {
 	// Realtime API can write session traces to the Traces Dashboard. Set to null to disable tracing. Once tracing is enabled for a session, the configuration cannot be modified.
 	//
 	// auto will create a trace for the session with default values for the workflow name, group id, and metadata.
-	Tracing *TracingUnion `json:"tracing,omitempty"`
+	Tracing Optional[TracingUnion] `json:"tracing,omitempty"`
 
 	// Controls how the realtime conversation is truncated prior to model inference. The default is auto.
 	Truncation *TruncationUnion `json:"truncation,omitempty"`
@@ -62,3 +62,19 @@ func TestTurnDetectionUnion(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, expected, actual)
 }
+
+func TestPromptVariableUnion(t *testing.T) {
+	data := `{"city":"Paris","photo":{"type":"input_image","image_url":"https://example.com/a.png"}}`
+	expected := map[string]openairt.PromptVariableUnion{
+		"city":  {String: "Paris"},
+		"photo": {InputImage: &openairt.PromptInputImage{ImageURL: "https://example.com/a.png"}},
+	}
+	actual := map[string]openairt.PromptVariableUnion{}
+	err := json.Unmarshal([]byte(data), &actual)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	marshaled, err := json.Marshal(actual)
+	require.NoError(t, err)
+	require.JSONEq(t, data, string(marshaled))
+}
@@ -0,0 +1,330 @@
+package openairt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrMultipleVariants is returned when more than one variant of a union is set.
+	ErrMultipleVariants = errors.New("more than one variant is set")
+
+	// ErrNoVariant is returned when visiting a union whose variants are all unset.
+	ErrNoVariant = errors.New("no variant is set")
+)
+
+// unionVariant is a variant of a union, set if its pointer isn't nil.
+type unionVariant[K ~string] struct {
+	kind K
+	set  bool
+}
+
+// unionKind returns the kind of the set variant, or an empty kind and an error if more than one is set.
+func unionKind[K ~string](union string, variants ...unionVariant[K]) (K, error) {
+	var kind K
+	var set []string
+	for _, v := range variants {
+		if v.set {
+			kind = v.kind
+			set = append(set, string(v.kind))
+		}
+	}
+	if len(set) > 1 {
+		return "", fmt.Errorf("%w: %s has %s", ErrMultipleVariants, union, strings.Join(set, ", "))
+	}
+	return kind, nil
+}
+
+// MessageItemKind is the variant of a MessageItemUnion.
+type MessageItemKind string
+
+const (
+	MessageItemKindSystem              MessageItemKind = "system"
+	MessageItemKindUser                MessageItemKind = "user"
+	MessageItemKindAssistant           MessageItemKind = "assistant"
+	MessageItemKindFunctionCall        MessageItemKind = "function_call"
+	MessageItemKindFunctionCallOutput  MessageItemKind = "function_call_output"
+	MessageItemKindMCPApprovalResponse MessageItemKind = "mcp_approval_response"
+	MessageItemKindMCPListTools        MessageItemKind = "mcp_list_tools"
+	MessageItemKindMCPToolCall         MessageItemKind = "mcp_call"
+	MessageItemKindMCPApprovalRequest  MessageItemKind = "mcp_approval_request"
+)
+
+// MessageItemOfSystem returns the union of a system message item.
+func MessageItemOfSystem(item MessageItemSystem) MessageItemUnion {
+	return MessageItemUnion{System: &item}
+}
+
+// MessageItemOfUser returns the union of a user message item.
+func MessageItemOfUser(item MessageItemUser) MessageItemUnion {
+	return MessageItemUnion{User: &item}
+}
+
+// MessageItemOfAssistant returns the union of an assistant message item.
+func MessageItemOfAssistant(item MessageItemAssistant) MessageItemUnion {
+	return MessageItemUnion{Assistant: &item}
+}
+
+// MessageItemOfFunctionCall returns the union of a function call item.
+func MessageItemOfFunctionCall(item MessageItemFunctionCall) MessageItemUnion {
+	return MessageItemUnion{FunctionCall: &item}
+}
+
+// MessageItemOfFunctionCallOutput returns the union of a function call output item.
+func MessageItemOfFunctionCallOutput(item MessageItemFunctionCallOutput) MessageItemUnion {
+	return MessageItemUnion{FunctionCallOutput: &item}
+}
+
+// MessageItemOfMCPApprovalResponse returns the union of an MCP approval response item.
+func MessageItemOfMCPApprovalResponse(item MessageItemMCPApprovalResponse) MessageItemUnion {
+	return MessageItemUnion{MCPApprovalResponse: &item}
+}
+
+// MessageItemOfMCPListTools returns the union of an MCP list tools item.
+func MessageItemOfMCPListTools(item MessageItemMCPListTools) MessageItemUnion {
+	return MessageItemUnion{MCPListTools: &item}
+}
+
+// MessageItemOfMCPToolCall returns the union of an MCP tool call item.
+func MessageItemOfMCPToolCall(item MessageItemMCPToolCall) MessageItemUnion {
+	return MessageItemUnion{MCPToolCall: &item}
+}
+
+// MessageItemOfMCPApprovalRequest returns the union of an MCP approval request item.
+func MessageItemOfMCPApprovalRequest(item MessageItemMCPApprovalRequest) MessageItemUnion {
+	return MessageItemUnion{MCPApprovalRequest: &item}
+}
+
+// SystemMessage returns a system message item of text.
+func SystemMessage(text string) MessageItemUnion {
+	return MessageItemOfSystem(MessageItemSystem{Content: []MessageContentSystem{{Text: text}}})
+}
+
+// UserMessage returns a user message item of text.
+func UserMessage(text string) MessageItemUnion {
+	return MessageItemOfUser(MessageItemUser{Content: []MessageContentInput{{
+		Type: MessageContentTypeInputText,
+		Text: text,
+	}}})
+}
+
+// AssistantMessage returns an assistant message item of text.
+func AssistantMessage(text string) MessageItemUnion {
+	return MessageItemOfAssistant(MessageItemAssistant{Content: []MessageContentOutput{{
+		Type: MessageContentTypeOutputText,
+		Text: text,
+	}}})
+}
+
+// Kind returns the kind of the set variant, or an empty kind if none or more than one is set.
+func (m MessageItemUnion) Kind() MessageItemKind {
+	kind, _ := m.kind()
+	return kind
+}
+
+func (m MessageItemUnion) kind() (MessageItemKind, error) {
+	return unionKind("MessageItemUnion",
+		unionVariant[MessageItemKind]{MessageItemKindSystem, m.System != nil},
+		unionVariant[MessageItemKind]{MessageItemKindUser, m.User != nil},
+		unionVariant[MessageItemKind]{MessageItemKindAssistant, m.Assistant != nil},
+		unionVariant[MessageItemKind]{MessageItemKindFunctionCall, m.FunctionCall != nil},
+		unionVariant[MessageItemKind]{MessageItemKindFunctionCallOutput, m.FunctionCallOutput != nil},
+		unionVariant[MessageItemKind]{MessageItemKindMCPApprovalResponse, m.MCPApprovalResponse != nil},
+		unionVariant[MessageItemKind]{MessageItemKindMCPListTools, m.MCPListTools != nil},
+		unionVariant[MessageItemKind]{MessageItemKindMCPToolCall, m.MCPToolCall != nil},
+		unionVariant[MessageItemKind]{MessageItemKindMCPApprovalRequest, m.MCPApprovalRequest != nil},
+	)
+}
+
+// MessageItemVisitor has a method for every variant of MessageItemUnion,
+// so that its implementations fail to compile when a variant is added.
+type MessageItemVisitor interface {
+	VisitSystem(item *MessageItemSystem) error
+	VisitUser(item *MessageItemUser) error
+	VisitAssistant(item *MessageItemAssistant) error
+	VisitFunctionCall(item *MessageItemFunctionCall) error
+	VisitFunctionCallOutput(item *MessageItemFunctionCallOutput) error
+	VisitMCPApprovalResponse(item *MessageItemMCPApprovalResponse) error
+	VisitMCPListTools(item *MessageItemMCPListTools) error
+	VisitMCPToolCall(item *MessageItemMCPToolCall) error
+	VisitMCPApprovalRequest(item *MessageItemMCPApprovalRequest) error
+}
+
+// Visit calls the method of visitor for the set variant. It returns ErrNoVariant if none is set,
+// and ErrMultipleVariants if more than one is set.
+func (m MessageItemUnion) Visit(visitor MessageItemVisitor) error {
+	kind, err := m.kind()
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case MessageItemKindSystem:
+		return visitor.VisitSystem(m.System)
+	case MessageItemKindUser:
+		return visitor.VisitUser(m.User)
+	case MessageItemKindAssistant:
+		return visitor.VisitAssistant(m.Assistant)
+	case MessageItemKindFunctionCall:
+		return visitor.VisitFunctionCall(m.FunctionCall)
+	case MessageItemKindFunctionCallOutput:
+		return visitor.VisitFunctionCallOutput(m.FunctionCallOutput)
+	case MessageItemKindMCPApprovalResponse:
+		return visitor.VisitMCPApprovalResponse(m.MCPApprovalResponse)
+	case MessageItemKindMCPListTools:
+		return visitor.VisitMCPListTools(m.MCPListTools)
+	case MessageItemKindMCPToolCall:
+		return visitor.VisitMCPToolCall(m.MCPToolCall)
+	case MessageItemKindMCPApprovalRequest:
+		return visitor.VisitMCPApprovalRequest(m.MCPApprovalRequest)
+	default:
+		return fmt.Errorf("%w: MessageItemUnion", ErrNoVariant)
+	}
+}
+
+// ToolOfFunction returns the union of a function tool.
+func ToolOfFunction(tool ToolFunction) ToolUnion {
+	return ToolUnion{Function: &tool}
+}
+
+// ToolOfMCP returns the union of a remote MCP server tool.
+func ToolOfMCP(tool ToolMCP) ToolUnion {
+	return ToolUnion{MCP: &tool}
+}
+
+// Kind returns the type of the set variant, or an empty type if none or more than one is set.
+func (t ToolUnion) Kind() ToolType {
+	kind, _ := t.kind()
+	return kind
+}
+
+func (t ToolUnion) kind() (ToolType, error) {
+	return unionKind("ToolUnion",
+		unionVariant[ToolType]{ToolTypeFunction, t.Function != nil},
+		unionVariant[ToolType]{ToolTypeMCP, t.MCP != nil},
+	)
+}
+
+// ToolVisitor has a method for every variant of ToolUnion.
+type ToolVisitor interface {
+	VisitFunction(tool *ToolFunction) error
+	VisitMCP(tool *ToolMCP) error
+}
+
+// Visit calls the method of visitor for the set variant. It returns ErrNoVariant if none is set,
+// and ErrMultipleVariants if more than one is set.
+func (t ToolUnion) Visit(visitor ToolVisitor) error {
+	kind, err := t.kind()
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case ToolTypeFunction:
+		return visitor.VisitFunction(t.Function)
+	case ToolTypeMCP:
+		return visitor.VisitMCP(t.MCP)
+	default:
+		return fmt.Errorf("%w: ToolUnion", ErrNoVariant)
+	}
+}
+
+// AudioFormatOfPCM returns the union of the PCM format.
+func AudioFormatOfPCM(format AudioFormatPCM) AudioFormatUnion {
+	return AudioFormatUnion{PCM: &format}
+}
+
+// AudioFormatOfPCMU returns the union of the G.711 μ-law format.
+func AudioFormatOfPCMU() AudioFormatUnion {
+	return AudioFormatUnion{PCMU: &AudioFormatPCMU{}}
+}
+
+// AudioFormatOfPCMA returns the union of the G.711 A-law format.
+func AudioFormatOfPCMA() AudioFormatUnion {
+	return AudioFormatUnion{PCMA: &AudioFormatPCMA{}}
+}
+
+// Kind returns the type of the set variant, or an empty type if none or more than one is set.
+func (r AudioFormatUnion) Kind() AudioFormatType {
+	kind, _ := r.kind()
+	return kind
+}
+
+func (r AudioFormatUnion) kind() (AudioFormatType, error) {
+	return unionKind("AudioFormatUnion",
+		unionVariant[AudioFormatType]{AudioFormatTypePCM, r.PCM != nil},
+		unionVariant[AudioFormatType]{AudioFormatTypePCMU, r.PCMU != nil},
+		unionVariant[AudioFormatType]{AudioFormatTypePCMA, r.PCMA != nil},
+	)
+}
+
+// AudioFormatVisitor has a method for every variant of AudioFormatUnion.
+type AudioFormatVisitor interface {
+	VisitPCM(format *AudioFormatPCM) error
+	VisitPCMU(format *AudioFormatPCMU) error
+	VisitPCMA(format *AudioFormatPCMA) error
+}
+
+// Visit calls the method of visitor for the set variant. It returns ErrNoVariant if none is set,
+// and ErrMultipleVariants if more than one is set.
+func (r AudioFormatUnion) Visit(visitor AudioFormatVisitor) error {
+	kind, err := r.kind()
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case AudioFormatTypePCM:
+		return visitor.VisitPCM(r.PCM)
+	case AudioFormatTypePCMU:
+		return visitor.VisitPCMU(r.PCMU)
+	case AudioFormatTypePCMA:
+		return visitor.VisitPCMA(r.PCMA)
+	default:
+		return fmt.Errorf("%w: AudioFormatUnion", ErrNoVariant)
+	}
+}
+
+// TurnDetectionOfServerVad returns the union of server VAD turn detection.
+func TurnDetectionOfServerVad(vad ServerVad) TurnDetectionUnion {
+	return TurnDetectionUnion{ServerVad: &vad}
+}
+
+// TurnDetectionOfSemanticVad returns the union of semantic VAD turn detection.
+func TurnDetectionOfSemanticVad(vad RealtimeSessionSemanticVad) TurnDetectionUnion {
+	return TurnDetectionUnion{SemanticVad: &vad}
+}
+
+// Kind returns the type of the set variant, or an empty type if none or more than one is set.
+func (r TurnDetectionUnion) Kind() TurnDetectionType {
+	kind, _ := r.kind()
+	return kind
+}
+
+func (r TurnDetectionUnion) kind() (TurnDetectionType, error) {
+	return unionKind("TurnDetectionUnion",
+		unionVariant[TurnDetectionType]{TurnDetectionTypeServerVad, r.ServerVad != nil},
+		unionVariant[TurnDetectionType]{TurnDetectionTypeSemanticVad, r.SemanticVad != nil},
+	)
+}
+
+// TurnDetectionVisitor has a method for every variant of TurnDetectionUnion.
+type TurnDetectionVisitor interface {
+	VisitServerVad(vad *ServerVad) error
+	VisitSemanticVad(vad *RealtimeSessionSemanticVad) error
+}
+
+// Visit calls the method of visitor for the set variant. It returns ErrNoVariant if none is set,
+// and ErrMultipleVariants if more than one is set.
+func (r TurnDetectionUnion) Visit(visitor TurnDetectionVisitor) error {
+	kind, err := r.kind()
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case TurnDetectionTypeServerVad:
+		return visitor.VisitServerVad(r.ServerVad)
+	case TurnDetectionTypeSemanticVad:
+		return visitor.VisitSemanticVad(r.SemanticVad)
+	default:
+		return fmt.Errorf("%w: TurnDetectionUnion", ErrNoVariant)
+	}
+}
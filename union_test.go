@@ -0,0 +1,196 @@
+package openairt_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// itemNames is a MessageItemVisitor collecting a description of the visited items.
+type itemNames struct {
+	names []string
+}
+
+func (v *itemNames) VisitSystem(item *openairt.MessageItemSystem) error {
+	v.names = append(v.names, "system: "+item.Content[0].Text)
+	return nil
+}
+
+func (v *itemNames) VisitUser(item *openairt.MessageItemUser) error {
+	v.names = append(v.names, "user: "+item.Content[0].Text)
+	return nil
+}
+
+func (v *itemNames) VisitAssistant(item *openairt.MessageItemAssistant) error {
+	v.names = append(v.names, "assistant: "+item.Content[0].Text)
+	return nil
+}
+
+func (v *itemNames) VisitFunctionCall(item *openairt.MessageItemFunctionCall) error {
+	v.names = append(v.names, "call: "+item.Name)
+	return nil
+}
+
+func (v *itemNames) VisitFunctionCallOutput(item *openairt.MessageItemFunctionCallOutput) error {
+	v.names = append(v.names, "output: "+item.Output)
+	return nil
+}
+
+func (v *itemNames) VisitMCPApprovalResponse(*openairt.MessageItemMCPApprovalResponse) error {
+	v.names = append(v.names, "mcp approval response")
+	return nil
+}
+
+func (v *itemNames) VisitMCPListTools(*openairt.MessageItemMCPListTools) error {
+	v.names = append(v.names, "mcp list tools")
+	return nil
+}
+
+func (v *itemNames) VisitMCPToolCall(item *openairt.MessageItemMCPToolCall) error {
+	v.names = append(v.names, "mcp call: "+item.Name)
+	return nil
+}
+
+func (v *itemNames) VisitMCPApprovalRequest(*openairt.MessageItemMCPApprovalRequest) error {
+	v.names = append(v.names, "mcp approval request")
+	return nil
+}
+
+func TestMessageItemUnionVisit(t *testing.T) {
+	items := []openairt.MessageItemUnion{
+		openairt.SystemMessage("be brief"),
+		openairt.UserMessage("hi"),
+		openairt.AssistantMessage("hello"),
+		openairt.MessageItemOfFunctionCall(openairt.MessageItemFunctionCall{CallID: "call_1", Name: "weather"}),
+		openairt.MessageItemOfFunctionCallOutput(openairt.MessageItemFunctionCallOutput{CallID: "call_1", Output: "sunny"}),
+		openairt.MessageItemOfMCPToolCall(openairt.MessageItemMCPToolCall{Name: "search"}),
+	}
+	visitor := &itemNames{}
+	var kinds []openairt.MessageItemKind
+	for _, item := range items {
+		require.NoError(t, item.Visit(visitor))
+		kinds = append(kinds, item.Kind())
+	}
+	require.Equal(t, []string{
+		"system: be brief", "user: hi", "assistant: hello", "call: weather", "output: sunny", "mcp call: search",
+	}, visitor.names)
+	require.Equal(t, []openairt.MessageItemKind{
+		openairt.MessageItemKindSystem, openairt.MessageItemKindUser, openairt.MessageItemKindAssistant,
+		openairt.MessageItemKindFunctionCall, openairt.MessageItemKindFunctionCallOutput, openairt.MessageItemKindMCPToolCall,
+	}, kinds)
+
+	data, err := json.Marshal(items[1])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"message","role":"user","content":[{"type":"input_text","text":"hi"}]}`, string(data))
+
+	var empty openairt.MessageItemUnion
+	require.Equal(t, openairt.MessageItemKind(""), empty.Kind())
+	require.ErrorIs(t, empty.Visit(visitor), openairt.ErrNoVariant)
+}
+
+func TestUnionMultipleVariants(t *testing.T) {
+	item := openairt.UserMessage("hi")
+	item.Assistant = &openairt.MessageItemAssistant{}
+	_, err := json.Marshal(item)
+	require.ErrorIs(t, err, openairt.ErrMultipleVariants)
+	require.ErrorContains(t, err, "MessageItemUnion has user, assistant")
+	require.ErrorIs(t, item.Visit(&itemNames{}), openairt.ErrMultipleVariants)
+	require.Equal(t, openairt.MessageItemKind(""), item.Kind())
+
+	tool := openairt.ToolOfFunction(openairt.ToolFunction{Name: "weather"})
+	tool.MCP = &openairt.ToolMCP{ServerLabel: "docs"}
+	_, err = json.Marshal(tool)
+	require.ErrorIs(t, err, openairt.ErrMultipleVariants)
+	require.Equal(t, openairt.ToolType(""), tool.Kind())
+
+	format := openairt.AudioFormatOfPCMU()
+	format.PCMA = &openairt.AudioFormatPCMA{}
+	_, err = json.Marshal(format)
+	require.ErrorIs(t, err, openairt.ErrMultipleVariants)
+
+	turnDetection := openairt.TurnDetectionOfServerVad(openairt.ServerVad{})
+	turnDetection.SemanticVad = &openairt.RealtimeSessionSemanticVad{}
+	_, err = json.Marshal(turnDetection)
+	require.ErrorIs(t, err, openairt.ErrMultipleVariants)
+}
+
+// formatRate is an AudioFormatVisitor returning the sample rate of the format.
+type formatRate struct {
+	rate int
+}
+
+func (v *formatRate) VisitPCM(format *openairt.AudioFormatPCM) error {
+	v.rate = format.Rate
+	return nil
+}
+
+func (v *formatRate) VisitPCMU(*openairt.AudioFormatPCMU) error {
+	v.rate = 8000
+	return nil
+}
+
+func (v *formatRate) VisitPCMA(*openairt.AudioFormatPCMA) error {
+	v.rate = 8000
+	return nil
+}
+
+func TestAudioFormatUnionVisit(t *testing.T) {
+	visitor := &formatRate{}
+	format := openairt.AudioFormatOfPCM(openairt.AudioFormatPCM{Rate: 24000})
+	require.Equal(t, openairt.AudioFormatTypePCM, format.Kind())
+	require.NoError(t, format.Visit(visitor))
+	require.Equal(t, 24000, visitor.rate)
+
+	format = openairt.AudioFormatOfPCMA()
+	require.Equal(t, openairt.AudioFormatTypePCMA, format.Kind())
+	require.NoError(t, format.Visit(visitor))
+	require.Equal(t, 8000, visitor.rate)
+}
+
+// toolNames is a ToolVisitor collecting the names of the tools.
+type toolNames []string
+
+func (v *toolNames) VisitFunction(tool *openairt.ToolFunction) error {
+	*v = append(*v, tool.Name)
+	return nil
+}
+
+func (v *toolNames) VisitMCP(tool *openairt.ToolMCP) error {
+	*v = append(*v, tool.ServerLabel)
+	return nil
+}
+
+// vadNames is a TurnDetectionVisitor collecting the types of the turn detections.
+type vadNames []string
+
+func (v *vadNames) VisitServerVad(*openairt.ServerVad) error {
+	*v = append(*v, "server")
+	return nil
+}
+
+func (v *vadNames) VisitSemanticVad(vad *openairt.RealtimeSessionSemanticVad) error {
+	*v = append(*v, "semantic "+vad.Eagerness)
+	return nil
+}
+
+func TestToolAndTurnDetectionUnionVisit(t *testing.T) {
+	var tools toolNames
+	for _, tool := range []openairt.ToolUnion{
+		openairt.ToolOfFunction(openairt.ToolFunction{Name: "weather"}),
+		openairt.ToolOfMCP(openairt.ToolMCP{ServerLabel: "docs"}),
+	} {
+		require.NoError(t, tool.Visit(&tools))
+	}
+	require.Equal(t, toolNames{"weather", "docs"}, tools)
+	require.Equal(t, openairt.ToolTypeMCP, openairt.ToolOfMCP(openairt.ToolMCP{}).Kind())
+
+	var vads vadNames
+	semantic := openairt.TurnDetectionOfSemanticVad(openairt.RealtimeSessionSemanticVad{Eagerness: "low"})
+	require.Equal(t, openairt.TurnDetectionTypeSemanticVad, semantic.Kind())
+	require.NoError(t, semantic.Visit(&vads))
+	require.NoError(t, openairt.TurnDetectionOfServerVad(openairt.ServerVad{}).Visit(&vads))
+	require.Equal(t, vadNames{"semantic low", "server"}, vads)
+	require.ErrorIs(t, openairt.TurnDetectionUnion{}.Visit(&vads), openairt.ErrNoVariant)
+}
@@ -0,0 +1,439 @@
+package openairt
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+const (
+	tokensPerMillion = 1_000_000
+	secondsPerMinute = 60
+)
+
+// ModelPrice is the price of a model in USD.
+// Token prices are per million tokens, PerMinute is used for duration based usage (e.g. whisper-1).
+type ModelPrice struct {
+	TextInput        float64
+	TextCachedInput  float64
+	TextOutput       float64
+	AudioInput       float64
+	AudioCachedInput float64
+	AudioOutput      float64
+	PerMinute        float64
+}
+
+// TokenCost returns the cost of the given token usage.
+func (p ModelPrice) TokenCost(u TokenUsage) float64 {
+	b := newUsageFromTokens(u)
+	return p.cost(b)
+}
+
+// DurationCost returns the cost of the given duration usage.
+func (p ModelPrice) DurationCost(u DurationUsage) float64 {
+	return u.Seconds / secondsPerMinute * p.PerMinute
+}
+
+func (p ModelPrice) cost(u Usage) float64 {
+	tokens := float64(u.InputTextTokens)*p.TextInput +
+		float64(u.CachedTextTokens)*p.TextCachedInput +
+		float64(u.OutputTextTokens)*p.TextOutput +
+		float64(u.InputAudioTokens)*p.AudioInput +
+		float64(u.CachedAudioTokens)*p.AudioCachedInput +
+		float64(u.OutputAudioTokens)*p.AudioOutput
+	return tokens/tokensPerMillion + u.DurationSeconds/secondsPerMinute*p.PerMinute
+}
+
+// PriceTable maps a model name to its price.
+type PriceTable map[string]ModelPrice
+
+// DefaultPriceTable returns the published prices of the realtime and transcription models.
+// Prices change over time, callers that bill customers should maintain their own table.
+func DefaultPriceTable() PriceTable {
+	return PriceTable{
+		GPTRealtime: {
+			TextInput: 4, TextCachedInput: 0.4, TextOutput: 16,
+			AudioInput: 32, AudioCachedInput: 0.4, AudioOutput: 64,
+		},
+		GPTRealtimeMini: {
+			TextInput: 0.6, TextCachedInput: 0.06, TextOutput: 2.4,
+			AudioInput: 10, AudioCachedInput: 0.3, AudioOutput: 20,
+		},
+		GPT4oRealtimePreview: {
+			TextInput: 5, TextCachedInput: 2.5, TextOutput: 20,
+			AudioInput: 40, AudioCachedInput: 2.5, AudioOutput: 80,
+		},
+		GPT4oMiniRealtimePreview: {
+			TextInput: 0.6, TextCachedInput: 0.3, TextOutput: 2.4,
+			AudioInput: 10, AudioCachedInput: 0.3, AudioOutput: 20,
+		},
+		GPT4oTranscribe:     {TextInput: 2.5, TextOutput: 10, AudioInput: 6},
+		GPT4oMiniTranscribe: {TextInput: 1.25, TextOutput: 5, AudioInput: 3},
+		Whisper1:            {PerMinute: 0.006},
+	}
+}
+
+// Lookup returns the price of the model.
+// Model snapshots (e.g. gpt-realtime-2025-08-28) fall back to the longest matching prefix (e.g. gpt-realtime).
+func (t PriceTable) Lookup(model string) (ModelPrice, bool) {
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+	var (
+		best  string
+		price ModelPrice
+		found bool
+	)
+	for name, p := range t {
+		if strings.HasPrefix(model, name+"-") && len(name) > len(best) {
+			best, price, found = name, p, true
+		}
+	}
+	return price, found
+}
+
+// Usage is an aggregation of token usage, duration usage and cost.
+type Usage struct {
+	// Number of responses accounted.
+	Responses int `json:"responses"`
+	// Number of transcriptions accounted.
+	Transcriptions int `json:"transcriptions"`
+
+	TotalTokens       int `json:"total_tokens"`
+	InputTextTokens   int `json:"input_text_tokens"`
+	CachedTextTokens  int `json:"cached_text_tokens"`
+	InputAudioTokens  int `json:"input_audio_tokens"`
+	CachedAudioTokens int `json:"cached_audio_tokens"`
+	OutputTextTokens  int `json:"output_text_tokens"`
+	OutputAudioTokens int `json:"output_audio_tokens"`
+
+	// Seconds of audio accounted by duration, e.g. whisper-1 transcriptions.
+	DurationSeconds float64 `json:"duration_seconds"`
+
+	// Cost in USD. Zero if the model is not in the price table.
+	Cost float64 `json:"cost"`
+}
+
+// Add adds o to u.
+func (u *Usage) Add(o Usage) {
+	u.Responses += o.Responses
+	u.Transcriptions += o.Transcriptions
+	u.TotalTokens += o.TotalTokens
+	u.InputTextTokens += o.InputTextTokens
+	u.CachedTextTokens += o.CachedTextTokens
+	u.InputAudioTokens += o.InputAudioTokens
+	u.CachedAudioTokens += o.CachedAudioTokens
+	u.OutputTextTokens += o.OutputTextTokens
+	u.OutputAudioTokens += o.OutputAudioTokens
+	u.DurationSeconds += o.DurationSeconds
+	u.Cost += o.Cost
+}
+
+// newUsageFromTranscription returns the usage of a transcription, and false if the usage is empty.
+func newUsageFromTranscription(usage UsageUnion) (Usage, bool) {
+	var u Usage
+	switch {
+	case usage.Tokens != nil:
+		u = newUsageFromTokens(*usage.Tokens)
+	case usage.Duration != nil:
+		u.DurationSeconds = usage.Duration.Seconds
+	default:
+		return Usage{}, false
+	}
+	u.Transcriptions = 1
+	return u, true
+}
+
+// newUsageFromTokens splits the token usage into uncached and cached input, text and audio.
+// Input text and audio tokens reported by the API include the cached ones.
+func newUsageFromTokens(t TokenUsage) Usage {
+	u := Usage{
+		TotalTokens: t.TotalTokens,
+	}
+	if d := t.InputTokenDetails; d != nil {
+		u.InputTextTokens = d.TextTokens
+		u.InputAudioTokens = d.AudioTokens
+		if d.CachedTokensDetails != nil {
+			u.CachedTextTokens = d.CachedTokensDetails.TextTokens
+			u.CachedAudioTokens = d.CachedTokensDetails.AudioTokens
+		} else {
+			u.CachedTextTokens, u.CachedAudioTokens = splitCachedTokens(d.CachedTokens, d.TextTokens, d.AudioTokens)
+		}
+		u.InputTextTokens -= u.CachedTextTokens
+		u.InputAudioTokens -= u.CachedAudioTokens
+	} else {
+		u.InputTextTokens = t.InputTokens
+	}
+	if d := t.OutputTokenDetails; d != nil {
+		u.OutputTextTokens = d.TextTokens
+		u.OutputAudioTokens = d.AudioTokens
+	} else {
+		u.OutputTextTokens = t.OutputTokens
+	}
+	return u
+}
+
+// splitCachedTokens splits the cached tokens without details in proportion to the input text and audio tokens.
+func splitCachedTokens(cached, text, audio int) (int, int) {
+	if text+audio <= 0 {
+		return cached, 0
+	}
+	cachedText := (cached*text + (text+audio)/2) / (text + audio) //nolint:mnd // rounded to the nearest
+	return cachedText, cached - cachedText
+}
+
+// UsageRecord is a single accounted usage, passed to the callback set by WithUsageCallback.
+type UsageRecord struct {
+	SessionID string
+	// ResponseID is set for response usage.
+	ResponseID string
+	// ItemID is set for transcription usage.
+	ItemID string
+	Model  string
+	Tags   []string
+	Usage  Usage
+}
+
+type usageOption struct {
+	onRecord     func(record UsageRecord)
+	alertCost    float64
+	onAlert      func(sessionID string, usage Usage)
+	keepResponse bool
+}
+
+type UsageOption func(*usageOption)
+
+// WithUsageCallback sets a callback called after each accounted usage with the running totals updated.
+func WithUsageCallback(fn func(record UsageRecord)) UsageOption {
+	return func(opts *usageOption) {
+		opts.onRecord = fn
+	}
+}
+
+// WithCostAlert sets a callback called once per session when the cost of the session reaches threshold.
+func WithCostAlert(threshold float64, fn func(sessionID string, usage Usage)) UsageOption {
+	return func(opts *usageOption) {
+		opts.alertCost = threshold
+		opts.onAlert = fn
+	}
+}
+
+// WithResponseUsage keeps the usage of every response so that it can be queried by UsageAccountant.Response.
+// It's disabled by default since the number of responses is unbounded.
+func WithResponseUsage() UsageOption {
+	return func(opts *usageOption) {
+		opts.keepResponse = true
+	}
+}
+
+// UsageAccountant aggregates usage and cost per response, per session and per tag.
+// It's safe for concurrent use, a single accountant could be shared by many connections.
+type UsageAccountant struct {
+	prices PriceTable
+	opts   usageOption
+
+	mu        sync.Mutex
+	total     Usage
+	sessions  map[string]*Usage
+	tags      map[string]*Usage
+	responses map[string]Usage
+	alerted   map[string]bool
+}
+
+// NewUsageAccountant creates a new UsageAccountant with the given price table.
+func NewUsageAccountant(prices PriceTable, opts ...UsageOption) *UsageAccountant {
+	a := &UsageAccountant{
+		prices:    prices,
+		sessions:  make(map[string]*Usage),
+		tags:      make(map[string]*Usage),
+		responses: make(map[string]Usage),
+		alerted:   make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(&a.opts)
+	}
+	return a
+}
+
+// RecordResponse accounts the usage of a completed response.
+func (a *UsageAccountant) RecordResponse(sessionID, model string, resp Response, tags ...string) Usage {
+	if resp.Usage == nil {
+		return Usage{}
+	}
+	u := newUsageFromTokens(*resp.Usage)
+	u.Responses = 1
+	if price, ok := a.prices.Lookup(model); ok {
+		u.Cost = price.cost(u)
+	}
+	a.record(UsageRecord{
+		SessionID:  sessionID,
+		ResponseID: resp.ID,
+		Model:      model,
+		Tags:       tags,
+		Usage:      u,
+	})
+	return u
+}
+
+// RecordTranscription accounts the usage of an input audio transcription.
+func (a *UsageAccountant) RecordTranscription(sessionID, model, itemID string, usage UsageUnion, tags ...string) Usage {
+	u, ok := newUsageFromTranscription(usage)
+	if !ok {
+		return Usage{}
+	}
+	if price, ok := a.prices.Lookup(model); ok {
+		u.Cost = price.cost(u)
+	}
+	a.record(UsageRecord{
+		SessionID: sessionID,
+		ItemID:    itemID,
+		Model:     model,
+		Tags:      tags,
+		Usage:     u,
+	})
+	return u
+}
+
+func (a *UsageAccountant) record(record UsageRecord) {
+	var (
+		alert   bool
+		session Usage
+	)
+	a.mu.Lock()
+	a.total.Add(record.Usage)
+	s, ok := a.sessions[record.SessionID]
+	if !ok {
+		s = &Usage{}
+		a.sessions[record.SessionID] = s
+	}
+	s.Add(record.Usage)
+	session = *s
+	for _, tag := range record.Tags {
+		t, ok := a.tags[tag]
+		if !ok {
+			t = &Usage{}
+			a.tags[tag] = t
+		}
+		t.Add(record.Usage)
+	}
+	if a.opts.keepResponse && record.ResponseID != "" {
+		a.responses[record.ResponseID] = record.Usage
+	}
+	if a.opts.onAlert != nil && !a.alerted[record.SessionID] && session.Cost >= a.opts.alertCost {
+		a.alerted[record.SessionID] = true
+		alert = true
+	}
+	a.mu.Unlock()
+
+	if a.opts.onRecord != nil {
+		a.opts.onRecord(record)
+	}
+	if alert {
+		a.opts.onAlert(record.SessionID, session)
+	}
+}
+
+// Total returns the usage of all sessions.
+func (a *UsageAccountant) Total() Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.total
+}
+
+// Session returns the usage of the session.
+func (a *UsageAccountant) Session(sessionID string) Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if s, ok := a.sessions[sessionID]; ok {
+		return *s
+	}
+	return Usage{}
+}
+
+// Sessions returns the usage of every session, keyed by session ID.
+func (a *UsageAccountant) Sessions() map[string]Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	sessions := make(map[string]Usage, len(a.sessions))
+	for id, s := range a.sessions {
+		sessions[id] = *s
+	}
+	return sessions
+}
+
+// Tag returns the usage of all sessions and responses accounted with the tag.
+func (a *UsageAccountant) Tag(tag string) Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if t, ok := a.tags[tag]; ok {
+		return *t
+	}
+	return Usage{}
+}
+
+// Response returns the usage of the response. It requires WithResponseUsage.
+func (a *UsageAccountant) Response(responseID string) (Usage, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u, ok := a.responses[responseID]
+	return u, ok
+}
+
+// Handler returns a ServerEventHandler accounting the usage of a single connection.
+// The session ID and models are taken from the session.created and session.updated events,
+// so the handler should be registered before the connection receives any event.
+// The tags are attached to every usage accounted by the handler.
+func (a *UsageAccountant) Handler(tags ...string) ServerEventHandler {
+	var (
+		mu                 sync.Mutex
+		sessionID          string
+		model              string
+		transcriptionModel string
+	)
+	updateSession := func(session SessionUnion) {
+		mu.Lock()
+		defer mu.Unlock()
+		var input *SessionAudioInput
+		switch {
+		case session.Realtime != nil:
+			sessionID = session.Realtime.ID
+			if session.Realtime.Model != "" {
+				model = session.Realtime.Model
+			}
+			if session.Realtime.Audio != nil {
+				input = session.Realtime.Audio.Input
+			}
+		case session.Transcription != nil:
+			sessionID = session.Transcription.ID
+			if session.Transcription.Audio != nil {
+				input = session.Transcription.Audio.Input
+			}
+		}
+		if input != nil {
+			if transcription, ok := input.Transcription.Get(); ok && transcription.Model != "" {
+				transcriptionModel = transcription.Model
+			}
+		}
+	}
+	return func(_ context.Context, event ServerEvent) {
+		switch e := event.(type) {
+		case SessionCreatedEvent:
+			updateSession(e.Session)
+		case SessionUpdatedEvent:
+			updateSession(e.Session)
+		case ResponseDoneEvent:
+			mu.Lock()
+			id, m := sessionID, model
+			mu.Unlock()
+			a.RecordResponse(id, m, e.Response, tags...)
+		case ConversationItemInputAudioTranscriptionCompletedEvent:
+			if e.Usage == nil {
+				return
+			}
+			mu.Lock()
+			id, m := sessionID, transcriptionModel
+			mu.Unlock()
+			a.RecordTranscription(id, m, e.ItemID, *e.Usage, tags...)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package openairt_test
+
+import (
+	"context"
+	"testing"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceTableLookup(t *testing.T) {
+	prices := openairt.DefaultPriceTable()
+
+	price, ok := prices.Lookup(openairt.GPTRealtime20250828)
+	require.True(t, ok)
+	require.Equal(t, prices[openairt.GPTRealtime], price)
+
+	price, ok = prices.Lookup(openairt.GPTRealtimeMini20251006)
+	require.True(t, ok)
+	require.Equal(t, prices[openairt.GPTRealtimeMini], price)
+
+	_, ok = prices.Lookup("gpt-realtimex")
+	require.False(t, ok)
+}
+
+func TestModelPriceTokenCost(t *testing.T) {
+	price := openairt.ModelPrice{
+		TextInput: 4, TextCachedInput: 0.4, TextOutput: 16,
+		AudioInput: 32, AudioCachedInput: 0.4, AudioOutput: 64,
+	}
+	cost := price.TokenCost(openairt.TokenUsage{
+		TotalTokens:  3000,
+		InputTokens:  2000,
+		OutputTokens: 1000,
+		InputTokenDetails: &openairt.InputTokenDetails{
+			CachedTokens: 600,
+			TextTokens:   1000,
+			AudioTokens:  1000,
+			CachedTokensDetails: &openairt.CachedTokensDetails{
+				TextTokens:  500,
+				AudioTokens: 100,
+			},
+		},
+		OutputTokenDetails: &openairt.OutputTokenDetails{
+			TextTokens:  200,
+			AudioTokens: 800,
+		},
+	})
+	expected := (500*4 + 500*0.4 + 900*32 + 100*0.4 + 200*16 + 800*64) / 1e6
+	require.InDelta(t, expected, cost, 1e-12)
+
+	// Without details, the cached tokens are split in proportion to the input text and audio tokens.
+	cost = price.TokenCost(openairt.TokenUsage{
+		TotalTokens: 2000,
+		InputTokens: 2000,
+		InputTokenDetails: &openairt.InputTokenDetails{
+			CachedTokens: 600,
+			TextTokens:   500,
+			AudioTokens:  1500,
+		},
+	})
+	expected = (350*4 + 150*0.4 + 1050*32 + 450*0.4) / 1e6
+	require.InDelta(t, expected, cost, 1e-12)
+
+	require.InDelta(t, 0.009, openairt.ModelPrice{PerMinute: 0.006}.DurationCost(openairt.DurationUsage{Seconds: 90}), 1e-12)
+}
+
+func TestUsageAccountantHandler(t *testing.T) {
+	var (
+		records []openairt.UsageRecord
+		alerts  []string
+	)
+	accountant := openairt.NewUsageAccountant(
+		openairt.PriceTable{
+			openairt.GPTRealtime: {TextInput: 1e6, TextOutput: 2e6},
+			openairt.Whisper1:    {PerMinute: 60},
+		},
+		openairt.WithUsageCallback(func(record openairt.UsageRecord) {
+			records = append(records, record)
+		}),
+		openairt.WithCostAlert(10, func(sessionID string, _ openairt.Usage) {
+			alerts = append(alerts, sessionID)
+		}),
+		openairt.WithResponseUsage(),
+	)
+
+	ctx := context.Background()
+	handler := accountant.Handler("customer-1")
+	handler(ctx, openairt.SessionCreatedEvent{
+		Session: openairt.SessionUnion{Realtime: &openairt.RealtimeSession{
+			ID:    "sess_1",
+			Model: openairt.GPTRealtime20250828,
+			Audio: &openairt.RealtimeSessionAudio{Input: &openairt.SessionAudioInput{
+				Transcription: openairt.NewOptional(openairt.AudioTranscription{Model: openairt.Whisper1}),
+			}},
+		}},
+	})
+	for _, id := range []string{"resp_1", "resp_2"} {
+		handler(ctx, openairt.ResponseDoneEvent{Response: openairt.Response{
+			ID: id,
+			Usage: &openairt.TokenUsage{
+				TotalTokens:  5,
+				InputTokens:  3,
+				OutputTokens: 2,
+			},
+		}})
+	}
+	handler(ctx, openairt.ConversationItemInputAudioTranscriptionCompletedEvent{
+		ItemID: "item_1",
+		Usage:  &openairt.UsageUnion{Duration: &openairt.DurationUsage{Seconds: 2}},
+	})
+
+	require.Len(t, records, 3)
+	require.Equal(t, "sess_1", records[0].SessionID)
+	require.Equal(t, "resp_1", records[0].ResponseID)
+	require.Equal(t, openairt.GPTRealtime20250828, records[0].Model)
+	require.Equal(t, openairt.Whisper1, records[2].Model)
+	require.Equal(t, []string{"sess_1"}, alerts)
+
+	resp, ok := accountant.Response("resp_2")
+	require.True(t, ok)
+	require.InDelta(t, 7.0, resp.Cost, 1e-9)
+
+	session := accountant.Session("sess_1")
+	require.Equal(t, 2, session.Responses)
+	require.Equal(t, 1, session.Transcriptions)
+	require.Equal(t, 6, session.InputTextTokens)
+	require.Equal(t, 4, session.OutputTextTokens)
+	require.InDelta(t, 2.0, session.DurationSeconds, 1e-9)
+	require.InDelta(t, 16.0, session.Cost, 1e-9)
+
+	require.Equal(t, session, accountant.Tag("customer-1"))
+	require.Equal(t, session, accountant.Total())
+	require.Equal(t, map[string]openairt.Usage{"sess_1": session}, accountant.Sessions())
+	require.Equal(t, openairt.Usage{}, accountant.Tag("customer-2"))
+}
@@ -0,0 +1,538 @@
+package openairt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Documented limits checked by Validate.
+const (
+	maxOutputTokensLimit = 4096
+	pcmSampleRate        = 24000
+	maxMetadataPairs     = 16
+	maxMetadataKeyLen    = 64
+	maxMetadataValueLen  = 512
+	minAudioSpeed        = 0.25
+	maxAudioSpeed        = 1.5
+)
+
+// Validator is implemented by the types which could be checked against the documented
+// constraints of the API before sending. All client events implement it.
+type Validator interface {
+	Validate() error
+}
+
+// ValidationError describes a field violating a documented constraint.
+type ValidationError struct {
+	// Field is the JSON path of the field, e.g. "session.audio.output.format.rate".
+	Field string
+	// Reason describes the violated constraint.
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Reason
+}
+
+// ValidationErrors is the list of violations found by Validate.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "invalid request: " + strings.Join(msgs, "; ")
+}
+
+// validator collects the violations of a value.
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) add(field, format string, args ...any) {
+	v.errs = append(v.errs, &ValidationError{Field: field, Reason: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func indexPath(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+func keyPath(path, key string) string {
+	return fmt.Sprintf("%s[%q]", path, key)
+}
+
+// exclusive checks that at most one of the variants of a union is set,
+// and at least one if required.
+func (v *validator) exclusive(path string, required bool, variants map[string]bool) {
+	var set []string
+	for name, ok := range variants {
+		if ok {
+			set = append(set, name)
+		}
+	}
+	switch {
+	case len(set) > 1:
+		sort.Strings(set)
+		v.add(path, "only one of %s could be set", strings.Join(set, ", "))
+	case len(set) == 0 && required:
+		v.add(path, "no variant is set")
+	}
+}
+
+// variant checks the kind of a union with a Kind accessor, exactly one variant must be set.
+func (v *validator) variant(path, kind string, err error) {
+	switch {
+	case err != nil:
+		v.add(path, "%v", err)
+	case kind == "":
+		v.add(path, "no variant is set")
+	}
+}
+
+func (v *validator) base64(path, s string) {
+	if _, err := base64.StdEncoding.DecodeString(s); err != nil {
+		v.add(path, "invalid base64: %v", err)
+	}
+}
+
+func (v *validator) maxOutputTokens(path string, m IntOrInf) {
+	if m == 0 || m.IsInf() {
+		return
+	}
+	if m < 1 || m > maxOutputTokensLimit {
+		v.add(path, "must be between 1 and %d or inf, got %d", maxOutputTokensLimit, int(m))
+	}
+}
+
+func (v *validator) outputModalities(path string, modalities []Modality) {
+	if len(modalities) > 1 {
+		v.add(path, "only one of text or audio could be requested, got %v", modalities)
+		return
+	}
+	for i, m := range modalities {
+		if m != ModalityText && m != ModalityAudio {
+			v.add(indexPath(path, i), "unknown modality %q", m)
+		}
+	}
+}
+
+func (v *validator) metadata(path string, metadata map[string]string) {
+	if len(metadata) > maxMetadataPairs {
+		v.add(path, "at most %d pairs are allowed, got %d", maxMetadataPairs, len(metadata))
+	}
+	for key, value := range metadata {
+		if n := utf8.RuneCountInString(key); n > maxMetadataKeyLen {
+			v.add(keyPath(path, key), "key is longer than %d characters", maxMetadataKeyLen)
+		}
+		if n := utf8.RuneCountInString(value); n > maxMetadataValueLen {
+			v.add(keyPath(path, key), "value is longer than %d characters", maxMetadataValueLen)
+		}
+	}
+}
+
+func (v *validator) include(path string, include []string) {
+	for i, s := range include {
+		if s != "item.input_audio_transcription.logprobs" {
+			v.add(indexPath(path, i), "unknown include %q", s)
+		}
+	}
+}
+
+func (v *validator) tools(path string, tools []ToolUnion) {
+	for i := range tools {
+		tools[i].validate(v, indexPath(path, i))
+	}
+}
+
+func (r AudioFormatUnion) validate(v *validator, path string) {
+	kind, err := r.kind()
+	v.variant(path, string(kind), err)
+	if r.PCM != nil && r.PCM.Rate != 0 && r.PCM.Rate != pcmSampleRate {
+		v.add(joinPath(path, "rate"), "only %d is supported, got %d", pcmSampleRate, r.PCM.Rate)
+	}
+}
+
+func (r TurnDetectionUnion) validate(v *validator, path string) {
+	kind, err := r.kind()
+	v.variant(path, string(kind), err)
+	if r.ServerVad != nil && (r.ServerVad.Threshold < 0 || r.ServerVad.Threshold > 1) {
+		v.add(joinPath(path, "threshold"), "must be between 0.0 and 1.0, got %v", r.ServerVad.Threshold)
+	}
+	if r.SemanticVad != nil {
+		switch r.SemanticVad.Eagerness {
+		case "", "low", "medium", "high", "auto":
+		default:
+			v.add(joinPath(path, "eagerness"), "unknown eagerness %q", r.SemanticVad.Eagerness)
+		}
+	}
+}
+
+func (r SessionAudioInput) validate(v *validator, path string) {
+	if r.Format != nil {
+		r.Format.validate(v, joinPath(path, "format"))
+	}
+	if noiseReduction, ok := r.NoiseReduction.Get(); ok {
+		switch noiseReduction.Type {
+		case "", NoiseReductionNearField, NoiseReductionFarField:
+		default:
+			v.add(joinPath(path, "noise_reduction.type"), "unknown noise reduction %q", noiseReduction.Type)
+		}
+	}
+	if turnDetection, ok := r.TurnDetection.Get(); ok {
+		turnDetection.validate(v, joinPath(path, "turn_detection"))
+	}
+}
+
+func (r SessionAudioOutput) validate(v *validator, path string) {
+	if r.Format != nil {
+		r.Format.validate(v, joinPath(path, "format"))
+	}
+	if r.Speed != 0 && (r.Speed < minAudioSpeed || r.Speed > maxAudioSpeed) {
+		v.add(joinPath(path, "speed"), "must be between %v and %v, got %v", minAudioSpeed, maxAudioSpeed, r.Speed)
+	}
+}
+
+func (t ToolChoiceUnion) validate(v *validator, path string) {
+	v.exclusive(path, false, map[string]bool{
+		"Mode":     t.Mode != "",
+		"Function": t.Function != nil,
+		"MCP":      t.MCP != nil,
+	})
+	switch t.Mode {
+	case "", ToolChoiceModeNone, ToolChoiceModeAuto, ToolChoiceModeRequired:
+	default:
+		v.add(path, "unknown mode %q", t.Mode)
+	}
+	if t.Function != nil && t.Function.Name == "" {
+		v.add(joinPath(path, "name"), "function name is required")
+	}
+	if t.MCP != nil && t.MCP.ServerLabel == "" {
+		v.add(joinPath(path, "server_label"), "server label is required")
+	}
+}
+
+func (t ToolUnion) validate(v *validator, path string) {
+	kind, err := t.kind()
+	v.variant(path, string(kind), err)
+	if t.Function != nil && t.Function.Name == "" {
+		v.add(joinPath(path, "name"), "function name is required")
+	}
+	if t.MCP != nil {
+		t.MCP.validate(v, path)
+	}
+}
+
+func (t ToolMCP) validate(v *validator, path string) {
+	if t.ServerLabel == "" {
+		v.add(joinPath(path, "server_label"), "server label is required")
+	}
+	switch {
+	case t.ServerURL == "" && t.ConnectorID == "":
+		v.add(path, "one of server_url or connector_id is required")
+	case t.ServerURL != "" && t.ConnectorID != "":
+		v.add(path, "only one of server_url or connector_id could be set")
+	case t.ServerURL != "":
+		u, err := url.Parse(t.ServerURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			v.add(joinPath(path, "server_url"), "must be an absolute http(s) URL, got %q", t.ServerURL)
+		}
+	case !strings.HasPrefix(t.ConnectorID, "connector_"):
+		v.add(joinPath(path, "connector_id"), "unknown connector %q", t.ConnectorID)
+	}
+	if t.AllowedTools != nil {
+		v.exclusive(joinPath(path, "allowed_tools"), false, map[string]bool{
+			"ToolNames": len(t.AllowedTools.ToolNames) > 0,
+			"Filter":    t.AllowedTools.Filter != nil,
+		})
+	}
+	if t.RequireApproval != nil {
+		approvalPath := joinPath(path, "require_approval")
+		v.exclusive(approvalPath, false, map[string]bool{
+			"Filter":  t.RequireApproval.Filter != nil,
+			"Setting": t.RequireApproval.Setting != "",
+		})
+		switch t.RequireApproval.Setting {
+		case "", "always", "never":
+		default:
+			v.add(approvalPath, "unknown setting %q", t.RequireApproval.Setting)
+		}
+	}
+}
+
+func (t TracingUnion) validate(v *validator, path string) {
+	v.exclusive(path, false, map[string]bool{
+		"Mode":          t.Mode != "",
+		"Configuration": t.Configuration != nil,
+	})
+	if t.Mode != "" && t.Mode != TracingModeAuto {
+		v.add(path, "unknown mode %q", t.Mode)
+	}
+}
+
+func (t TruncationUnion) validate(v *validator, path string) {
+	v.exclusive(path, false, map[string]bool{
+		"Strategy":                 t.Strategy != "",
+		"RetentionRatioTruncation": t.RetentionRatioTruncation != nil,
+	})
+	switch t.Strategy {
+	case "", TruncationStrategyAuto, TruncationStrategyDisabled:
+	default:
+		v.add(path, "unknown strategy %q", t.Strategy)
+	}
+	if r := t.RetentionRatioTruncation; r != nil && (r.Ratio <= 0 || r.Ratio > 1) {
+		v.add(joinPath(path, "retention_ratio"), "must be greater than 0.0 and at most 1.0, got %v", r.Ratio)
+	}
+}
+
+func (r PromptReference) validate(v *validator, path string) {
+	if r.ID == "" {
+		v.add(joinPath(path, "id"), "prompt id is required")
+	}
+	for name, u := range r.Variables {
+		v.exclusive(keyPath(joinPath(path, "variables"), name), false, map[string]bool{
+			"String":     u.String != "",
+			"InputText":  u.InputText != nil,
+			"InputImage": u.InputImage != nil,
+			"InputFile":  u.InputFile != nil,
+		})
+	}
+}
+
+func (m MessageItemUnion) validate(v *validator, path string) {
+	kind, err := m.kind()
+	v.variant(path, string(kind), err)
+	switch kind {
+	case MessageItemKindUser:
+		for i, c := range m.User.Content {
+			contentPath := indexPath(joinPath(path, "content"), i)
+			switch c.Type {
+			case MessageContentTypeInputText:
+			case MessageContentTypeInputAudio:
+				v.base64(joinPath(contentPath, "audio"), c.Audio)
+			case MessageContentTypeInputImage:
+				if c.ImageURL == "" {
+					v.add(joinPath(contentPath, "image_url"), "image url is required")
+				}
+			default:
+				v.add(joinPath(contentPath, "type"), "unknown user content type %q", c.Type)
+			}
+		}
+	case MessageItemKindAssistant:
+		for i, c := range m.Assistant.Content {
+			if c.Audio != "" {
+				v.base64(joinPath(indexPath(joinPath(path, "content"), i), "audio"), c.Audio)
+			}
+		}
+	case MessageItemKindFunctionCall:
+		if m.FunctionCall.Name == "" {
+			v.add(joinPath(path, "name"), "function name is required")
+		}
+	case MessageItemKindFunctionCallOutput:
+		if m.FunctionCallOutput.CallID == "" {
+			v.add(joinPath(path, "call_id"), "call id is required")
+		}
+	case MessageItemKindMCPApprovalResponse:
+		if m.MCPApprovalResponse.ApprovalRequestID == "" {
+			v.add(joinPath(path, "approval_request_id"), "approval request id is required")
+		}
+	}
+}
+
+func (r RealtimeSession) validate(v *validator, path string) {
+	if r.Audio != nil {
+		if r.Audio.Input != nil {
+			r.Audio.Input.validate(v, joinPath(path, "audio.input"))
+		}
+		if r.Audio.Output != nil {
+			r.Audio.Output.validate(v, joinPath(path, "audio.output"))
+		}
+	}
+	v.include(joinPath(path, "include"), r.Include)
+	v.maxOutputTokens(joinPath(path, "max_output_tokens"), r.MaxOutputTokens)
+	v.outputModalities(joinPath(path, "output_modalities"), r.OutputModalities)
+	if r.Prompt != nil {
+		r.Prompt.validate(v, joinPath(path, "prompt"))
+	}
+	if r.ToolChoice != nil {
+		r.ToolChoice.validate(v, joinPath(path, "tool_choice"))
+	}
+	v.tools(joinPath(path, "tools"), r.Tools)
+	if tracing, ok := r.Tracing.Get(); ok {
+		tracing.validate(v, joinPath(path, "tracing"))
+	}
+	if r.Truncation != nil {
+		r.Truncation.validate(v, joinPath(path, "truncation"))
+	}
+}
+
+// Validate checks the session against the documented constraints of the API.
+// It returns ValidationErrors listing all violations, or nil.
+func (r RealtimeSession) Validate() error {
+	var v validator
+	r.validate(&v, "")
+	return v.err()
+}
+
+func (r TranscriptionSession) validate(v *validator, path string) {
+	if r.Audio != nil && r.Audio.Input != nil {
+		r.Audio.Input.validate(v, joinPath(path, "audio.input"))
+	}
+	v.include(joinPath(path, "include"), r.Include)
+}
+
+// Validate checks the session against the documented constraints of the API.
+// It returns ValidationErrors listing all violations, or nil.
+func (r TranscriptionSession) Validate() error {
+	var v validator
+	r.validate(&v, "")
+	return v.err()
+}
+
+func (r SessionUnion) validate(v *validator, path string) {
+	v.exclusive(path, true, map[string]bool{
+		"Realtime":      r.Realtime != nil,
+		"Transcription": r.Transcription != nil,
+	})
+	if r.Realtime != nil {
+		r.Realtime.validate(v, path)
+	}
+	if r.Transcription != nil {
+		r.Transcription.validate(v, path)
+	}
+}
+
+func (r ResponseCreateParams) validate(v *validator, path string) {
+	if r.Audio != nil && r.Audio.Output != nil && r.Audio.Output.Format != nil {
+		r.Audio.Output.Format.validate(v, joinPath(path, "audio.output.format"))
+	}
+	switch r.Conversation {
+	case "", "auto", "none":
+	default:
+		v.add(joinPath(path, "conversation"), "must be auto or none, got %q", r.Conversation)
+	}
+	for i, item := range r.Input {
+		item.validate(v, indexPath(joinPath(path, "input"), i))
+	}
+	v.maxOutputTokens(joinPath(path, "max_output_tokens"), r.MaxOutputTokens)
+	v.metadata(joinPath(path, "metadata"), r.Metadata)
+	v.outputModalities(joinPath(path, "output_modalities"), r.OutputModalities)
+	if r.Prompt != nil {
+		r.Prompt.validate(v, joinPath(path, "prompt"))
+	}
+	if r.ToolChoice != nil {
+		r.ToolChoice.validate(v, joinPath(path, "tool_choice"))
+	}
+	v.tools(joinPath(path, "tools"), r.Tools)
+}
+
+// Validate checks the parameters against the documented constraints of the API.
+// It returns ValidationErrors listing all violations, or nil.
+func (r ResponseCreateParams) Validate() error {
+	var v validator
+	r.validate(&v, "")
+	return v.err()
+}
+
+// Validate checks the session of the event against the documented constraints of the API.
+func (m SessionUpdateEvent) Validate() error {
+	var v validator
+	m.Session.validate(&v, "session")
+	return v.err()
+}
+
+// Validate checks that the audio is valid base64.
+func (m InputAudioBufferAppendEvent) Validate() error {
+	var v validator
+	if m.Audio == "" {
+		v.add("audio", "audio is required")
+	} else {
+		v.base64("audio", m.Audio)
+	}
+	return v.err()
+}
+
+// Validate always returns nil, the event has no parameters.
+func (m InputAudioBufferCommitEvent) Validate() error {
+	return nil
+}
+
+// Validate always returns nil, the event has no parameters.
+func (m InputAudioBufferClearEvent) Validate() error {
+	return nil
+}
+
+// Validate always returns nil, the event has no parameters.
+func (m OutputAudioBufferClearEvent) Validate() error {
+	return nil
+}
+
+// Validate checks the item of the event against the documented constraints of the API.
+func (m ConversationItemCreateEvent) Validate() error {
+	var v validator
+	m.Item.validate(&v, "item")
+	return v.err()
+}
+
+// Validate checks that the item id is set.
+func (m ConversationItemRetrieveEvent) Validate() error {
+	var v validator
+	if m.ItemID == "" {
+		v.add("item_id", "item id is required")
+	}
+	return v.err()
+}
+
+// Validate checks that the item id is set and the indexes are not negative.
+func (m ConversationItemTruncateEvent) Validate() error {
+	var v validator
+	if m.ItemID == "" {
+		v.add("item_id", "item id is required")
+	}
+	if m.ContentIndex < 0 {
+		v.add("content_index", "must not be negative, got %d", m.ContentIndex)
+	}
+	if m.AudioEndMs < 0 {
+		v.add("audio_end_ms", "must not be negative, got %d", m.AudioEndMs)
+	}
+	return v.err()
+}
+
+// Validate checks that the item id is set.
+func (m ConversationItemDeleteEvent) Validate() error {
+	var v validator
+	if m.ItemID == "" {
+		v.add("item_id", "item id is required")
+	}
+	return v.err()
+}
+
+// Validate checks the response of the event against the documented constraints of the API.
+func (m ResponseCreateEvent) Validate() error {
+	var v validator
+	m.Response.validate(&v, "response")
+	return v.err()
+}
+
+// Validate always returns nil, the response id is optional.
+func (m ResponseCancelEvent) Validate() error {
+	return nil
+}
@@ -0,0 +1,188 @@
+package openairt_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	openairt "github.com/WqyJh/go-openai-realtime/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func validationFields(t *testing.T, err error) []string {
+	t.Helper()
+	var errs openairt.ValidationErrors
+	require.True(t, errors.As(err, &errs), "expected ValidationErrors, got %v", err)
+	fields := make([]string, len(errs))
+	for i, e := range errs {
+		fields[i] = e.Field
+	}
+	return fields
+}
+
+func TestRealtimeSessionValidate(t *testing.T) {
+	valid := openairt.RealtimeSession{
+		MaxOutputTokens:  openairt.Inf,
+		OutputModalities: []openairt.Modality{openairt.ModalityAudio},
+		Audio: &openairt.RealtimeSessionAudio{
+			Input: &openairt.SessionAudioInput{
+				Format:        &openairt.AudioFormatUnion{PCM: &openairt.AudioFormatPCM{Rate: 24000}},
+				TurnDetection: openairt.NewOptional(openairt.TurnDetectionUnion{ServerVad: &openairt.ServerVad{Threshold: 0.5}}),
+			},
+			Output: &openairt.SessionAudioOutput{Speed: 1},
+		},
+		Tools: []openairt.ToolUnion{
+			{Function: &openairt.ToolFunction{Name: "get_weather"}},
+			{MCP: &openairt.ToolMCP{ServerLabel: "deepwiki", ServerURL: "https://mcp.deepwiki.com/mcp"}},
+			{MCP: &openairt.ToolMCP{ServerLabel: "gmail", ConnectorID: "connector_gmail"}},
+		},
+	}
+	require.NoError(t, valid.Validate())
+
+	invalid := openairt.RealtimeSession{
+		MaxOutputTokens:  5000,
+		OutputModalities: []openairt.Modality{openairt.ModalityText, openairt.ModalityAudio},
+		Audio: &openairt.RealtimeSessionAudio{
+			Input: &openairt.SessionAudioInput{
+				Format: &openairt.AudioFormatUnion{PCM: &openairt.AudioFormatPCM{Rate: 16000}},
+				TurnDetection: openairt.NewOptional(openairt.TurnDetectionUnion{
+					ServerVad:   &openairt.ServerVad{},
+					SemanticVad: &openairt.RealtimeSessionSemanticVad{},
+				}),
+			},
+		},
+		Tools: []openairt.ToolUnion{
+			{Function: &openairt.ToolFunction{Name: "f"}, MCP: &openairt.ToolMCP{ServerLabel: "s", ServerURL: "https://a"}},
+			{MCP: &openairt.ToolMCP{ServerLabel: "s"}},
+			{MCP: &openairt.ToolMCP{ServerLabel: "s", ServerURL: "https://a", ConnectorID: "connector_gmail"}},
+			{MCP: &openairt.ToolMCP{ServerLabel: "s", ServerURL: "mcp.example.com"}},
+		},
+	}
+	err := invalid.Validate()
+	require.Equal(t, []string{
+		"audio.input.format.rate",
+		"audio.input.turn_detection",
+		"max_output_tokens",
+		"output_modalities",
+		"tools[0]",
+		"tools[1]",
+		"tools[2]",
+		"tools[3].server_url",
+	}, validationFields(t, err))
+	require.Contains(t, err.Error(), "ToolUnion has function, mcp")
+}
+
+func TestTranscriptionSessionValidate(t *testing.T) {
+	session := openairt.TranscriptionSession{
+		Include: []string{"item.input_audio_transcription.logprobs"},
+		Audio: &openairt.TranscriptionSessionAudio{
+			Input: &openairt.SessionAudioInput{
+				Format:         &openairt.AudioFormatUnion{PCMU: &openairt.AudioFormatPCMU{}},
+				NoiseReduction: openairt.NewOptional(openairt.AudioNoiseReduction{Type: openairt.NoiseReductionNearField}),
+			},
+		},
+	}
+	require.NoError(t, session.Validate())
+
+	session.Include = append(session.Include, "unknown")
+	session.Audio.Input.Format = &openairt.AudioFormatUnion{}
+	require.Equal(t, []string{"audio.input.format", "include[1]"}, validationFields(t, session.Validate()))
+}
+
+func TestResponseCreateParamsValidate(t *testing.T) {
+	params := openairt.ResponseCreateParams{
+		Conversation:    "none",
+		MaxOutputTokens: 4096,
+		Metadata:        map[string]string{"topic": "weather"},
+		Input: []openairt.MessageItemUnion{
+			{User: &openairt.MessageItemUser{Content: []openairt.MessageContentInput{
+				{Type: openairt.MessageContentTypeInputAudio, Audio: "AAECAw=="},
+			}}},
+		},
+	}
+	require.NoError(t, params.Validate())
+
+	metadata := map[string]string{strings.Repeat("k", 65): "v", "long": strings.Repeat("v", 513)}
+	for i := 0; i < 15; i++ {
+		metadata[strings.Repeat("x", i+1)] = "v"
+	}
+	params = openairt.ResponseCreateParams{
+		Conversation: "default",
+		Metadata:     metadata,
+		Input: []openairt.MessageItemUnion{
+			{User: &openairt.MessageItemUser{Content: []openairt.MessageContentInput{
+				{Type: openairt.MessageContentTypeInputAudio, Audio: "not base64!"},
+			}}},
+			{},
+		},
+		ToolChoice: &openairt.ToolChoiceUnion{
+			Mode:     openairt.ToolChoiceModeAuto,
+			Function: &openairt.ToolChoiceFunction{Name: "f"},
+		},
+	}
+	fields := validationFields(t, params.Validate())
+	require.Contains(t, fields, "conversation")
+	require.Contains(t, fields, "input[0].content[0].audio")
+	require.Contains(t, fields, "input[1]")
+	require.Contains(t, fields, "metadata")
+	require.Contains(t, fields, `metadata["long"]`)
+	require.Contains(t, fields, `metadata["`+strings.Repeat("k", 65)+`"]`)
+	require.Contains(t, fields, "tool_choice")
+}
+
+func TestClientEventValidate(t *testing.T) {
+	events := []openairt.ClientEvent{
+		openairt.SessionUpdateEvent{},
+		openairt.InputAudioBufferAppendEvent{Audio: "AAE"},
+		openairt.ConversationItemCreateEvent{},
+		openairt.ConversationItemRetrieveEvent{},
+		openairt.ConversationItemTruncateEvent{ItemID: "item_1", AudioEndMs: -1},
+		openairt.ConversationItemDeleteEvent{},
+		openairt.ResponseCreateEvent{Response: openairt.ResponseCreateParams{MaxOutputTokens: -1}},
+	}
+	for _, event := range events {
+		v, ok := event.(openairt.Validator)
+		require.True(t, ok)
+		require.Error(t, v.Validate(), event.ClientEventType())
+	}
+
+	events = []openairt.ClientEvent{
+		openairt.SessionUpdateEvent{Session: openairt.SessionUnion{Realtime: &openairt.RealtimeSession{}}},
+		openairt.InputAudioBufferAppendEvent{Audio: "AAECAw=="},
+		openairt.InputAudioBufferCommitEvent{},
+		openairt.InputAudioBufferClearEvent{},
+		openairt.OutputAudioBufferClearEvent{},
+		openairt.ConversationItemCreateEvent{Item: openairt.MessageItemUnion{
+			FunctionCallOutput: &openairt.MessageItemFunctionCallOutput{CallID: "call_1"},
+		}},
+		openairt.ConversationItemRetrieveEvent{ItemID: "item_1"},
+		openairt.ConversationItemTruncateEvent{ItemID: "item_1"},
+		openairt.ConversationItemDeleteEvent{ItemID: "item_1"},
+		openairt.ResponseCreateEvent{},
+		openairt.ResponseCancelEvent{},
+	}
+	for _, event := range events {
+		v, ok := event.(openairt.Validator)
+		require.True(t, ok)
+		require.NoError(t, v.Validate(), event.ClientEventType())
+	}
+}
+
+func TestConnWithValidation(t *testing.T) {
+	ctx := context.Background()
+	invalid := openairt.ResponseCreateEvent{Response: openairt.ResponseCreateParams{MaxOutputTokens: 5000}}
+
+	metrics := &recordingMetrics{}
+	conn := newScriptedConn(t, nil, openairt.WithValidation(), openairt.WithMetrics(metrics))
+	var errs openairt.ValidationErrors
+	require.ErrorAs(t, conn.SendMessage(ctx, invalid), &errs)
+	require.Equal(t, []string{"response.max_output_tokens"}, validationFields(t, errs))
+	require.Empty(t, metrics.sent)
+	require.NoError(t, conn.SendMessage(ctx, openairt.ResponseCreateEvent{}))
+	require.Len(t, metrics.sent, 1)
+
+	// Validation is opt-in.
+	conn = newScriptedConn(t, nil)
+	require.NoError(t, conn.SendMessage(ctx, invalid))
+}
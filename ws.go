@@ -1,8 +1,10 @@
 package openairt
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 )
 
@@ -74,3 +76,46 @@ func DefaultDialer() WebSocketDialer {
 var (
 	ErrUnsupportedMessageType = errors.New("unsupported message type")
 )
+
+// StatusCode is a WebSocket close status code.
+// See https://tools.ietf.org/html/rfc6455#section-7.4
+type StatusCode int
+
+// StatusCode constants.
+const (
+	StatusNormalClosure   StatusCode = 1000
+	StatusGoingAway       StatusCode = 1001
+	StatusProtocolError   StatusCode = 1002
+	StatusPolicyViolation StatusCode = 1008
+	StatusInternalError   StatusCode = 1011
+	StatusTryAgainLater   StatusCode = 1013
+)
+
+// WebSocketStatusCloser is implemented by the WebSocketConns which can close with a status code and reason.
+type WebSocketStatusCloser interface {
+	// CloseWithStatus closes the WebSocket connection with the close status code and reason.
+	CloseWithStatus(code StatusCode, reason string) error
+}
+
+// WebSocketBufferReader is implemented by the WebSocketConns which can read a message into a reused buffer.
+// Conn.ReadMessage uses it to avoid allocating a buffer per message.
+type WebSocketBufferReader interface {
+	// ReadMessageInto resets buf and reads the next message into it.
+	// The errors follow the same rules as WebSocketConn.ReadMessage.
+	ReadMessageInto(ctx context.Context, buf *bytes.Buffer) (MessageType, error)
+}
+
+// CloseError is the error of the read operations once the peer closed the connection with a close frame.
+type CloseError struct {
+	Code   StatusCode
+	Reason string
+	Err    error
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed with status %d: %s", e.Code, e.Reason)
+}
+
+func (e *CloseError) Unwrap() error {
+	return e.Err
+}
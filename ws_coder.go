@@ -1,7 +1,9 @@
 package openairt
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"io"
 	"net/http"
 
@@ -74,29 +76,64 @@ type CoderWebSocketConn struct {
 	options CoderWebSocketOptions
 }
 
-// ReadMessage reads a message from the WebSocket connection.
+// ReadMessage reads a message from the WebSocket connection into a new slice.
+// ReadMessageInto is the allocation-free path, reading into a reused buffer.
 //
 // The ctx could be used to cancel the read operation. If the ctx is canceled or timedout,
 // the read operation will be canceled and the connection will be closed.
 //
 // If the returned error is Permanent, the future read operations on the same connection will not succeed.
 func (c *CoderWebSocketConn) ReadMessage(ctx context.Context) (MessageType, []byte, error) {
-	messageType, r, err := c.conn.Reader(ctx)
+	messageType, r, err := c.reader(ctx)
 	if err != nil {
-		return 0, nil, Permanent(err)
+		return 0, nil, err
 	}
 
 	data, err := io.ReadAll(r)
 	if err != nil {
 		return 0, nil, Permanent(err)
 	}
+	return messageType, data, nil
+}
+
+// ReadMessageInto resets buf and reads a message from the WebSocket connection into it,
+// so that a buffer can be reused across messages without allocating.
+//
+// The ctx and the returned error behave as in ReadMessage.
+func (c *CoderWebSocketConn) ReadMessageInto(ctx context.Context, buf *bytes.Buffer) (MessageType, error) {
+	buf.Reset()
+	messageType, r, err := c.reader(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = buf.ReadFrom(r); err != nil {
+		return 0, Permanent(err)
+	}
+	return messageType, nil
+}
+
+// reader returns the reader of the next message.
+func (c *CoderWebSocketConn) reader(ctx context.Context) (MessageType, io.Reader, error) {
+	messageType, r, err := c.conn.Reader(ctx)
+	if err != nil {
+		var closeErr websocket.CloseError
+		if errors.As(err, &closeErr) {
+			err = &CloseError{Code: StatusCode(closeErr.Code), Reason: closeErr.Reason, Err: err}
+		}
+		return 0, nil, Permanent(err)
+	}
 
 	switch messageType {
 	case websocket.MessageText:
-		return MessageText, data, nil
+		return MessageText, r, nil
 	case websocket.MessageBinary:
-		return MessageBinary, data, nil
+		return MessageBinary, r, nil
 	default:
+		// Discard the frame, so that the next read starts at the next message.
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return 0, nil, Permanent(err)
+		}
 		return 0, nil, ErrUnsupportedMessageType
 	}
 }
@@ -123,6 +160,11 @@ func (c *CoderWebSocketConn) Close() error {
 	return c.conn.Close(websocket.StatusNormalClosure, "")
 }
 
+// CloseWithStatus closes the WebSocket connection with the close status code and reason.
+func (c *CoderWebSocketConn) CloseWithStatus(code StatusCode, reason string) error {
+	return c.conn.Close(websocket.StatusCode(code), reason)
+}
+
 // Response returns the *http.Response of the WebSocket connection.
 // Commonly used to get response headers.
 func (c *CoderWebSocketConn) Response() *http.Response {